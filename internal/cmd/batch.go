@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// IsBatch reports whether the --file values call for a combined report:
+// more than one value, a directory or a glob pattern
+func IsBatch(inputs []string) bool {
+	if len(inputs) > 1 {
+		return true
+	}
+	for _, input := range inputs {
+		if isGlob(input) {
+			return true
+		}
+		if info, err := os.Stat(input); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// isGlob reports whether path is a glob pattern. URLs are never patterns
+// since presigned URLs carry a query string.
+func isGlob(path string) bool {
+	return !parse.IsURL(path) && strings.ContainsAny(path, "*?[")
+}
+
+// ExpandInputs resolves --file values to caption files. Directories are
+// searched recursively for supported caption files and glob patterns are
+// expanded; other paths are kept as given so that missing files are
+// reported by validation. Each file is listed once, in the order it was
+// first named.
+func ExpandInputs(inputs []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, input := range inputs {
+		if isGlob(input) {
+			matches, err := filepath.Glob(input)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", input, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", input)
+			}
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && info.IsDir() {
+					continue
+				}
+				add(match)
+			}
+			continue
+		}
+
+		info, err := os.Stat(input)
+		if err != nil || !info.IsDir() || input == parse.StdinPath {
+			add(input)
+			continue
+		}
+		found := 0
+		err = filepath.WalkDir(input, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && parse.IsCaptionFile(path) {
+				add(path)
+				found++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %v", input, err)
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("no caption files found in %s", input)
+		}
+	}
+	return files, nil
+}
+
+// ValidateBatch validates files concurrently with at most config.Jobs
+// workers and combines their reports, in the order of files. A file with
+// several caption tracks contributes a report per track.
+func ValidateBatch(config *models.Config, files []string) *models.BatchReport {
+	results := make([][]*models.Report, len(files))
+	jobs := config.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fileConfig := *config
+				fileConfig.FilePath = files[i]
+				results[i] = ValidateTracks(&fileConfig)
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var reports []*models.Report
+	for _, result := range results {
+		reports = append(reports, result...)
+	}
+	return CombineReports(reports)
+}
+
+// CombineReports totals per-file reports. The batch fails when any file
+// fails, and its exit code is the most severe one of any file.
+func CombineReports(reports []*models.Report) *models.BatchReport {
+	batch := &models.BatchReport{Files: reports, ExitCode: ExitPass}
+	for _, report := range reports {
+		batch.Totals.Files++
+		batch.Totals.Findings += len(report.Findings)
+		switch report.Verdict {
+		case models.VerdictPass:
+			batch.Totals.Passed++
+		case models.VerdictFail:
+			batch.Totals.Failed++
+		default:
+			batch.Totals.Errored++
+		}
+		if exitSeverity(report.ExitCode) > exitSeverity(batch.ExitCode) {
+			batch.ExitCode = report.ExitCode
+		}
+	}
+
+	switch {
+	case batch.Totals.Errored > 0:
+		batch.Verdict = models.VerdictError
+		batch.Reason = fmt.Sprintf("%d of %d files could not be validated", batch.Totals.Errored, batch.Totals.Files)
+		if batch.Totals.Failed > 0 {
+			batch.Reason += fmt.Sprintf(", %d failed validation", batch.Totals.Failed)
+		}
+	case batch.Totals.Failed > 0:
+		batch.Verdict = models.VerdictFail
+		batch.Reason = fmt.Sprintf("%d of %d files failed validation", batch.Totals.Failed, batch.Totals.Files)
+	default:
+		batch.Verdict = models.VerdictPass
+	}
+	return batch
+}
+
+// exitSeverity orders exit codes so that a usage error, such as a --track
+// that names no track of a file, outranks an unusable detector, which
+// outranks unreadable input, which outranks a failed validation
+func exitSeverity(code int) int {
+	switch code {
+	case ExitUsage:
+		return 4
+	case ExitDetectorUnavailable:
+		return 3
+	case ExitInputUnreadable:
+		return 2
+	case ExitValidationFailed:
+		return 1
+	}
+	return 0
+}
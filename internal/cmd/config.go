@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+	"github.com/theCompanyDream/srt-test/internal/report"
+)
+
+// FileConfig holds the settings read from a --config file. Every key is
+// the command-line flag of the same name in snake_case, such as fail_on for
+// --fail-on.
+type FileConfig struct {
+	File           StringList `yaml:"file"`
+	Start          string     `yaml:"start"`
+	End            string     `yaml:"end"`
+	Endpoint       string     `yaml:"endpoint"`
+	Language       string     `yaml:"lang"`
+	Profile        string     `yaml:"profile"`
+	FailOn         string     `yaml:"fail_on"`
+	Format         string     `yaml:"format"`
+	HTML           string     `yaml:"html"`
+	Rules          yaml.Node  `yaml:"rules"`
+	LanguageWindow string     `yaml:"language_window"`
+	Jobs           int        `yaml:"jobs"`
+	InputFormat    string     `yaml:"input_format"`
+	MaxInputSize   string     `yaml:"max_input_size"`
+	MaxLineSize    string     `yaml:"max_line_size"`
+	FetchTimeout   string     `yaml:"fetch_timeout"`
+	FPS            string     `yaml:"fps"`
+	ASRMapping     string     `yaml:"asr_mapping"`
+	Track          string     `yaml:"track"`
+	Profiles       Profiles   `yaml:"profiles"`
+}
+
+var unknownFieldRegex = regexp.MustCompile(`^(line \d+): field (\S+) not found in type \S+$`)
+
+// LoadConfigFile reads a YAML or JSON configuration file. Unknown keys and
+// values of the wrong type are errors, as ValidateConfigFile reports them.
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, problems, err := decodeConfigFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config file %s: %s", path, strings.Join(problems, "; "))
+	}
+	return config, nil
+}
+
+// decodeConfigFile decodes a configuration file and returns its unknown
+// keys and values of the wrong type as problems, or an error when it is
+// not valid YAML
+func decodeConfigFile(data []byte) (*FileConfig, []string, error) {
+	// Unknown top-level keys are reported by the decoder, and those of the
+	// rules and profiles sections, which are decoded later, by their keys
+	var problems []string
+	var config FileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, nil, err
+		}
+		for _, msg := range typeErr.Errors {
+			if m := unknownFieldRegex.FindStringSubmatch(msg); m != nil {
+				msg = fmt.Sprintf("%s: unknown key %q", m[1], m[2])
+			}
+			problems = append(problems, msg)
+		}
+	}
+	return &config, append(problems, unknownSectionKeys(&config)...), nil
+}
+
+// ValidateConfigFile checks a configuration file and returns every problem
+// found: syntax errors, unknown keys, malformed values and rules that are
+// out of range
+func ValidateConfigFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, problems, err := decodeConfigFile(data)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid config file %s: %v", path, err)}, nil
+	}
+	if config.Rules.Kind != 0 {
+		var rules models.Rules
+		var typeErr *yaml.TypeError
+		if err := config.Rules.Decode(&rules); errors.As(err, &typeErr) {
+			problems = append(problems, typeErr.Errors...)
+		} else if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	// Times and sizes are checked as a run checks them, with the defaults
+	// of the flags for those that are not set
+	orDefault := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	_, errs := parseSettings(settingValues{
+		start:          orDefault(config.Start, settingDefaults.start),
+		end:            config.End,
+		languageWindow: orDefault(config.LanguageWindow, settingDefaults.languageWindow),
+		maxInputSize:   orDefault(config.MaxInputSize, settingDefaults.maxInputSize),
+		maxLineSize:    orDefault(config.MaxLineSize, settingDefaults.maxLineSize),
+		fetchTimeout:   orDefault(config.FetchTimeout, settingDefaults.fetchTimeout),
+	})
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+
+	if config.FailOn != "" {
+		if _, err := models.ParseSeverity(config.FailOn); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid fail_on threshold: %v", err))
+		}
+	}
+
+	if config.Format != "" && !report.IsValidFormat(config.Format) {
+		problems = append(problems, fmt.Sprintf("unsupported output format %q (expected %s)", config.Format, strings.Join(report.Formats, ", ")))
+	}
+
+	if config.InputFormat != "" && !parse.IsSupportedFormat(strings.ToLower(config.InputFormat)) {
+		problems = append(problems, fmt.Sprintf("unsupported input format %q (expected %s)", config.InputFormat, strings.Join(parse.Formats, ", ")))
+	}
+
+	if config.FPS != "" {
+		if _, err := parse.ParseFrameRate(config.FPS); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid fps: %v", err))
+		}
+	}
+	if config.ASRMapping != "" {
+		if _, err := parse.ParseASRMapping(config.ASRMapping); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid asr_mapping: %v", err))
+		}
+	}
+
+	if config.Jobs < 0 {
+		problems = append(problems, "jobs must be at least 1")
+	}
+
+	for _, name := range ProfileNames(config.Profiles) {
+		if _, err := ResolveProfile(name, config.Profiles); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	// Custom profiles and malformed rule values were reported above
+	rules, err := ResolveProfile(config.Profile, config.Profiles)
+	if err != nil {
+		if _, isCustom := config.Profiles[config.Profile]; !isCustom {
+			problems = append(problems, err.Error())
+		}
+		return problems, nil
+	}
+	if config.Rules.Kind != 0 {
+		if err := config.Rules.Decode(&rules); err != nil {
+			return problems, nil
+		}
+	}
+	if err := ValidateRules(rules); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems, nil
+}
+
+// unknownSectionKeys reports the keys of the rules section and of each
+// custom profile that name no rule, in the order they appear in the file
+func unknownSectionKeys(config *FileConfig) []string {
+	ruleKeys := map[string]bool{}
+	yamlKeys(reflect.TypeOf(models.Rules{}), ruleKeys)
+
+	var unknown []*yaml.Node
+	collect := func(node *yaml.Node, extra string) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; !ruleKeys[key.Value] && key.Value != extra {
+				unknown = append(unknown, key)
+			}
+		}
+	}
+	collect(&config.Rules, "")
+	for name := range config.Profiles {
+		node := config.Profiles[name]
+		collect(&node, "extends")
+	}
+
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Line < unknown[j].Line })
+	problems := make([]string, len(unknown))
+	for i, key := range unknown {
+		problems[i] = fmt.Sprintf("line %d: unknown key %q", key.Line, key.Value)
+	}
+	return problems
+}
+
+// yamlKeys adds the YAML keys of the fields of a struct type to keys,
+// including those of inlined structs
+func yamlKeys(typ reflect.Type, keys map[string]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-":
+		case options == "inline":
+			yamlKeys(field.Type, keys)
+		case name != "":
+			keys[name] = true
+		default:
+			keys[strings.ToLower(field.Name)] = true
+		}
+	}
+}
+
+// RunConfigCommand implements the `config` subcommand and returns the
+// process exit code
+func RunConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 || args[0] != "validate" {
+		fmt.Fprintln(stderr, "usage: caption-validator config validate <file>")
+		return ExitUsage
+	}
+
+	problems, err := ValidateConfigFile(args[1])
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading config: %v\n", err)
+		return ExitInputUnreadable
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(stderr, "%s: %s\n", args[1], problem)
+	}
+	if len(problems) > 0 {
+		return ExitValidationFailed
+	}
+
+	fmt.Fprintf(stdout, "%s: OK\n", args[1])
+	return ExitPass
+}
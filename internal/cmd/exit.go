@@ -0,0 +1,19 @@
+package cmd
+
+// Process exit codes. They are part of the command-line interface and are
+// documented in the Readme; do not renumber them.
+const (
+	// ExitPass means validation ran and no finding reached --fail-on
+	ExitPass = 0
+	// ExitValidationFailed means at least one finding reached --fail-on
+	ExitValidationFailed = 1
+	// ExitUsage means the flags or configuration were invalid
+	ExitUsage = 2
+	// ExitInputUnreadable means the caption input could not be read or parsed
+	ExitInputUnreadable = 3
+	// ExitDetectorUnavailable means the language detection endpoint failed
+	ExitDetectorUnavailable = 4
+	// ExitOutputFailed means the report or the HTML report could not be
+	// written
+	ExitOutputFailed = 5
+)
@@ -3,54 +3,422 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+	"github.com/theCompanyDream/srt-test/internal/report"
+	"github.com/theCompanyDream/srt-test/internal/utils"
 )
 
+// settingValues are the times and sizes that flags and a config file set
+// alike, before they are parsed
+type settingValues struct {
+	start, end, languageWindow, maxInputSize, maxLineSize, fetchTimeout string
+}
+
+// settingDefaults are the values of settings that are not set. Without an
+// end time, a container's duration is used.
+var settingDefaults = settingValues{
+	start:          "0s",
+	languageWindow: "0s",
+	maxInputSize:   "50MB",
+	maxLineSize:    "8MB",
+	fetchTimeout:   parse.DefaultFetchTimeout.String(),
+}
+
+// settings are the parsed times and sizes
+type settings struct {
+	start, end, languageWindow, fetchTimeout time.Duration
+	maxInputSize, maxLineSize                int64
+}
+
+// parseSettings parses and checks the times and sizes and returns every
+// problem found
+func parseSettings(values settingValues) (settings, []error) {
+	var s settings
+	var errs []error
+	var err error
+	startOK, endOK, windowOK := true, true, true
+	if s.start, err = time.ParseDuration(values.start); err != nil {
+		errs = append(errs, fmt.Errorf("invalid start time format: %v", err))
+		startOK = false
+	}
+	if values.end != "" {
+		if s.end, err = time.ParseDuration(values.end); err != nil {
+			errs = append(errs, fmt.Errorf("invalid end time format: %v", err))
+			endOK = false
+		} else if startOK && s.start >= s.end {
+			errs = append(errs, fmt.Errorf("start time must be less than end time"))
+		}
+	}
+
+	if s.languageWindow, err = time.ParseDuration(values.languageWindow); err != nil || s.languageWindow < 0 {
+		errs = append(errs, fmt.Errorf("invalid language window %q", values.languageWindow))
+		windowOK = false
+	}
+	// Without an end time, the windows of a container's duration are
+	// counted once it is read
+	if startOK && endOK && windowOK {
+		if err := utils.CheckLanguageWindow(s.start, s.end, s.languageWindow); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.maxInputSize, err = ParseByteSize(values.maxInputSize); err != nil || s.maxInputSize <= 0 {
+		errs = append(errs, fmt.Errorf("invalid max input size %q", values.maxInputSize))
+	}
+	if s.maxLineSize, err = ParseByteSize(values.maxLineSize); err != nil || s.maxLineSize <= 0 || s.maxLineSize > math.MaxInt32 {
+		errs = append(errs, fmt.Errorf("invalid max line size %q", values.maxLineSize))
+	}
+	if s.fetchTimeout, err = time.ParseDuration(values.fetchTimeout); err != nil || s.fetchTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("invalid fetch timeout %q", values.fetchTimeout))
+	}
+	return s, errs
+}
+
 func ParseFlags() (*models.Config, error) {
+	return ParseArgs(os.Args[1:])
+}
+
+// ParseArgs builds the configuration from command-line arguments and an
+// optional --config file. Values are layered from lowest to highest
+// precedence: defaults, the selected profile, the config file, and flags
+// that are set explicitly.
+func ParseArgs(args []string) (*models.Config, error) {
+	defaults := DefaultRules()
+	fs := flag.NewFlagSet("caption-validator", flag.ContinueOnError)
 	var (
-		filePath = flag.String("file", "", "Path to caption file (required)")
-		tStart   = flag.String("start", "0s", "Start time (e.g., 30s, 1m30s)")
-		tEnd     = flag.String("end", "", "End time (required)")
-		coverage = flag.Float64("coverage", 0.8, "Required coverage percentage (0.0-1.0)")
-		endpoint = flag.String("endpoint", "", "Language detection endpoint URL (required)")
+		configPath   = fs.String("config", "", "YAML or JSON configuration file")
+		jobs         = fs.Int("jobs", runtime.NumCPU(), "Number of files validated concurrently")
+		inputFormat  = fs.String("input-format", "", fmt.Sprintf("Force the caption format (%s) instead of detecting it", strings.Join(parse.Formats, ", ")))
+		maxInputSize = fs.String("max-input-size", settingDefaults.maxInputSize, "Maximum size of stdin or URL input (e.g., 512KB, 10MB)")
+		maxLineSize  = fs.String("max-line-size", settingDefaults.maxLineSize, "Longest input line accepted (e.g., 64KB, 16MB)")
+		fetchTimeout = fs.String("fetch-timeout", settingDefaults.fetchTimeout, "Timeout for downloading URL input")
+		fps          = fs.String("fps", "", "Frame rate of frame-based captions such as MicroDVD (e.g., 25, 23.976, 24000/1001)")
+		track        = fs.String("track", "", "ID or language of the only caption track validated in a file that holds several")
+		asrMapping   = fs.String("asr-mapping", "", "Where the timings of an ASR JSON transcript are (whisper, transcribe, generic or key=value,...)")
+		tStart       = fs.String("start", settingDefaults.start, "Start time (e.g., 30s, 1m30s)")
+		tEnd         = fs.String("end", "", "End time (required unless every input is an MKV or MP4 file, whose duration is used)")
+		endpoint     = fs.String("endpoint", "", "Language detection endpoint URL (required)")
+		language     = fs.String("lang", "en-US", "Expected caption language")
+		profile      = fs.String("profile", "", "Style-guide profile (broadcast, streaming-adult, streaming-kids or a custom profile)")
+		profilesPath = fs.String("profiles", "", "YAML or JSON file with custom profiles")
+		coverage     = fs.Float64("coverage", defaults.Coverage, "Required coverage percentage (0.0-1.0)")
+		maxCPS       = fs.Float64("max-cps", defaults.MaxCPS, "Maximum reading speed in characters per second (0 disables)")
+		maxLineChars = fs.Int("max-line-chars", 0, "Maximum characters per line (0 uses the language default)")
+		maxLines     = fs.Int("max-lines", 0, "Maximum lines per cue (0 uses the language default)")
+		lineLimits   = fs.String("line-limits", "", "Per-language line limits (e.g., ja=13:2,de=40:2)")
+		minGap       = fs.Duration("min-gap", defaults.MinGap, "Minimum gap between consecutive cues (0 disables)")
+		minDuration  = fs.Duration("min-duration", defaults.MinDuration, "Minimum cue duration (0 disables)")
+		maxDuration  = fs.Duration("max-duration", defaults.MaxDuration, "Maximum cue duration (0 disables)")
+		allowedTags  = fs.String("allowed-tags", "", "Comma separated formatting tags cues may use (e.g., i,b)")
+		format       = fs.String("format", report.FormatJSONLines, "Report format (jsonl, json, junit, sarif, text)")
+		htmlPath     = fs.String("html", "", "Also write a self-contained HTML report to this path")
+		langWindow   = fs.String("language-window", settingDefaults.languageWindow, "Detect the language separately in windows of this size (0 disables)")
+		failOn       = fs.String("fail-on", string(models.SeverityError), "Lowest finding severity that fails validation (error, warning, info, none)")
 	)
-	flag.Parse()
+	var filePaths StringList
+	fs.Var(&filePaths, "file", "Caption file, directory or glob pattern; repeat for several (required)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
 
-	if *filePath == "" {
+	file := &FileConfig{}
+	if *configPath != "" {
+		var err error
+		if file, err = LoadConfigFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// A flag set on the command line wins over the config file value
+	pick := func(name, flagValue, fileValue string) string {
+		if setFlags[name] || fileValue == "" {
+			return flagValue
+		}
+		return fileValue
+	}
+	inputs := []string(filePaths)
+	if !setFlags["file"] {
+		inputs = file.File
+	}
+	if !setFlags["jobs"] && file.Jobs != 0 {
+		*jobs = file.Jobs
+	}
+	startValue := pick("start", *tStart, file.Start)
+	endValue := pick("end", *tEnd, file.End)
+	endpointValue := pick("endpoint", *endpoint, file.Endpoint)
+	languageValue := pick("lang", *language, file.Language)
+	profileValue := pick("profile", *profile, file.Profile)
+	failOnValue := pick("fail-on", *failOn, file.FailOn)
+	formatValue := pick("format", *format, file.Format)
+	htmlValue := pick("html", *htmlPath, file.HTML)
+	langWindowValue := pick("language-window", *langWindow, file.LanguageWindow)
+	inputFormatValue := strings.ToLower(pick("input-format", *inputFormat, file.InputFormat))
+	maxInputSizeValue := pick("max-input-size", *maxInputSize, file.MaxInputSize)
+	maxLineSizeValue := pick("max-line-size", *maxLineSize, file.MaxLineSize)
+	fetchTimeoutValue := pick("fetch-timeout", *fetchTimeout, file.FetchTimeout)
+	fpsValue := pick("fps", *fps, file.FPS)
+	asrMappingValue := pick("asr-mapping", *asrMapping, file.ASRMapping)
+	trackValue := pick("track", *track, file.Track)
+
+	if len(inputs) == 0 || inputs[0] == "" {
 		return nil, fmt.Errorf("file path is required")
 	}
-	if *tEnd == "" {
+	if *jobs < 1 {
+		return nil, fmt.Errorf("jobs must be at least 1")
+	}
+	if inputFormatValue != "" && !parse.IsSupportedFormat(inputFormatValue) {
+		return nil, fmt.Errorf("unsupported input format %q (expected %s)", inputFormatValue, strings.Join(parse.Formats, ", "))
+	}
+	if endValue == "" && !containerInputs(inputs, inputFormatValue) {
 		return nil, fmt.Errorf("end time is required")
 	}
-	if *endpoint == "" {
+	if endpointValue == "" {
 		return nil, fmt.Errorf("endpoint URL is required")
 	}
 
-	startTime, err := time.ParseDuration(*tStart)
+	parsed, errs := parseSettings(settingValues{
+		start:          startValue,
+		end:            endValue,
+		languageWindow: langWindowValue,
+		maxInputSize:   maxInputSizeValue,
+		maxLineSize:    maxLineSizeValue,
+		fetchTimeout:   fetchTimeoutValue,
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	if !report.IsValidFormat(formatValue) {
+		return nil, fmt.Errorf("unsupported output format %q (expected %s)", formatValue, strings.Join(report.Formats, ", "))
+	}
+
+	if fpsValue != "" {
+		if _, err := parse.ParseFrameRate(fpsValue); err != nil {
+			return nil, err
+		}
+	}
+	if asrMappingValue != "" {
+		if _, err := parse.ParseASRMapping(asrMappingValue); err != nil {
+			return nil, fmt.Errorf("invalid ASR mapping: %v", err)
+		}
+	}
+
+	failOnSeverity, err := models.ParseSeverity(failOnValue)
 	if err != nil {
-		return nil, fmt.Errorf("invalid start time format: %v", err)
+		return nil, fmt.Errorf("invalid fail-on threshold: %v", err)
+	}
+
+	custom := file.Profiles
+	if *profilesPath != "" {
+		extra, err := LoadProfiles(*profilesPath)
+		if err != nil {
+			return nil, err
+		}
+		if custom == nil {
+			custom = make(Profiles)
+		}
+		for name, node := range extra {
+			custom[name] = node
+		}
 	}
 
-	endTime, err := time.ParseDuration(*tEnd)
+	rules, err := ResolveProfile(profileValue, custom)
 	if err != nil {
-		return nil, fmt.Errorf("invalid end time format: %v", err)
+		return nil, err
+	}
+	if file.Rules.Kind != 0 {
+		if err := file.Rules.Decode(&rules); err != nil {
+			return nil, fmt.Errorf("invalid rules in config file: %v", err)
+		}
 	}
 
-	if startTime >= endTime {
-		return nil, fmt.Errorf("start time must be less than end time")
+	// Explicit rule flags take precedence over the profile and config file
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "coverage":
+			rules.Coverage = *coverage
+		case "max-cps":
+			rules.MaxCPS = *maxCPS
+		case "max-line-chars":
+			rules.MaxCharsPerLine = *maxLineChars
+		case "max-lines":
+			rules.MaxLines = *maxLines
+		case "min-gap":
+			rules.MinGap = *minGap
+		case "min-duration":
+			rules.MinDuration = *minDuration
+		case "max-duration":
+			rules.MaxDuration = *maxDuration
+		case "allowed-tags":
+			rules.AllowedTags = ParseTagList(*allowedTags)
+		case "line-limits":
+			limits, err := ParseLineLimits(*lineLimits)
+			if err != nil {
+				flagErr = fmt.Errorf("invalid line limits: %v", err)
+				return
+			}
+			if rules.LanguageLineLimits == nil {
+				rules.LanguageLineLimits = make(map[string]models.LineLimits)
+			}
+			for lang, l := range limits {
+				rules.LanguageLineLimits[lang] = l
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
 	}
 
-	if *coverage < 0 || *coverage > 1 {
-		return nil, fmt.Errorf("coverage must be between 0.0 and 1.0")
+	if err := ValidateRules(rules); err != nil {
+		return nil, err
 	}
 
 	return &models.Config{
-		FilePath: *filePath,
-		TStart:   startTime,
-		TEnd:     endTime,
-		Coverage: *coverage,
-		Endpoint: *endpoint,
+		FilePath:       inputs[0],
+		TStart:         parsed.start,
+		TEnd:           parsed.end,
+		Endpoint:       endpointValue,
+		Language:       languageValue,
+		Profile:        profileValue,
+		Rules:          rules,
+		FailOn:         failOnSeverity,
+		Format:         formatValue,
+		HTMLPath:       htmlValue,
+		LanguageWindow: parsed.languageWindow,
+		Inputs:         inputs,
+		Jobs:           *jobs,
+		InputFormat:    inputFormatValue,
+		MaxInputSize:   parsed.maxInputSize,
+		FetchTimeout:   parsed.fetchTimeout,
+		MaxLineSize:    int(parsed.maxLineSize),
+		FrameRate:      fpsValue,
+		ASRMapping:     asrMappingValue,
+		Track:          trackValue,
 	}, nil
 }
+
+// containerInputs reports whether every input is a container file, which
+// declares its duration
+func containerInputs(inputs []string, format string) bool {
+	if format != "" {
+		return parse.IsContainerFormat(format)
+	}
+	for _, input := range inputs {
+		if !parse.IsContainerFormat(parse.FormatFromPath(input)) {
+			return false
+		}
+	}
+	return true
+}
+
+// StringList collects the values of a repeatable flag. In a config file it
+// accepts a single string or a list.
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err
+		}
+		*l = StringList{s}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// ParseByteSize reads a size in bytes with an optional KB, MB or GB suffix
+// (powers of 1024)
+func ParseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	size := value
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size %q is too large", size)
+	}
+	return n * multiplier, nil
+}
+
+// ParseTagList splits a comma separated list of tag names. An empty value
+// yields an empty, non-nil list so that no tags are allowed.
+func ParseTagList(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, strings.ToLower(tag))
+		}
+	}
+	return tags
+}
+
+// ParseLineLimits reads a comma separated list of lang=chars:lines entries
+func ParseLineLimits(value string) (map[string]models.LineLimits, error) {
+	limits := make(map[string]models.LineLimits)
+	if strings.TrimSpace(value) == "" {
+		return limits, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		lang, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || lang == "" {
+			return nil, fmt.Errorf("expected lang=chars:lines, got %q", entry)
+		}
+
+		charsStr, linesStr, _ := strings.Cut(spec, ":")
+		chars, err := strconv.Atoi(charsStr)
+		if err != nil || chars < 0 {
+			return nil, fmt.Errorf("invalid character limit in %q", entry)
+		}
+		lines := 0
+		if linesStr != "" {
+			if lines, err = strconv.Atoi(linesStr); err != nil || lines < 0 {
+				return nil, fmt.Errorf("invalid line limit in %q", entry)
+			}
+		}
+
+		limits[strings.ToLower(lang)] = models.LineLimits{MaxCharsPerLine: chars, MaxLines: lines}
+	}
+	return limits, nil
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// Profiles maps profile names to their definitions in a profiles file.
+// A definition may name the profile it builds on with an `extends` key.
+type Profiles map[string]yaml.Node
+
+type profileHeader struct {
+	Extends string `yaml:"extends"`
+}
+
+// DefaultRules returns the rules applied when no profile is selected
+func DefaultRules() models.Rules {
+	return models.Rules{
+		Coverage:    0.8,
+		MinDuration: 833 * time.Millisecond,
+		MaxDuration: 7 * time.Second,
+	}
+}
+
+// BuiltinProfiles holds the style guides shipped with the validator
+var BuiltinProfiles = map[string]models.Rules{
+	"broadcast": {
+		Coverage:    0.9,
+		MaxCPS:      17,
+		LineLimits:  models.LineLimits{MaxCharsPerLine: 37, MaxLines: 2},
+		MinGap:      80 * time.Millisecond,
+		MinDuration: time.Second,
+		MaxDuration: 7 * time.Second,
+		AllowedTags: []string{"i"},
+	},
+	"streaming-adult": {
+		Coverage:    0.8,
+		MaxCPS:      20,
+		MinGap:      83 * time.Millisecond,
+		MinDuration: 833 * time.Millisecond,
+		MaxDuration: 7 * time.Second,
+		AllowedTags: []string{"i", "b", "u"},
+	},
+	"streaming-kids": {
+		Coverage:    0.8,
+		MaxCPS:      17,
+		MinGap:      83 * time.Millisecond,
+		MinDuration: 833 * time.Millisecond,
+		MaxDuration: 7 * time.Second,
+		AllowedTags: []string{"i"},
+	},
+}
+
+// LoadProfiles reads custom profiles from the `profiles` key of a YAML or
+// JSON file
+func LoadProfiles(path string) (Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Profiles Profiles `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid profiles file %s: %v", path, err)
+	}
+	return file.Profiles, nil
+}
+
+// ProfileNames lists every built-in and custom profile name, sorted
+func ProfileNames(custom Profiles) []string {
+	var names []string
+	for name := range BuiltinProfiles {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := BuiltinProfiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProfile returns the rules of the named profile. A custom profile
+// is applied on top of the profile it extends, or on top of the built-in
+// profile with the same name, or on top of DefaultRules. An empty name
+// resolves to DefaultRules.
+func ResolveProfile(name string, custom Profiles) (models.Rules, error) {
+	return resolveProfile(name, custom, map[string]bool{})
+}
+
+func resolveProfile(name string, custom Profiles, visiting map[string]bool) (models.Rules, error) {
+	if name == "" {
+		return DefaultRules(), nil
+	}
+	if visiting[name] {
+		return models.Rules{}, fmt.Errorf("profile %q extends itself", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	node, isCustom := custom[name]
+	builtin, isBuiltin := BuiltinProfiles[name]
+	if !isCustom {
+		if !isBuiltin {
+			return models.Rules{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(ProfileNames(custom), ", "))
+		}
+		return cloneRules(builtin), nil
+	}
+
+	var header profileHeader
+	if err := node.Decode(&header); err != nil {
+		return models.Rules{}, fmt.Errorf("invalid profile %q: %v", name, err)
+	}
+
+	var base models.Rules
+	switch {
+	case header.Extends == name && isBuiltin:
+		base = cloneRules(builtin)
+	case header.Extends != "":
+		var err error
+		if base, err = resolveProfile(header.Extends, custom, visiting); err != nil {
+			return models.Rules{}, err
+		}
+	case isBuiltin:
+		base = cloneRules(builtin)
+	default:
+		base = DefaultRules()
+	}
+
+	if err := node.Decode(&base); err != nil {
+		return models.Rules{}, fmt.Errorf("invalid profile %q: %v", name, err)
+	}
+	return base, nil
+}
+
+// cloneRules copies rules so later overrides do not alter the original
+func cloneRules(rules models.Rules) models.Rules {
+	if rules.LanguageLineLimits != nil {
+		limits := make(map[string]models.LineLimits, len(rules.LanguageLineLimits))
+		for lang, l := range rules.LanguageLineLimits {
+			limits[lang] = l
+		}
+		rules.LanguageLineLimits = limits
+	}
+	if rules.AllowedTags != nil {
+		rules.AllowedTags = append([]string{}, rules.AllowedTags...)
+	}
+	return rules
+}
+
+// ValidateRules checks that every threshold is within its valid range
+func ValidateRules(rules models.Rules) error {
+	if rules.Coverage < 0 || rules.Coverage > 1 {
+		return fmt.Errorf("coverage must be between 0.0 and 1.0")
+	}
+	if rules.MaxCPS < 0 {
+		return fmt.Errorf("reading speed must not be negative")
+	}
+	if rules.MaxCharsPerLine < 0 || rules.MaxLines < 0 {
+		return fmt.Errorf("line limits must not be negative")
+	}
+	for lang, limits := range rules.LanguageLineLimits {
+		if limits.MaxCharsPerLine < 0 || limits.MaxLines < 0 {
+			return fmt.Errorf("line limits for %s must not be negative", lang)
+		}
+	}
+	if rules.MinGap < 0 {
+		return fmt.Errorf("minimum gap must not be negative")
+	}
+	if rules.MinDuration < 0 || rules.MaxDuration < 0 {
+		return fmt.Errorf("cue durations must not be negative")
+	}
+	if rules.MaxDuration > 0 && rules.MinDuration > rules.MaxDuration {
+		return fmt.Errorf("minimum cue duration must not exceed maximum cue duration")
+	}
+	return nil
+}
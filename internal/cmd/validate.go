@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+// ValidateTracks runs every check on the caption file named in config and
+// returns one report per caption track. Most formats hold a single track;
+// a SAMI file holds one per language class and a container file one per
+// subtitle track, each checked against the language it declares, unless
+// config.Track chooses one. Input that cannot be read yields a single
+// report whose exit code and reason tell why.
+func ValidateTracks(config *models.Config) []*models.Report {
+	format := config.InputFormat
+	if format == "" {
+		format = parse.FormatFromPath(config.FilePath)
+	}
+	base := models.Report{
+		File:   parse.DisplayName(config.FilePath),
+		Format: format,
+		Config: models.ReportConfig{
+			Start:      config.TStart.String(),
+			End:        config.TEnd.String(),
+			Endpoint:   config.Endpoint,
+			Language:   config.Language,
+			Profile:    config.Profile,
+			FailOn:     config.FailOn,
+			Rules:      config.Rules,
+			FrameRate:  config.FrameRate,
+			ASRMapping: config.ASRMapping,
+		},
+		Checks: []string{models.CheckParse},
+		Start:  config.TStart,
+		End:    config.TEnd,
+	}
+	if config.LanguageWindow > 0 {
+		base.Config.LanguageWindow = config.LanguageWindow.String()
+	}
+
+	// Open the captions, detecting the format from the content
+	opts := parse.Options{
+		Format:       config.InputFormat,
+		MaxInputSize: config.MaxInputSize,
+		FetchTimeout: config.FetchTimeout,
+		MaxLineSize:  config.MaxLineSize,
+		Track:        config.Track,
+	}
+	if config.FrameRate != "" {
+		rate, err := parse.ParseFrameRate(config.FrameRate)
+		if err != nil {
+			return []*models.Report{inputError(&base, err.Error())}
+		}
+		opts.FrameRate = rate
+	}
+	if config.ASRMapping != "" {
+		mapping, err := parse.ParseASRMapping(config.ASRMapping)
+		if err != nil {
+			return []*models.Report{inputError(&base, fmt.Sprintf("invalid ASR mapping: %v", err))}
+		}
+		opts.ASRMapping = &mapping
+	}
+	stream, err := parse.OpenStream(config.FilePath, opts)
+	if err != nil {
+		return []*models.Report{readError(&base, err)}
+	}
+	defer stream.Close()
+
+	// A container lists its tracks before any cue, so --html is refused
+	// before they are validated
+	if config.HTMLPath != "" && config.Track == "" && len(stream.Tracks) > 1 {
+		return []*models.Report{usageError(&base, "--html requires a single caption track; choose one with --track")}
+	}
+
+	// Without an end time the range ends with the container
+	if config.TEnd == 0 {
+		if stream.Duration <= config.TStart {
+			return []*models.Report{inputError(&base, fmt.Sprintf("end time is required: %s input declares no duration after the start time", parse.FormatNames[stream.Format]))}
+		}
+		withEnd := *config
+		withEnd.TEnd = stream.Duration
+		config = &withEnd
+		base.End, base.Config.End = config.TEnd, config.TEnd.String()
+		if err := utils.CheckLanguageWindow(config.TStart, config.TEnd, config.LanguageWindow); err != nil {
+			return []*models.Report{inputError(&base, err.Error())}
+		}
+	}
+
+	// Read the cues one at a time into the track each belongs to, keeping
+	// only what the checks that run after the last cue need
+	tracks := map[string]*trackValidation{}
+	var order []*trackValidation
+	err = parse.ForEachCue(stream, func(cue parse.Cue) error {
+		track, ok := tracks[cue.Track]
+		if !ok {
+			track = newTrackValidation(config, base, cue, stream.Language)
+			tracks[cue.Track] = track
+			order = append(order, track)
+		}
+		track.add(cue, config.HTMLPath != "")
+		return nil
+	})
+	if err != nil {
+		return []*models.Report{readError(&base, err)}
+	}
+	// Every subtitle track of a container is reported, even one without
+	// cues
+	if parse.IsContainerFormat(stream.Format) {
+		for _, track := range stream.Tracks {
+			if config.Track != "" && !parse.TrackMatches(track, config.Track) {
+				continue
+			}
+			if _, ok := tracks[track.ID]; !ok {
+				empty := parse.Cue{CaptionEntry: models.CaptionEntry{Track: track.ID, Language: track.Language}}
+				tracks[track.ID] = newTrackValidation(config, base, empty, stream.Language)
+				order = append(order, tracks[track.ID])
+			}
+			// The first track that matches is the one chosen
+			if config.Track != "" {
+				break
+			}
+		}
+	}
+	if len(order) == 0 {
+		order = append(order, newTrackValidation(config, base, parse.Cue{}, stream.Language))
+	}
+
+	reports := make([]*models.Report, len(order))
+	for i, track := range order {
+		reports[i] = track.finish(config, stream)
+	}
+	return reports
+}
+
+// trackValidation holds the state of validating one caption track while
+// its cues are read
+type trackValidation struct {
+	report *models.Report
+	// language is the language the track is expected to be in
+	language    string
+	checks      []string
+	cueChecks   []utils.CueCheck
+	coverage    *utils.Coverage
+	windowTexts *utils.WindowTexts
+	allText     strings.Builder
+	diagnostics []models.ValidationError
+}
+
+// newTrackValidation starts validating the track of the first cue. The
+// track is expected to be in the language its first cue declares, such as
+// that of a SAMI class or a TTML xml:lang, or else in the language declared
+// for the whole input, such as by an EBU STL header; config.Language
+// applies to neither.
+func newTrackValidation(config *models.Config, base models.Report, first parse.Cue, declared string) *trackValidation {
+	report := base
+	t := &trackValidation{report: &report, language: config.Language}
+	if first.Track != "" {
+		report.Track = &models.Track{ID: first.Track, Language: first.Language}
+	}
+	if first.Language != "" {
+		declared = first.Language
+	}
+	if declared != "" {
+		t.language = declared
+		report.Config.Language = declared
+	}
+
+	// Per-cue checks run on each cue as it is read, in report order
+	rules := config.Rules
+	t.checks = []string{models.CheckParse, models.CheckCoverage, models.CheckLineLimits}
+	lineLimits := utils.LineLimitsFor(t.language, rules.LineLimits, rules.LanguageLineLimits)
+	t.cueChecks = []utils.CueCheck{utils.NewLineLimitsCheck(lineLimits)}
+	if rules.MinDuration > 0 || rules.MaxDuration > 0 {
+		t.checks = append(t.checks, models.CheckCueDuration)
+		t.cueChecks = append(t.cueChecks, utils.NewDurationCheck(rules.MinDuration, rules.MaxDuration))
+	}
+	// Overlaps are always reported; the minimum gap only when it is set
+	t.checks = append(t.checks, models.CheckCueGap)
+	t.cueChecks = append(t.cueChecks, utils.NewGapCheck(rules.MinGap))
+	if rules.MaxCPS > 0 {
+		t.checks = append(t.checks, models.CheckReadingSpeed)
+		t.cueChecks = append(t.cueChecks, utils.NewReadingSpeedCheck(rules.MaxCPS))
+	}
+	if rules.AllowedTags != nil {
+		t.checks = append(t.checks, models.CheckTags)
+		t.cueChecks = append(t.cueChecks, utils.NewTagCheck(rules.AllowedTags))
+	}
+	t.coverage = utils.NewCoverage(config.TStart, config.TEnd)
+	t.windowTexts = utils.NewWindowTexts(config.TStart, config.TEnd, config.LanguageWindow)
+	return t
+}
+
+// add runs the per-cue checks on the next cue of the track
+func (t *trackValidation) add(cue parse.Cue, keepCues bool) {
+	index := t.report.Metrics.CueCount
+	t.report.Metrics.CueCount++
+	t.diagnostics = append(t.diagnostics, diagnosticFindings(index, cue)...)
+	t.coverage.Add(cue.CaptionEntry)
+	for _, check := range t.cueChecks {
+		check.Add(index, cue.CaptionEntry)
+	}
+	if strings.TrimSpace(cue.Text) != "" {
+		if t.allText.Len() > 0 {
+			t.allText.WriteByte(' ')
+		}
+		t.allText.WriteString(cue.Text)
+	}
+	t.windowTexts.Add(cue.CaptionEntry)
+	if keepCues {
+		t.report.Cues = append(t.report.Cues, cue.CaptionEntry)
+	}
+}
+
+// finish runs the checks that need every cue of the track and completes
+// its report
+func (t *trackValidation) finish(config *models.Config, stream *parse.Stream) *models.Report {
+	report := t.report
+	rules := config.Rules
+	report.Format = stream.Format
+	report.Encoding = stream.Encoding
+	report.Checks = append(t.checks, models.CheckLanguage)
+	if report.Track != nil {
+		for _, track := range stream.Tracks {
+			if track.ID == report.Track.ID {
+				report.Track.Name = track.Name
+			}
+		}
+	}
+
+	var validationErrors []models.ValidationError
+	if stream.ExtensionMismatch() {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Type:        "format_mismatch",
+			Description: fmt.Sprintf("File extension .%s does not match its %s content", stream.Extension, parse.FormatNames[stream.Detected]),
+			Code:        models.CodeFormatMismatch,
+			Severity:    models.SeverityWarning,
+		})
+	}
+	if stream.Encoding != parse.EncodingUTF8 && !parse.IsBinaryFormat(stream.Format) {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Type:        "encoding",
+			Description: fmt.Sprintf("Captions are encoded as %s, not UTF-8", stream.Encoding),
+			Code:        models.CodeEncoding,
+			Severity:    models.SeverityWarning,
+		})
+	}
+	validationErrors = append(validationErrors, t.diagnostics...)
+
+	// Validate coverage
+	report.Metrics.Coverage = t.coverage.Ratio()
+	if !t.coverage.Meets(rules.Coverage) {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Type:        "insufficient_coverage",
+			Description: fmt.Sprintf("Captions do not cover required %.1f%% of time range %v to %v", rules.Coverage*100, config.TStart, config.TEnd),
+			Code:        models.CodeCoverage,
+			Severity:    models.SeverityError,
+		})
+	}
+
+	// Line limits, cue timing and formatting tags
+	for _, check := range t.cueChecks {
+		validationErrors = append(validationErrors, check.Findings()...)
+	}
+
+	// Validate language
+	var detectorErr error
+	text := t.allText.String()
+	if text == "" {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Type:        "invalid_language",
+			Description: "Captions contain no text to detect the language of",
+			Code:        models.CodeLanguage,
+			Severity:    models.SeverityError,
+		})
+	} else if lang, err := utils.DetectLanguage(text, config.Endpoint); err != nil {
+		detectorErr = err
+		validationErrors = append(validationErrors, models.ValidationError{
+			Type:        "language_detection_failed",
+			Description: fmt.Sprintf("Language detection failed: %v", err),
+			Code:        models.CodeLanguageDetection,
+			Severity:    models.SeverityError,
+		})
+	} else {
+		report.Metrics.DetectedLanguage = lang
+		if !utils.LanguageMatches(lang, t.language) {
+			validationErrors = append(validationErrors, models.ValidationError{
+				Type:        "invalid_language",
+				Description: fmt.Sprintf("Caption language is %s, expected %s", lang, t.language),
+				Code:        models.CodeLanguage,
+				Severity:    models.SeverityError,
+			})
+		}
+	}
+
+	// Validate the language of each window of the range
+	if config.LanguageWindow > 0 && detectorErr == nil && text != "" {
+		report.LanguageWindows = t.windowTexts.Detect(config.Endpoint, t.language)
+		for _, window := range report.LanguageWindows {
+			if window.Language == "" || window.Matches {
+				continue
+			}
+			validationErrors = append(validationErrors, models.ValidationError{
+				Type: "window_language_mismatch",
+				Description: fmt.Sprintf("Captions from %s to %s are in %s, expected %s",
+					utils.FormatTimestamp(window.Start), utils.FormatTimestamp(window.End), window.Language, t.language),
+				Code:     models.CodeWindowLanguage,
+				Severity: models.SeverityWarning,
+				Location: &models.Location{Start: window.Start, End: window.End},
+			})
+		}
+	}
+
+	report.Findings = utils.ApplySeverities(validationErrors, rules.Severities)
+
+	switch {
+	case detectorErr != nil:
+		report.Verdict = models.VerdictError
+		report.ExitCode = ExitDetectorUnavailable
+		report.Reason = fmt.Sprintf("language detection endpoint unavailable: %v", detectorErr)
+	case utils.FailsThreshold(report.Findings, config.FailOn):
+		report.Verdict = models.VerdictFail
+		report.ExitCode = ExitValidationFailed
+		report.Reason = fmt.Sprintf("findings at or above %s severity", config.FailOn)
+	default:
+		report.Verdict = models.VerdictPass
+		report.ExitCode = ExitPass
+	}
+	return report
+}
+
+// readError completes a report for input that could not be opened or read
+func readError(report *models.Report, err error) *models.Report {
+	// A --track choice that matches no track is a usage error
+	if errors.Is(err, parse.ErrNoTrack) {
+		return usageError(report, err.Error())
+	}
+	if errors.Is(err, parse.ErrUnknownFormat) {
+		return inputError(report, fmt.Sprintf("unsupported caption file type %q (expected %s content)", report.File, formatNames()))
+	}
+	var lineErr *parse.LineTooLongError
+	if errors.As(err, &lineErr) {
+		return inputError(report, fmt.Sprintf("failed to read caption file: %v (raise --max-line-size to accept it)", err))
+	}
+	return inputError(report, fmt.Sprintf("failed to read caption file: %v", err))
+}
+
+// formatNames lists the display names of the formats that can be parsed,
+// such as "SRT, WebVTT or TTML"
+func formatNames() string {
+	names := make([]string, len(parse.Formats))
+	for i, format := range parse.Formats {
+		names[i] = parse.FormatNames[format]
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// diagnosticFindings reports the problems found while parsing a cue
+func diagnosticFindings(index int, cue parse.Cue) []models.ValidationError {
+	var findings []models.ValidationError
+	for _, diag := range cue.Diagnostics {
+		description := diag.Message
+		if diag.Line > 0 {
+			description = fmt.Sprintf("Line %d: %s", diag.Line, diag.Message)
+		}
+		finding := models.ValidationError{
+			Type:        "malformed_cue",
+			Description: description,
+			Code:        models.CodeMalformedCue,
+			Severity:    models.SeverityWarning,
+			Location: &models.Location{
+				Cue:     index + 1,
+				Start:   cue.StartTime,
+				End:     cue.EndTime,
+				Line:    diag.Line,
+				Excerpt: parse.TextPreview(cue.Text),
+			},
+		}
+		// A cue repeated across HLS segments is expected of packagers, so
+		// it is only reported
+		if diag.Code == models.CodeDuplicateCue {
+			finding.Type, finding.Code, finding.Severity = "duplicate_cue", models.CodeDuplicateCue, models.SeverityInfo
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// usageError fails report because the flags do not suit the input
+func usageError(report *models.Report, reason string) *models.Report {
+	report.Verdict = models.VerdictError
+	report.ExitCode = ExitUsage
+	report.Reason = reason
+	return report
+}
+
+// inputError completes a report for input that could not be read
+func inputError(report *models.Report, reason string) *models.Report {
+	report.Findings = append(report.Findings, models.ValidationError{
+		Type:        "file_parse_error",
+		Description: strings.ToUpper(reason[:1]) + reason[1:],
+		Code:        models.CodeParseError,
+		Severity:    models.SeverityError,
+	})
+	report.Verdict = models.VerdictError
+	report.ExitCode = ExitInputUnreadable
+	report.Reason = reason
+	return report
+}
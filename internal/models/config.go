@@ -1,10 +1,16 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type ValidationError struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Code        string    `json:"code,omitempty"`
+	Severity    Severity  `json:"severity,omitempty"`
+	Location    *Location `json:"location,omitempty"`
 }
 
 // LangResponse represents the response from the language detection endpoint
@@ -17,6 +23,64 @@ type CaptionEntry struct {
 	StartTime time.Duration
 	EndTime   time.Duration
 	Text      string
+	// Lines holds the cue text as it was laid out in the source file
+	Lines []string
+	// Line is the 1-based source line of the cue timing, 0 if unknown
+	Line int
+	// Language is the language the source declares for the cue, if any
+	Language string
+	// Metadata holds format-specific cue attributes, such as the style and
+	// actor of an ASS dialogue line
+	Metadata map[string]string
+	// Track is the ID of the track the cue belongs to in a file that holds
+	// several, such as a SAMI language class
+	Track string
+}
+
+// Track is one of several caption tracks held in one file
+type Track struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Language string `json:"lang,omitempty"`
+}
+
+// LineLimits caps how a single cue may be laid out on screen.
+// A zero field means the limit is not set.
+type LineLimits struct {
+	MaxCharsPerLine int `yaml:"max_chars_per_line" json:"max_chars_per_line,omitempty"`
+	MaxLines        int `yaml:"max_lines" json:"max_lines,omitempty"`
+}
+
+// Rules bundles the thresholds a caption file is validated against.
+// A zero threshold disables its check.
+type Rules struct {
+	Coverage float64 `yaml:"coverage" json:"coverage"`
+	MaxCPS   float64 `yaml:"max_cps" json:"max_cps,omitempty"`
+	// LineLimits applies to languages without limits of their own, in
+	// LanguageLineLimits or the built-in per-language table
+	LineLimits `yaml:",inline"`
+	// LanguageLineLimits holds line limits keyed by language tag
+	LanguageLineLimits map[string]LineLimits `yaml:"language_line_limits" json:"language_line_limits,omitempty"`
+	MinGap             time.Duration         `yaml:"min_gap" json:"-"`
+	MinDuration        time.Duration         `yaml:"min_duration" json:"-"`
+	MaxDuration        time.Duration         `yaml:"max_duration" json:"-"`
+	// AllowedTags lists the formatting tags cues may use. A nil list allows
+	// any tag, an empty list allows none.
+	AllowedTags []string `yaml:"allowed_tags" json:"allowed_tags"`
+	// Severities overrides the default severity of findings, keyed by
+	// finding code or type
+	Severities map[string]Severity `yaml:"severities" json:"severities,omitempty"`
+}
+
+// MarshalJSON writes durations in the same form the config file uses
+func (r Rules) MarshalJSON() ([]byte, error) {
+	type rules Rules
+	return json.Marshal(struct {
+		rules
+		MinGap      string `json:"min_gap"`
+		MinDuration string `json:"min_duration"`
+		MaxDuration string `json:"max_duration"`
+	}{rules(r), r.MinGap.String(), r.MinDuration.String(), r.MaxDuration.String()})
 }
 
 // Config holds the program configuration
@@ -24,6 +88,39 @@ type Config struct {
 	FilePath string
 	TStart   time.Duration
 	TEnd     time.Duration
-	Coverage float64
 	Endpoint string
+	Language string
+	Profile  string
+	Rules    Rules
+	// FailOn is the lowest severity that fails validation
+	FailOn Severity
+	// Format is the report output format
+	Format string
+	// HTMLPath is where the HTML report is written, if set
+	HTMLPath string
+	// LanguageWindow splits the range into windows whose language is
+	// detected separately. Zero disables windowed detection.
+	LanguageWindow time.Duration
+	// Inputs are the --file values: files, directories or glob patterns.
+	// FilePath is the first of them.
+	Inputs []string
+	// Jobs is the number of files validated concurrently in a batch
+	Jobs int
+	// InputFormat forces the caption format instead of detecting it
+	InputFormat string
+	// MaxInputSize caps the bytes read from stdin or a URL
+	MaxInputSize int64
+	// FetchTimeout bounds the download of a URL input
+	FetchTimeout time.Duration
+	// MaxLineSize is the longest input line accepted, in bytes
+	MaxLineSize int
+	// FrameRate is the frame rate of frame-based formats such as MicroDVD,
+	// as given to --fps, or "" to use the rate the file declares
+	FrameRate string
+	// ASRMapping locates the timings of an ASR JSON transcript, as given
+	// to --asr-mapping, or "" to recognise the common shapes
+	ASRMapping string
+	// Track is the ID or language of the only caption track validated in a
+	// file that holds several, or "" for every track
+	Track string
 }
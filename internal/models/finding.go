@@ -0,0 +1,158 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Severity ranks how serious a validation finding is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	// SeverityNone is only used as a --fail-on threshold that never fails
+	SeverityNone Severity = "none"
+)
+
+// Rank orders severities so they can be compared against a threshold.
+// Unknown severities rank below info.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	}
+	return 0
+}
+
+// ParseSeverity reads a severity name, case-insensitively
+func ParseSeverity(value string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(value)))
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityInfo, SeverityNone:
+		return severity, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (expected error, warning, info or none)", value)
+}
+
+// UnmarshalYAML lets severities in config files be validated on load
+func (s *Severity) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	severity, err := ParseSeverity(value)
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// Stable finding codes. Codes never change meaning once released, so
+// pipelines can match on them instead of on descriptions.
+const (
+	CodeParseError        = "CV100"
+	CodeFormatMismatch    = "CV101"
+	CodeEncoding          = "CV102"
+	CodeMalformedCue      = "CV103"
+	CodeDuplicateCue      = "CV104"
+	CodeCoverage          = "CV200"
+	CodeLanguage          = "CV300"
+	CodeLanguageDetection = "CV301"
+	CodeWindowLanguage    = "CV302"
+	CodeLineTooLong       = "CV401"
+	CodeTooManyLines      = "CV402"
+	CodeCueTooShort       = "CV501"
+	CodeCueTooLong        = "CV502"
+	CodeGapTooShort       = "CV503"
+	CodeCueOverlap        = "CV504"
+	CodeReadingSpeed      = "CV505"
+	CodeTagNotAllowed     = "CV601"
+)
+
+// CodeSummaries gives a one-line explanation of every finding code
+var CodeSummaries = map[string]string{
+	CodeParseError:        "The caption file could not be read or parsed",
+	CodeFormatMismatch:    "The file extension does not match the caption format of the content",
+	CodeEncoding:          "The caption file is not encoded as UTF-8",
+	CodeMalformedCue:      "A cue is malformed and was read on a best-effort basis",
+	CodeDuplicateCue:      "A cue is repeated across the boundary of two HLS segments",
+	CodeCoverage:          "Captions do not cover enough of the validation range",
+	CodeLanguage:          "Captions are not in the expected language",
+	CodeLanguageDetection: "The language detection endpoint failed",
+	CodeWindowLanguage:    "Part of the captions is not in the expected language",
+	CodeLineTooLong:       "A caption line has too many characters",
+	CodeTooManyLines:      "A cue has too many lines",
+	CodeCueTooShort:       "A cue is not displayed long enough to be read",
+	CodeCueTooLong:        "A cue stays on screen too long",
+	CodeGapTooShort:       "The gap between two cues is too short",
+	CodeCueOverlap:        "Two cues overlap",
+	CodeReadingSpeed:      "A cue needs too many characters per second to read",
+	CodeTagNotAllowed:     "A cue uses a formatting tag that is not allowed",
+}
+
+// Names of the checks recorded in a report
+const (
+	CheckParse        = "parse"
+	CheckCoverage     = "coverage"
+	CheckLanguage     = "language"
+	CheckLineLimits   = "line_limits"
+	CheckCueDuration  = "cue_duration"
+	CheckCueGap       = "cue_gap"
+	CheckReadingSpeed = "reading_speed"
+	CheckTags         = "tags"
+)
+
+// CheckForCode returns the check that produces findings with the given code
+func CheckForCode(code string) string {
+	switch code {
+	case CodeParseError, CodeFormatMismatch, CodeEncoding, CodeMalformedCue, CodeDuplicateCue:
+		return CheckParse
+	case CodeCoverage:
+		return CheckCoverage
+	case CodeLanguage, CodeLanguageDetection, CodeWindowLanguage:
+		return CheckLanguage
+	case CodeLineTooLong, CodeTooManyLines:
+		return CheckLineLimits
+	case CodeCueTooShort, CodeCueTooLong:
+		return CheckCueDuration
+	case CodeGapTooShort, CodeCueOverlap:
+		return CheckCueGap
+	case CodeReadingSpeed:
+		return CheckReadingSpeed
+	case CodeTagNotAllowed:
+		return CheckTags
+	}
+	return code
+}
+
+// Location points a finding at a cue in the caption file
+type Location struct {
+	// Cue is the 1-based index of the cue
+	Cue   int
+	Start time.Duration
+	End   time.Duration
+	// Line is the 1-based source line, 0 if unknown
+	Line int
+	// Excerpt is a shortened copy of the cue text
+	Excerpt string
+}
+
+// MarshalJSON writes cue times in milliseconds
+func (l Location) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Cue     int    `json:"cue,omitempty"`
+		StartMs int64  `json:"start_ms"`
+		EndMs   int64  `json:"end_ms"`
+		Line    int    `json:"line,omitempty"`
+		Excerpt string `json:"excerpt,omitempty"`
+	}{l.Cue, l.Start.Milliseconds(), l.End.Milliseconds(), l.Line, l.Excerpt})
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Verdict summarises the outcome of validating one caption file
+type Verdict string
+
+const (
+	VerdictPass Verdict = "pass"
+	VerdictFail Verdict = "fail"
+	// VerdictError means validation could not run to completion
+	VerdictError Verdict = "error"
+)
+
+// Report is the outcome of validating one caption file
+type Report struct {
+	File string `json:"file"`
+	// Track identifies the caption track of a file that holds several
+	Track    *Track            `json:"track,omitempty"`
+	Format   string            `json:"format,omitempty"`
+	Encoding string            `json:"encoding,omitempty"`
+	Config   ReportConfig      `json:"config"`
+	Metrics  Metrics           `json:"metrics"`
+	Checks   []string          `json:"checks"`
+	Findings []ValidationError `json:"findings"`
+	Verdict  Verdict           `json:"verdict"`
+	// LanguageWindows holds per-window language results, if requested
+	LanguageWindows []LanguageWindow `json:"language_windows,omitempty"`
+	// ExitCode is the process exit code this report alone would produce
+	ExitCode int `json:"-"`
+	// Reason explains a verdict other than pass
+	Reason string `json:"reason,omitempty"`
+	// Start and End bound the validation range
+	Start time.Duration `json:"-"`
+	End   time.Duration `json:"-"`
+	// Cues is kept only when a timeline is rendered
+	Cues []CaptionEntry `json:"-"`
+}
+
+// ReportConfig echoes the settings a report was produced with
+type ReportConfig struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Endpoint string   `json:"endpoint"`
+	Language string   `json:"lang"`
+	Profile  string   `json:"profile,omitempty"`
+	FailOn   Severity `json:"fail_on"`
+	// LanguageWindow is the per-window language detection size, if any
+	LanguageWindow string `json:"language_window,omitempty"`
+	// FrameRate is the --fps frame rate, if set
+	FrameRate string `json:"fps,omitempty"`
+	// ASRMapping is the --asr-mapping setting, if set
+	ASRMapping string `json:"asr_mapping,omitempty"`
+	Rules      Rules  `json:"rules"`
+}
+
+// Metrics holds measurements taken while validating a caption file
+type Metrics struct {
+	CueCount int `json:"cue_count"`
+	// Coverage is the fraction of the validation range covered by cues
+	Coverage         float64 `json:"coverage"`
+	DetectedLanguage string  `json:"detected_language,omitempty"`
+}
+
+// BatchReport combines the reports of several caption files validated in
+// one run
+type BatchReport struct {
+	Files   []*Report   `json:"files"`
+	Totals  BatchTotals `json:"totals"`
+	Verdict Verdict     `json:"verdict"`
+	// ExitCode is the most severe exit code of any file
+	ExitCode int `json:"-"`
+	// Reason explains a verdict other than pass
+	Reason string `json:"reason,omitempty"`
+}
+
+// BatchTotals counts the files of a batch by verdict
+type BatchTotals struct {
+	Files    int `json:"files"`
+	Passed   int `json:"passed"`
+	Failed   int `json:"failed"`
+	Errored  int `json:"errored"`
+	Findings int `json:"findings"`
+}
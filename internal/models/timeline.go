@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Interval is a span of the caption timeline
+type Interval struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// LanguageWindow holds the language detected for one window of the
+// validation range
+type LanguageWindow struct {
+	Start    time.Duration
+	End      time.Duration
+	Language string
+	// Error is set when detection failed for this window
+	Error string
+	// Matches reports whether Language is the expected language
+	Matches bool
+}
+
+// MarshalJSON writes window times in milliseconds
+func (w LanguageWindow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		StartMs  int64  `json:"start_ms"`
+		EndMs    int64  `json:"end_ms"`
+		Language string `json:"language,omitempty"`
+		Error    string `json:"error,omitempty"`
+		Matches  bool   `json:"matches"`
+	}{w.Start.Milliseconds(), w.End.Milliseconds(), w.Language, w.Error, w.Matches})
+}
@@ -0,0 +1,412 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// Limits used to group word timings into cues and to wrap cue text
+const (
+	asrLineChars   = 42
+	asrMaxLines    = 2
+	asrMaxPause    = time.Second
+	asrMaxDuration = 6 * time.Second
+)
+
+// ASRMapping describes where the timed segments or words of a speech
+// recognition transcript are found in its JSON
+type ASRMapping struct {
+	// Path is the dotted path to the array of items, "" for a top-level
+	// array
+	Path string
+	// Start, End and Text are dotted paths within an item; array indexes
+	// are numbers, such as alternatives.0.content
+	Start, End, Text string
+	// Unit is what a time number counts, time.Second or time.Millisecond
+	Unit time.Duration
+	// Words is set when every item is a single word, to be grouped into
+	// cues
+	Words bool
+}
+
+// ASR transcript shapes recognised without a mapping
+var (
+	// ASRWhisper reads the segments of OpenAI Whisper JSON
+	ASRWhisper = ASRMapping{Path: "segments", Start: "start", End: "end", Text: "text", Unit: time.Second}
+	// ASRTranscribe reads the word items of AWS Transcribe JSON, whose
+	// punctuation items have no timing
+	ASRTranscribe = ASRMapping{Path: "results.items", Start: "start_time", End: "end_time", Text: "alternatives.0.content", Unit: time.Second, Words: true}
+	// ASRGeneric reads a top-level array of {start, end, text} segments
+	ASRGeneric = ASRMapping{Start: "start", End: "end", Text: "text", Unit: time.Second}
+)
+
+// asrPresets are the mappings that can be named by --asr-mapping
+var asrPresets = map[string]ASRMapping{
+	"whisper":    ASRWhisper,
+	"transcribe": ASRTranscribe,
+	"generic":    ASRGeneric,
+}
+
+// ParseASRMapping reads a mapping given as a preset name (whisper,
+// transcribe or generic) or as a comma separated list of key=value
+// settings: path, start, end, text, units (s or ms) and words (true or
+// false). Settings that are left out keep the generic ones.
+func ParseASRMapping(value string) (ASRMapping, error) {
+	value = strings.TrimSpace(value)
+	if preset, ok := asrPresets[strings.ToLower(value)]; ok {
+		return preset, nil
+	}
+
+	mapping := ASRGeneric
+	for _, entry := range strings.Split(value, ",") {
+		key, setting, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return ASRMapping{}, fmt.Errorf("expected a preset (whisper, transcribe, generic) or key=value, got %q", entry)
+		}
+		setting = strings.TrimSpace(setting)
+		switch strings.TrimSpace(key) {
+		case "path":
+			mapping.Path = setting
+		case "start":
+			mapping.Start = setting
+		case "end":
+			mapping.End = setting
+		case "text":
+			mapping.Text = setting
+		case "units":
+			switch setting {
+			case "s":
+				mapping.Unit = time.Second
+			case "ms":
+				mapping.Unit = time.Millisecond
+			default:
+				return ASRMapping{}, fmt.Errorf("invalid units %q (expected s or ms)", setting)
+			}
+		case "words":
+			words, err := strconv.ParseBool(setting)
+			if err != nil {
+				return ASRMapping{}, fmt.Errorf("invalid words setting %q", setting)
+			}
+			mapping.Words = words
+		default:
+			return ASRMapping{}, fmt.Errorf("unknown ASR mapping key %q", key)
+		}
+	}
+	if mapping.Start == "" || mapping.End == "" || mapping.Text == "" {
+		return ASRMapping{}, errors.New("ASR mapping needs start, end and text fields")
+	}
+	return mapping, nil
+}
+
+// asrWord is a word waiting to be grouped into a cue
+type asrWord struct {
+	start, end time.Duration
+	text       string
+}
+
+// ASRReader reads the cues of a speech recognition transcript in JSON
+// one at a time. Without a mapping the shape is recognised by the path of
+// its first array: Whisper segments, AWS Transcribe items or a top-level
+// array of segments. Segments become cues as they are; words are grouped
+// into cues at the end of a sentence, at a pause and when a cue grows too
+// long.
+type ASRReader struct {
+	dec      *json.Decoder
+	mappings []ASRMapping
+	mapping  *ASRMapping
+	started  bool
+	done     bool
+	// item is the 1-based index of the last item read
+	item int
+
+	words       []asrWord
+	sentenceEnd bool
+	pending     []Cue
+	diagnostics []Diagnostic
+}
+
+// NewASRReader returns a streaming ASR transcript parser. A nil mapping
+// recognises the Whisper, AWS Transcribe and generic shapes.
+func NewASRReader(reader io.Reader, mapping *ASRMapping) *ASRReader {
+	dec := json.NewDecoder(reader)
+	dec.UseNumber()
+	mappings := []ASRMapping{ASRWhisper, ASRTranscribe, ASRGeneric}
+	if mapping != nil {
+		mappings = []ASRMapping{*mapping}
+	}
+	return &ASRReader{dec: dec, mappings: mappings}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *ASRReader) Next() (Cue, error) {
+	if !p.started {
+		p.started = true
+		found, err := p.seek(nil)
+		if err != nil {
+			return Cue{}, p.syntaxError(err)
+		}
+		if !found {
+			return Cue{}, errors.New("invalid ASR transcript: no array of timed segments or words found (set --asr-mapping)")
+		}
+	}
+
+	for len(p.pending) == 0 {
+		if p.done {
+			return Cue{}, io.EOF
+		}
+		if !p.dec.More() {
+			p.done = true
+			p.flushWords()
+			continue
+		}
+		var item interface{}
+		if err := p.dec.Decode(&item); err != nil {
+			return Cue{}, p.syntaxError(err)
+		}
+		p.item++
+		p.add(item)
+	}
+
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+func (p *ASRReader) syntaxError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("invalid ASR transcript: unexpected end of JSON")
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("invalid ASR transcript: %v", err)
+	}
+	return err
+}
+
+// seek advances the decoder into the first array whose path is that of a
+// mapping, skipping every other value. path is the path of the value about
+// to be read.
+func (p *ASRReader) seek(path []string) (bool, error) {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return false, err
+	}
+	switch tok {
+	case json.Delim('['):
+		for i := range p.mappings {
+			if p.mappings[i].Path == strings.Join(path, ".") {
+				p.mapping = &p.mappings[i]
+				return true, nil
+			}
+		}
+		for p.dec.More() {
+			if err := p.skip(); err != nil {
+				return false, err
+			}
+		}
+	case json.Delim('{'):
+		for p.dec.More() {
+			key, err := p.dec.Token()
+			if err != nil {
+				return false, err
+			}
+			child := append(append([]string{}, path...), fmt.Sprint(key))
+			if !p.leadsToMapping(child) {
+				if err := p.skip(); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if found, err := p.seek(child); found || err != nil {
+				return found, err
+			}
+		}
+	default:
+		return false, nil
+	}
+	// Read the closing delimiter
+	_, err = p.dec.Token()
+	return false, err
+}
+
+// leadsToMapping reports whether path is, or is on the way to, the path of
+// a mapping
+func (p *ASRReader) leadsToMapping(path []string) bool {
+	prefix := strings.Join(path, ".")
+	for _, mapping := range p.mappings {
+		if mapping.Path == prefix || strings.HasPrefix(mapping.Path, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ASRReader) skip() error {
+	var raw json.RawMessage
+	return p.dec.Decode(&raw)
+}
+
+// add turns an item into a cue or adds it to the words of the next cue
+func (p *ASRReader) add(item interface{}) {
+	mapping := p.mapping
+	text, _ := asrField(item, mapping.Text).(string)
+	text = strings.TrimSpace(text)
+	start, hasStart := p.time(item, mapping.Start, "start")
+	end, hasEnd := p.time(item, mapping.End, "end")
+	if text == "" {
+		return
+	}
+
+	if !mapping.Words {
+		if !hasStart || !hasEnd {
+			p.diagnose(fmt.Sprintf("item %d has no timing; it is ignored", p.item))
+			return
+		}
+		p.emit(start, end, strings.Fields(text))
+		return
+	}
+
+	// Untimed words, such as punctuation, join the word before them
+	if !hasStart || !hasEnd {
+		if len(p.words) == 0 {
+			p.diagnose(fmt.Sprintf("item %d has no timing; it is ignored", p.item))
+			return
+		}
+		last := &p.words[len(p.words)-1]
+		if isPunctuation(text) {
+			last.text += text
+		} else {
+			last.text += " " + text
+		}
+		p.sentenceEnd = strings.ContainsAny(text[len(text)-1:], ".?!")
+		return
+	}
+
+	if len(p.words) > 0 {
+		first, last := p.words[0], p.words[len(p.words)-1]
+		if p.sentenceEnd || start-last.end >= asrMaxPause || end-first.start > asrMaxDuration || p.textLength()+1+len([]rune(text)) > asrLineChars*asrMaxLines {
+			p.flushWords()
+		}
+	}
+	p.words = append(p.words, asrWord{start: start, end: end, text: text})
+	p.sentenceEnd = strings.ContainsAny(text[len(text)-1:], ".?!")
+}
+
+// time reads a time field of an item, given as a number or a string of
+// the mapping's unit, or a duration string such as "1.5s"
+func (p *ASRReader) time(item interface{}, field, name string) (time.Duration, bool) {
+	switch v := asrField(item, field).(type) {
+	case nil:
+		return 0, false
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return time.Duration(math.Round(f * float64(p.mapping.Unit))), true
+		}
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(math.Round(f * float64(p.mapping.Unit))), true
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, true
+		}
+	}
+	p.diagnose(fmt.Sprintf("item %d: %s time %q is not recognised", p.item, name, TextPreview(fmt.Sprint(asrField(item, field)))))
+	return 0, false
+}
+
+func (p *ASRReader) textLength() int {
+	n := -1
+	for _, word := range p.words {
+		n += 1 + len([]rune(word.text))
+	}
+	return n
+}
+
+// flushWords makes a cue of the words grouped so far
+func (p *ASRReader) flushWords() {
+	if len(p.words) == 0 {
+		return
+	}
+	words := make([]string, len(p.words))
+	for i, word := range p.words {
+		words[i] = word.text
+	}
+	p.emit(p.words[0].start, p.words[len(p.words)-1].end, words)
+	p.words = nil
+	p.sentenceEnd = false
+}
+
+// emit queues a cue of the given words, wrapped into lines
+func (p *ASRReader) emit(start, end time.Duration, words []string) {
+	lines := wrapWords(words, asrLineChars)
+	p.pending = append(p.pending, Cue{
+		CaptionEntry: models.CaptionEntry{
+			StartTime: start,
+			EndTime:   end,
+			Text:      strings.Join(lines, " "),
+			Lines:     lines,
+		},
+		Diagnostics: p.diagnostics,
+	})
+	p.diagnostics = nil
+}
+
+func (p *ASRReader) diagnose(message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Message: message})
+}
+
+// asrField follows a dotted path into a decoded JSON value, returning nil
+// when it leads nowhere
+func asrField(value interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// isPunctuation reports whether text has no letters or digits
+func isPunctuation(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool {
+		return r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7F
+	}) < 0
+}
+
+// wrapWords joins words into lines of at most width characters, breaking
+// only between words
+func wrapWords(words []string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range words {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,205 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+var (
+	assTimeRegex = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})(?:\.(\d{1,3}))?$`)
+	// assOverrideRegex matches override blocks such as {\an8} or {\i1}
+	assOverrideRegex = regexp.MustCompile(`\{[^}]*\}`)
+	// assDrawingRegex matches the \p tag that switches vector drawing on
+	// or off
+	assDrawingRegex = regexp.MustCompile(`\\p(\d+)`)
+)
+
+// assDefaultFormat is the [Events] field order of ASS files, assumed when
+// a file has no Format line
+var assDefaultFormat = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+
+func ParseASS(reader io.Reader) ([]models.CaptionEntry, error) {
+	return parseASS(reader, DefaultMaxLineSize)
+}
+
+func parseASS(reader io.Reader, maxLineSize int) ([]models.CaptionEntry, error) {
+	return collect(NewASSReader(reader, maxLineSize))
+}
+
+// ASSReader reads the Dialogue events of ASS and SSA files one at a time.
+// Fields are mapped by the Format line of the [Events] section, override
+// blocks are removed from the text, and the style and actor names are kept
+// as cue metadata under "style" and "actor".
+type ASSReader struct {
+	scanner     *LineReader
+	inEvents    bool
+	format      []string
+	diagnostics []Diagnostic
+}
+
+// NewASSReader returns a streaming ASS/SSA parser
+func NewASSReader(reader io.Reader, maxLineSize int) *ASSReader {
+	return &ASSReader{scanner: NewLineReader(reader, maxLineSize)}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *ASSReader) Next() (Cue, error) {
+	for p.scanner.Scan() {
+		lineNum := p.scanner.Line()
+		line := strings.TrimSpace(p.scanner.Text())
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			p.inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !p.inEvents {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "format":
+			p.format = nil
+			for _, field := range strings.Split(value, ",") {
+				p.format = append(p.format, strings.ToLower(strings.TrimSpace(field)))
+			}
+		case "dialogue":
+			if cue, ok := p.dialogue(lineNum, value); ok {
+				return cue, nil
+			}
+		}
+	}
+
+	if err := p.scanner.Err(); err != nil {
+		return Cue{}, err
+	}
+	return Cue{}, io.EOF
+}
+
+// dialogue reads the fields of a Dialogue line. The text is the last field
+// and may itself contain commas.
+func (p *ASSReader) dialogue(lineNum int, value string) (Cue, bool) {
+	format := p.format
+	if format == nil {
+		p.diagnose(lineNum, "dialogue comes before the [Events] Format line; the ASS field order is assumed")
+		format = assDefaultFormat
+		p.format = assDefaultFormat
+	}
+
+	values := strings.SplitN(strings.TrimLeft(value, " "), ",", len(format))
+	if len(values) < len(format) {
+		p.diagnose(lineNum, fmt.Sprintf("dialogue has %d fields, expected %d, and is ignored", len(values), len(format)))
+		return Cue{}, false
+	}
+	fields := make(map[string]string, len(format))
+	for i, name := range format {
+		if name == "text" {
+			fields[name] = values[i]
+		} else {
+			fields[name] = strings.TrimSpace(values[i])
+		}
+	}
+
+	start, err := parseASSTime(fields["start"])
+	if err != nil {
+		p.diagnose(lineNum, fmt.Sprintf("start time %q is not recognised; the dialogue is ignored", TextPreview(fields["start"])))
+		return Cue{}, false
+	}
+	end, err := parseASSTime(fields["end"])
+	if err != nil {
+		p.diagnose(lineNum, fmt.Sprintf("end time %q is not recognised; the dialogue is ignored", TextPreview(fields["end"])))
+		return Cue{}, false
+	}
+
+	lines := ASSText(fields["text"])
+	if len(lines) == 0 {
+		return Cue{}, false
+	}
+
+	metadata := map[string]string{}
+	if style := fields["style"]; style != "" {
+		metadata["style"] = style
+	}
+	if actor := fields["name"]; actor != "" {
+		metadata["actor"] = actor
+	}
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+
+	cue := Cue{
+		CaptionEntry: models.CaptionEntry{
+			StartTime: start,
+			EndTime:   end,
+			Text:      strings.Join(lines, " "),
+			Lines:     lines,
+			Line:      lineNum,
+			Metadata:  metadata,
+		},
+		Diagnostics: p.diagnostics,
+	}
+	p.diagnostics = nil
+	return cue, true
+}
+
+func (p *ASSReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
+
+// ASSText returns the displayed lines of an ASS dialogue text. Override
+// blocks and vector drawings are removed, \N and \n break lines and \h is
+// a space.
+func ASSText(text string) []string {
+	var b strings.Builder
+	drawing := false
+	rest := text
+	for rest != "" {
+		loc := assOverrideRegex.FindStringIndex(rest)
+		if loc == nil {
+			loc = []int{len(rest), len(rest)}
+		}
+		if !drawing {
+			b.WriteString(rest[:loc[0]])
+		}
+		for _, m := range assDrawingRegex.FindAllStringSubmatch(rest[loc[0]:loc[1]], -1) {
+			drawing = m[1] != "0"
+		}
+		rest = rest[loc[1]:]
+	}
+
+	replacer := strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ")
+	var lines []string
+	for _, line := range strings.Split(replacer.Replace(b.String()), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseASSTime converts an H:MM:SS.cc time to a duration
+func parseASSTime(timeStr string) (time.Duration, error) {
+	m := assTimeRegex.FindStringSubmatch(timeStr)
+	if m == nil {
+		return 0, fmt.Errorf("invalid time format: %s", timeStr)
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, _ := strconv.Atoi(m[3])
+
+	// The fraction is usually centiseconds
+	fraction := m[4] + strings.Repeat("0", 3-len(m[4]))
+	milliseconds, _ := strconv.Atoi(fraction)
+
+	totalMilliseconds := hours*3600000 + minutes*60000 + seconds*1000 + milliseconds
+	return time.Duration(totalMilliseconds) * time.Millisecond, nil
+}
@@ -1,28 +1,326 @@
 package parse
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/theCompanyDream/srt-test/internal/models"
 )
 
+// Caption formats accepted by --input-format
+const (
+	FormatSRT        = "srt"
+	FormatWebVTT     = "vtt"
+	FormatTTML       = "ttml"
+	FormatSCC        = "scc"
+	FormatASS        = "ass"
+	FormatSBV        = "sbv"
+	FormatTranscript = "transcript"
+	FormatSAMI       = "sami"
+	FormatSTL        = "stl"
+	FormatMicroDVD   = "microdvd"
+	FormatASR        = "asr"
+	FormatHLS        = "hls"
+	FormatMatroska   = "mkv"
+	FormatMP4        = "mp4"
+)
+
+// Formats lists every caption format that can be parsed
+var Formats = []string{FormatSRT, FormatWebVTT, FormatTTML, FormatSCC, FormatASS, FormatSBV, FormatTranscript, FormatSAMI, FormatSTL, FormatMicroDVD, FormatASR, FormatHLS, FormatMatroska, FormatMP4}
+
+// ErrUnknownFormat is returned when neither the content nor the extension
+// of an input identifies a caption format
+var ErrUnknownFormat = errors.New("unknown caption format")
+
+// ErrNoTrack is returned when the track chosen with Options.Track names no
+// track of an input that holds several
+var ErrNoTrack = errors.New("no caption track")
+
+// IsSupportedFormat reports whether format names a caption format that can
+// be parsed
+func IsSupportedFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Extension returns the lower-case extension of a file path or URL without
+// the dot, ignoring any query string
+func Extension(path string) string {
+	if IsURL(path) {
+		if u, err := url.Parse(path); err == nil {
+			path = u.Path
+		}
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// FormatFromPath returns the caption format implied by the extension of a
+// file path or URL, or "" when the extension is not a caption format
+func FormatFromPath(path string) string {
+	return formatForExtension(Extension(path))
+}
+
+// genericExtensions are extensions of a caption format that other files
+// use too, so they are only read when named rather than found in a
+// directory
+var genericExtensions = map[string]bool{"xml": true, "json": true}
+
+// IsCaptionFile reports whether a file found in a directory is read as
+// captions, judging by its extension
+func IsCaptionFile(path string) bool {
+	ext := Extension(path)
+	return !genericExtensions[ext] && formatForExtension(ext) != ""
+}
+
+// formatForExtension returns the caption format that uses a file
+// extension, or ""
+func formatForExtension(ext string) string {
+	for format, extensions := range formatExtensions {
+		for _, e := range extensions {
+			if e == ext {
+				return format
+			}
+		}
+	}
+	return ""
+}
+
+// Input describes a caption input identified by OpenStream
+type Input struct {
+	// Format is the format the input is parsed as
+	Format string
+	// Detected is the format recognised from the content, if any
+	Detected string
+	// Extension is the file extension of the input, if any
+	Extension string
+	// Encoding is the character encoding the input is decoded from, or the
+	// character code table of a binary format
+	Encoding string
+	// Language is the language the input declares for all its captions,
+	// such as the language code of an EBU STL header
+	Language string
+	// Tracks lists the caption tracks of a format that holds several, such
+	// as the language classes of a SAMI file
+	Tracks []models.Track
+	// Duration is the running time a container file declares, or 0
+	Duration time.Duration
+}
+
+// ExtensionMismatch reports whether the file extension names a different
+// format than the content has. Formats without an extension of their own,
+// such as transcripts, only mismatch the extension of another format.
+func (in Input) ExtensionMismatch() bool {
+	if in.Detected == "" || in.Extension == "" {
+		return false
+	}
+	if len(formatExtensions[in.Detected]) == 0 {
+		return formatForExtension(in.Extension) != ""
+	}
+	for _, ext := range formatExtensions[in.Detected] {
+		if ext == in.Extension {
+			return false
+		}
+	}
+	return true
+}
+
+// Document is a parsed caption input
+type Document struct {
+	Input
+	Captions []models.CaptionEntry
+	// Diagnostics lists the problems found while parsing, in input order
+	Diagnostics []Diagnostic
+}
+
+// Stream reads the cues of a caption input one at a time
+type Stream struct {
+	// Input identifies the input. Its Encoding, Language and Tracks are
+	// final once Next has returned io.EOF.
+	Input
+	cues    CueReader
+	decoder *decoder
+	source  io.Closer
+	// track is the ID or language of the only track to read from an input
+	// that holds several, and trackID the ID it was found to name
+	track, trackID string
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (s *Stream) Next() (Cue, error) {
+	for {
+		cue, err := s.cues.Next()
+		s.Encoding = s.decoder.Encoding()
+		s.describe()
+		// An input with a single track is read whatever track is chosen
+		if s.track == "" || len(s.Tracks) == 0 {
+			return cue, err
+		}
+		if s.trackID == "" {
+			for _, track := range s.Tracks {
+				if TrackMatches(track, s.track) {
+					s.trackID = track.ID
+					break
+				}
+			}
+		}
+		if s.trackID == "" {
+			return Cue{}, s.noTrack()
+		}
+		if err != nil || cue.Track == s.trackID {
+			return cue, err
+		}
+	}
+}
+
+// noTrack describes a --track choice that names no track of the input
+func (s *Stream) noTrack() error {
+	tracks := make([]string, len(s.Tracks))
+	for i, track := range s.Tracks {
+		tracks[i] = track.ID
+		if track.Language != "" {
+			tracks[i] += " (" + track.Language + ")"
+		}
+	}
+	return fmt.Errorf("%w %q; the tracks are %s", ErrNoTrack, s.track, strings.Join(tracks, ", "))
+}
+
+func (s *Stream) describe() {
+	if describer, ok := s.cues.(inputDescriber); ok {
+		describer.describe(&s.Input)
+	}
+}
+
+// inputDescriber is implemented by cue readers that learn about the whole
+// input as they read it, such as its tracks or declared language
+type inputDescriber interface {
+	describe(in *Input)
+}
+
+// Close releases the underlying file or connection
+func (s *Stream) Close() error {
+	return s.source.Close()
+}
+
 func ParseCaptionFile(filePath string) ([]models.CaptionEntry, error) {
-	file, err := os.Open(filePath)
+	return ParseInput(filePath, Options{})
+}
+
+// ParseInput reads captions from a file, stdin or URL
+func ParseInput(input string, opts Options) ([]models.CaptionEntry, error) {
+	doc, err := ParseDocument(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Captions, nil
+}
+
+// ParseDocument reads every cue of a file, stdin or URL into memory. Use
+// OpenStream to process large inputs cue by cue.
+func ParseDocument(input string, opts Options) (*Document, error) {
+	stream, err := OpenStream(input, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	defer stream.Close()
 
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".vtt":
-		return ParseWebVTT(file)
-	case ".srt":
-		return ParseSRT(file)
+	doc := &Document{}
+	err = ForEachCue(stream, func(cue Cue) error {
+		doc.Captions = append(doc.Captions, cue.CaptionEntry)
+		doc.Diagnostics = append(doc.Diagnostics, cue.Diagnostics...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	doc.Input = stream.Input
+	return doc, nil
+}
+
+// OpenStream opens a file, stdin or URL for reading cue by cue. The input
+// is decoded to UTF-8 first. The format is opts.Format when set; otherwise
+// it is detected from the content, falling back to the extension when the
+// content is not recognised.
+func OpenStream(input string, opts Options) (*Stream, error) {
+	stream := &Stream{Input: Input{Extension: Extension(input)}}
+	if input == StdinPath {
+		stream.Extension = ""
+	}
+	if opts.Format != "" && !IsSupportedFormat(opts.Format) {
+		return nil, unsupportedFormat(opts.Format)
+	}
+
+	reader, err := Open(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	stream.source = reader
+
+	if stream.decoder, err = newDecoder(reader, opts.Format); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	buffered := bufio.NewReaderSize(stream.decoder, sniffSize)
+	head, err := buffered.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		reader.Close()
+		return nil, err
+	}
+	stream.Detected = Sniff(head)
+
+	stream.Format = opts.Format
+	if stream.Format == "" {
+		stream.Format = stream.Detected
+	}
+	if stream.Format == "" {
+		stream.Format = FormatFromPath(input)
+	}
+	if stream.Format == "" {
+		reader.Close()
+		return nil, ErrUnknownFormat
+	}
+
+	switch file, isFile := reader.(*os.File); {
+	case stream.Format == FormatHLS:
+		// Segments of an HLS playlist are found next to it
+		stream.cues, err = NewHLSReader(buffered, input, opts), nil
+	case stream.Format == FormatMP4 && isFile:
+		// A local MP4 file is read where its samples are rather than
+		// into memory
+		var info os.FileInfo
+		if info, err = file.Stat(); err == nil {
+			stream.cues, err = NewMP4Reader(file, info.Size())
+		}
 	default:
-		return nil, fmt.Errorf("unsupported file type: %s", ext)
+		stream.cues, err = newCueReader(buffered, stream.Format, opts)
+	}
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	stream.Encoding = stream.decoder.Encoding()
+	stream.describe()
+	stream.track = opts.Track
+	return stream, nil
+}
+
+// ParseCaptions parses captions in the given format from reader. Lines
+// longer than maxLineSize bytes are an error; zero or less uses
+// DefaultMaxLineSize.
+func ParseCaptions(reader io.Reader, format string, maxLineSize int) ([]models.CaptionEntry, error) {
+	cues, err := NewCueReader(reader, format, maxLineSize)
+	if err != nil {
+		return nil, err
 	}
+	return collect(cues)
 }
@@ -0,0 +1,190 @@
+package parse
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// iso6937 maps bytes 0xA0-0xFF of the ISO 6937 supplementary set, used by
+// the Latin code table of EBU STL, to runes. The non-spacing diacritical
+// marks at 0xC1-0xCF and unused positions map to 0.
+var iso6937 = [96]rune{
+	0x00A0, 0x00A1, 0x00A2, 0x00A3, 0x0024, 0x00A5, 0x0023, 0x00A7,
+	0x00A4, 0x2018, 0x201C, 0x00AB, 0x2190, 0x2191, 0x2192, 0x2193,
+	0x00B0, 0x00B1, 0x00B2, 0x00B3, 0x00D7, 0x00B5, 0x00B6, 0x00B7,
+	0x00F7, 0x2019, 0x201D, 0x00BB, 0x00BC, 0x00BD, 0x00BE, 0x00BF,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0x2015, 0x00B9, 0x00AE, 0x00A9, 0x2122, 0x266A, 0x00AC, 0x00A6,
+	0, 0, 0, 0, 0x215B, 0x215C, 0x215D, 0x215E,
+	0x2126, 0x00C6, 0x0110, 0x00AA, 0x0126, 0, 0x0132, 0x013F,
+	0x0141, 0x00D8, 0x0152, 0x00BA, 0x00DE, 0x0166, 0x014A, 0x0149,
+	0x0138, 0x00E6, 0x0111, 0x00F0, 0x0127, 0x0131, 0x0133, 0x0140,
+	0x0142, 0x00F8, 0x0153, 0x00DF, 0x00FE, 0x0167, 0x014B, 0x00AD,
+}
+
+// iso6937Mark is a non-spacing diacritical mark of ISO 6937, which comes
+// before the letter it is placed on
+type iso6937Mark struct {
+	// combining is the Unicode combining character of the mark
+	combining rune
+	// bases lists the letters with a precomposed form, and composed those
+	// forms in the same order
+	bases, composed string
+}
+
+var iso6937Marks = map[byte]iso6937Mark{
+	0xC1: {0x0300, "AEIOUaeiou", "ÀÈÌÒÙàèìòù"},
+	0xC2: {0x0301, "ACEILNORSUYZacegilnorsuyz", "ÁĆÉÍĹŃÓŔŚÚÝŹáćéǵíĺńóŕśúýź"},
+	0xC3: {0x0302, "ACEGHIJOSUWYaceghijosuwy", "ÂĈÊĜĤÎĴÔŜÛŴŶâĉêĝĥîĵôŝûŵŷ"},
+	0xC4: {0x0303, "AINOUainou", "ÃĨÑÕŨãĩñõũ"},
+	0xC5: {0x0304, "AEIOUaeiou", "ĀĒĪŌŪāēīōū"},
+	0xC6: {0x0306, "AGUagu", "ĂĞŬăğŭ"},
+	0xC7: {0x0307, "CEGIZcegz", "ĊĖĠİŻċėġż"},
+	0xC8: {0x0308, "AEIOUYaeiouy", "ÄËÏÖÜŸäëïöüÿ"},
+	0xCA: {0x030A, "AUau", "ÅŮåů"},
+	0xCB: {0x0327, "CGKLNRSTcgklnrst", "ÇĢĶĻŅŖŞŢçģķļņŗşţ"},
+	0xCD: {0x030B, "OUou", "ŐŰőű"},
+	0xCE: {0x0328, "AEIUaeiu", "ĄĘĮŲąęįų"},
+	0xCF: {0x030C, "CDELNRSTZcdelnrstz", "ČĎĚĽŇŘŠŤŽčďěľňřšťž"},
+}
+
+// apply places the mark on a letter, using the precomposed form when
+// there is one and the combining character otherwise
+func (m iso6937Mark) apply(letter rune) string {
+	if i := strings.IndexRune(m.bases, letter); i >= 0 {
+		composed := []rune(m.composed)
+		return string(composed[utf8.RuneCountInString(m.bases[:i])])
+	}
+	return string(letter) + string(m.combining)
+}
+
+// stlRune decodes a byte of 0xA0 or above in an EBU STL character code
+// table, returning 0 for unused positions. Table 00 is ISO 6937 and 01 to
+// 04 are ISO 8859-5 to 8859-8.
+func stlRune(b byte, table string) rune {
+	switch table {
+	case "01":
+		// Latin/Cyrillic
+		switch {
+		case b == 0xA0 || b == 0xAD:
+			return rune(b)
+		case b == 0xF0:
+			return 0x2116
+		case b == 0xFD:
+			return 0x00A7
+		}
+		return 0x0401 + rune(b-0xA1)
+	case "02":
+		// Latin/Arabic
+		switch {
+		case b == 0xA0 || b == 0xA4 || b == 0xAD:
+			return rune(b)
+		case b == 0xAC:
+			return 0x060C
+		case b == 0xBB:
+			return 0x061B
+		case b == 0xBF:
+			return 0x061F
+		case b >= 0xC1 && b <= 0xDA:
+			return 0x0621 + rune(b-0xC1)
+		case b >= 0xE0 && b <= 0xF2:
+			return 0x0640 + rune(b-0xE0)
+		}
+		return 0
+	case "03":
+		// Latin/Greek
+		if b >= 0xB4 {
+			switch b {
+			case 0xB7, 0xBB, 0xBD:
+				return rune(b)
+			case 0xD2, 0xFF:
+				return 0
+			}
+			return 0x0384 + rune(b-0xB4)
+		}
+		switch b {
+		case 0xA1:
+			return 0x2018
+		case 0xA2:
+			return 0x2019
+		case 0xA4:
+			return 0x20AC
+		case 0xA5:
+			return 0x20AF
+		case 0xAA:
+			return 0x037A
+		case 0xAE:
+			return 0
+		case 0xAF:
+			return 0x2015
+		}
+		return rune(b)
+	case "04":
+		// Latin/Hebrew
+		switch {
+		case b == 0xAA:
+			return 0x00D7
+		case b == 0xBA:
+			return 0x00F7
+		case b == 0xA0 || b >= 0xA2 && b <= 0xBE:
+			return rune(b)
+		case b == 0xDF:
+			return 0x2017
+		case b >= 0xE0 && b <= 0xFA:
+			return 0x05D0 + rune(b-0xE0)
+		case b == 0xFD:
+			return 0x200E
+		case b == 0xFE:
+			return 0x200F
+		}
+		return 0
+	}
+	return iso6937[b-0xA0]
+}
+
+// stlText decodes the text field of a subtitle into its lines. Teletext
+// colour and size codes take up a space on screen; the italic, underline
+// and boxing codes do not. Lines are trimmed and empty ones, such as those
+// between double-height rows, are dropped.
+func stlText(field []byte, table string) []string {
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+
+	var mark *iso6937Mark
+	for _, b := range field {
+		switch {
+		case b == stlNewline:
+			flush()
+			mark = nil
+		case b < 0x20:
+			line.WriteByte(' ')
+		case b == 0x7F || b >= 0x80 && b < 0xA0:
+		case table == "00" && b >= 0xC1 && b <= 0xCF:
+			if m, ok := iso6937Marks[b]; ok {
+				mark = &m
+			}
+		default:
+			r := rune(b)
+			if b >= 0xA0 {
+				if r = stlRune(b, table); r == 0 {
+					continue
+				}
+			}
+			if mark != nil {
+				line.WriteString(mark.apply(r))
+				mark = nil
+				continue
+			}
+			line.WriteRune(r)
+		}
+	}
+	flush()
+	return lines
+}
@@ -0,0 +1,73 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// iso6392Languages maps the ISO 639-2 codes that containers tag tracks with
+// to ISO 639-1 codes, including both the bibliographic and terminology
+// codes of a language
+var iso6392Languages = map[string]string{
+	"alb": "sq", "sqi": "sq", "ara": "ar", "arm": "hy", "hye": "hy", "baq": "eu", "eus": "eu",
+	"ben": "bn", "bul": "bg", "cat": "ca", "chi": "zh", "zho": "zh", "cze": "cs", "ces": "cs",
+	"dan": "da", "dut": "nl", "nld": "nl", "eng": "en", "est": "et", "fil": "fil", "fin": "fi",
+	"fre": "fr", "fra": "fr", "geo": "ka", "kat": "ka", "ger": "de", "deu": "de", "gle": "ga",
+	"glg": "gl", "gre": "el", "ell": "el", "heb": "he", "hin": "hi", "hrv": "hr", "hun": "hu",
+	"ice": "is", "isl": "is", "ind": "id", "ita": "it", "jpn": "ja", "kor": "ko", "lav": "lv",
+	"lit": "lt", "mac": "mk", "mkd": "mk", "may": "ms", "msa": "ms", "nob": "nb", "nno": "nn",
+	"nor": "no", "per": "fa", "fas": "fa", "pol": "pl", "por": "pt", "rum": "ro", "ron": "ro",
+	"rus": "ru", "slo": "sk", "slk": "sk", "slv": "sl", "spa": "es", "srp": "sr", "swe": "sv",
+	"tam": "ta", "tel": "te", "tha": "th", "tur": "tr", "ukr": "uk", "urd": "ur", "vie": "vi",
+	"wel": "cy", "cym": "cy",
+}
+
+// containerLanguage returns the language tag of a track tagged with an ISO
+// 639-2 code, or "" when the language is undetermined. Codes without an
+// ISO 639-1 equivalent are kept.
+func containerLanguage(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	switch code {
+	case "", "und", "mul", "zxx", "mis":
+		return ""
+	}
+	if tag, ok := iso6392Languages[code]; ok {
+		return tag
+	}
+	return code
+}
+
+// containerTrack is a subtitle track of a container file
+type containerTrack struct {
+	models.Track
+	// codec names the subtitle format of the track, such as S_TEXT/UTF8 or
+	// tx3g
+	codec string
+	// readable is set when the text of the track can be extracted
+	readable bool
+}
+
+// unreadableTrack is the diagnostic for a subtitle track whose format
+// cannot be read, such as one made of images
+func unreadableTrack(track containerTrack) Diagnostic {
+	return Diagnostic{Message: fmt.Sprintf("subtitle track %s (%s) cannot be read and is not validated", track.ID, track.codec)}
+}
+
+// textLines splits subtitle text into its non-empty lines
+func textLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// TrackMatches reports whether track is the one chosen by a --track value,
+// which names a track ID or a language
+func TrackMatches(track models.Track, choice string) bool {
+	return track.ID == choice || track.Language != "" && strings.EqualFold(track.Language, choice)
+}
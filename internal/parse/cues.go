@@ -0,0 +1,131 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// Diagnostic is a problem found while parsing a cue that did not stop
+// parsing
+type Diagnostic struct {
+	// Line is the 1-based input line the problem was found on, or 0 when
+	// there is none to point at, as in a binary format
+	Line    int
+	Message string
+	// Code is the finding code the problem is reported as;
+	// models.CodeMalformedCue when empty
+	Code string
+}
+
+// Cue is a caption read from a stream together with the problems found
+// while parsing it
+type Cue struct {
+	models.CaptionEntry
+	Diagnostics []Diagnostic
+}
+
+// CueReader yields the cues of an input one at a time, so that large
+// inputs can be validated without holding every cue in memory
+type CueReader interface {
+	// Next returns the next cue, or io.EOF after the last one
+	Next() (Cue, error)
+}
+
+// NewCueReader returns a reader of cues in the given format. In line-based
+// formats, lines longer than maxLineSize bytes are an error; zero or less
+// uses DefaultMaxLineSize.
+func NewCueReader(r io.Reader, format string, maxLineSize int) (CueReader, error) {
+	return newCueReader(r, format, Options{MaxLineSize: maxLineSize})
+}
+
+// newCueReader returns a reader of cues in the given format, configured by
+// the parsing options
+func newCueReader(r io.Reader, format string, opts Options) (CueReader, error) {
+	maxLineSize := opts.MaxLineSize
+	switch format {
+	case FormatWebVTT:
+		return NewWebVTTReader(r, maxLineSize), nil
+	case FormatSRT:
+		return NewSRTReader(r, maxLineSize), nil
+	case FormatTTML:
+		return NewTTMLReader(r), nil
+	case FormatSCC:
+		return NewSCCReader(r, maxLineSize), nil
+	case FormatASS:
+		return NewASSReader(r, maxLineSize), nil
+	case FormatSBV:
+		return NewSBVReader(r, maxLineSize), nil
+	case FormatTranscript:
+		return NewTranscriptReader(r, maxLineSize), nil
+	case FormatSAMI:
+		return NewSAMIReader(r), nil
+	case FormatSTL:
+		return NewSTLReader(r), nil
+	case FormatMicroDVD:
+		return NewMicroDVDReader(r, maxLineSize, opts.FrameRate), nil
+	case FormatASR:
+		return NewASRReader(r, opts.ASRMapping), nil
+	case FormatHLS:
+		return NewHLSReader(r, "", opts), nil
+	case FormatMatroska:
+		return NewMatroskaReader(r)
+	case FormatMP4:
+		// The sample tables of an MP4 file may follow its samples
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewMP4Reader(bytes.NewReader(data), int64(len(data)))
+	}
+	return nil, unsupportedFormat(format)
+}
+
+// ForEachCue calls fn with every cue of r until the input ends, r fails or
+// fn returns an error
+func ForEachCue(r CueReader, fn func(Cue) error) error {
+	for {
+		cue, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(cue); err != nil {
+			return err
+		}
+	}
+}
+
+// collect reads every cue of r. Cues read before an input error are
+// returned with it.
+func collect(r CueReader) ([]models.CaptionEntry, error) {
+	var captions []models.CaptionEntry
+	err := ForEachCue(r, func(cue Cue) error {
+		captions = append(captions, cue.CaptionEntry)
+		return nil
+	})
+	return captions, err
+}
+
+func unsupportedFormat(format string) error {
+	return fmt.Errorf("unsupported caption format: %s", format)
+}
+
+// previewLength is the number of characters shown when quoting text
+const previewLength = 40
+
+// TextPreview shortens text quoted in a diagnostic or finding description,
+// with runs of white space collapsed
+func TextPreview(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= previewLength {
+		return text
+	}
+	return string([]rune(text)[:previewLength-1]) + "…"
+}
@@ -0,0 +1,233 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Character encodings recognised on input. Everything is decoded to UTF-8
+// before parsing.
+const (
+	EncodingUTF8        = "UTF-8"
+	EncodingUTF16LE     = "UTF-16LE"
+	EncodingUTF16BE     = "UTF-16BE"
+	EncodingWindows1252 = "windows-1252"
+	EncodingLatin1      = "ISO-8859-1"
+)
+
+// windows1252 maps bytes 0x80-0x9F to runes. The five undefined bytes map
+// to the C1 control of the same value, as browsers do.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+type sourceEncoding int
+
+const (
+	sourceUTF8 sourceEncoding = iota
+	sourceUTF16LE
+	sourceUTF16BE
+	// sourceBinary passes the bytes of a binary format through unchanged
+	sourceBinary
+)
+
+// decoder converts input to UTF-8 as it is read. A byte order mark selects
+// UTF-8 or UTF-16; without one, UTF-16 is recognised by its zero bytes.
+// Bytes that are not valid UTF-8 are decoded as windows-1252, which
+// covers ISO-8859-1 as well.
+type decoder struct {
+	src      io.Reader
+	encoding sourceEncoding
+	buf      []byte
+	in       []byte
+	out      []byte
+	err      error
+	// high is a UTF-16 high surrogate waiting for its low half
+	high rune
+	// legacy is set once a byte was decoded as windows-1252, and c1 once
+	// one of them was in the 0x80-0x9F range unused by ISO-8859-1
+	legacy, c1 bool
+}
+
+// newDecoder returns a decoder for r. Binary formats, whether forced by
+// format or recognised from the content, are not decoded.
+func newDecoder(r io.Reader, format string) (*decoder, error) {
+	src := bufio.NewReaderSize(r, sniffSize)
+	head, err := src.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	d := &decoder{src: src, buf: make([]byte, 32*1024)}
+	if format == "" {
+		format = Sniff(head)
+	}
+	switch {
+	case IsBinaryFormat(format):
+		d.encoding = sourceBinary
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		src.Discard(3)
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		d.encoding = sourceUTF16LE
+		src.Discard(2)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		d.encoding = sourceUTF16BE
+		src.Discard(2)
+	default:
+		d.encoding = guessUTF16(head)
+	}
+	return d, nil
+}
+
+// guessUTF16 recognises UTF-16 without a byte order mark from the zero
+// high bytes of ASCII characters, which UTF-8 text does not contain
+func guessUTF16(head []byte) sourceEncoding {
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	pairs := len(head) / 2
+	if pairs < 4 {
+		return sourceUTF8
+	}
+	var little, big int
+	for i := 0; i+1 < len(head); i += 2 {
+		switch {
+		case head[i] != 0 && head[i+1] == 0:
+			little++
+		case head[i] == 0 && head[i+1] != 0:
+			big++
+		}
+	}
+	switch {
+	case little*10 >= pairs*4 && little > big:
+		return sourceUTF16LE
+	case big*10 >= pairs*4:
+		return sourceUTF16BE
+	}
+	return sourceUTF8
+}
+
+// Encoding names the encoding of the input read so far, or "" for a
+// binary format
+func (d *decoder) Encoding() string {
+	switch {
+	case d.encoding == sourceBinary:
+		return ""
+	case d.encoding == sourceUTF16LE:
+		return EncodingUTF16LE
+	case d.encoding == sourceUTF16BE:
+		return EncodingUTF16BE
+	case d.c1:
+		return EncodingWindows1252
+	case d.legacy:
+		return EncodingLatin1
+	}
+	return EncodingUTF8
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.fill()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// fill reads the next chunk of input and decodes as much of it as forms
+// complete characters
+func (d *decoder) fill() {
+	n, err := d.src.Read(d.buf)
+	d.in = append(d.in, d.buf[:n]...)
+	atEOF := err != nil
+	d.out = d.out[:0]
+	switch d.encoding {
+	case sourceBinary:
+		d.out = append(d.out, d.in...)
+		d.in = d.in[:0]
+	case sourceUTF8:
+		d.decodeUTF8(atEOF)
+	default:
+		d.decodeUTF16(atEOF)
+	}
+	d.err = err
+}
+
+func (d *decoder) decodeUTF8(atEOF bool) {
+	in := d.in
+	if utf8.Valid(in) {
+		d.out = append(d.out, in...)
+		d.in = d.in[:0]
+		return
+	}
+	for len(in) > 0 {
+		r, size := utf8.DecodeRune(in)
+		if r == utf8.RuneError && size <= 1 {
+			// Wait for the rest of a sequence split across reads
+			if !atEOF && !utf8.FullRune(in) {
+				break
+			}
+			d.out = utf8.AppendRune(d.out, d.legacyRune(in[0]))
+			in = in[1:]
+			continue
+		}
+		d.out = append(d.out, in[:size]...)
+		in = in[size:]
+	}
+	d.in = append(d.in[:0], in...)
+}
+
+func (d *decoder) legacyRune(b byte) rune {
+	d.legacy = true
+	if b >= 0x80 && b <= 0x9F {
+		d.c1 = true
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
+
+func (d *decoder) decodeUTF16(atEOF bool) {
+	in := d.in
+	for len(in) >= 2 {
+		var r rune
+		if d.encoding == sourceUTF16LE {
+			r = rune(in[0]) | rune(in[1])<<8
+		} else {
+			r = rune(in[0])<<8 | rune(in[1])
+		}
+		in = in[2:]
+
+		if d.high != 0 {
+			if r >= 0xDC00 && r <= 0xDFFF {
+				d.out = utf8.AppendRune(d.out, utf16.DecodeRune(d.high, r))
+				d.high = 0
+				continue
+			}
+			d.out = utf8.AppendRune(d.out, utf8.RuneError)
+			d.high = 0
+		}
+		switch {
+		case r >= 0xD800 && r <= 0xDBFF:
+			d.high = r
+		case r >= 0xDC00 && r <= 0xDFFF:
+			d.out = utf8.AppendRune(d.out, utf8.RuneError)
+		default:
+			d.out = utf8.AppendRune(d.out, r)
+		}
+	}
+	if atEOF && (len(in) > 0 || d.high != 0) {
+		d.out = utf8.AppendRune(d.out, utf8.RuneError)
+		d.high = 0
+		in = nil
+	}
+	d.in = append(d.in[:0], in...)
+}
@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// FrameRate is a video frame rate held as a ratio of frames per second, so
+// that rates such as 29.97 (30000/1001) convert frame counts exactly
+type FrameRate struct {
+	Num, Den int64
+}
+
+// Common frame rates
+var (
+	FrameRate23976 = FrameRate{Num: 24000, Den: 1001}
+	FrameRate24    = FrameRate{Num: 24, Den: 1}
+	FrameRate25    = FrameRate{Num: 25, Den: 1}
+	FrameRate2997  = FrameRate{Num: 30000, Den: 1001}
+	FrameRate30    = FrameRate{Num: 30, Den: 1}
+)
+
+// ntscBases are the whole rates whose NTSC variants run 1000/1001 as fast
+var ntscBases = []int64{24, 30, 48, 60, 120}
+
+// ParseFrameRate reads a frame rate given as a whole number ("25"), a
+// ratio ("24000/1001") or a decimal ("12.5"). The usual decimal
+// approximations of NTSC rates, such as 23.976, 23.98 and 29.97, are read
+// as their exact ratios.
+func ParseFrameRate(s string) (FrameRate, error) {
+	s = strings.TrimSpace(s)
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 || !rate.Num().IsInt64() || !rate.Denom().IsInt64() {
+		return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+	}
+	if !strings.Contains(s, "/") && !rate.IsInt() {
+		for _, base := range ntscBases {
+			ntsc := big.NewRat(base*1000, 1001)
+			diff := new(big.Rat).Sub(rate, ntsc)
+			if diff.Abs(diff).Cmp(big.NewRat(1, 200)) < 0 {
+				return FrameRate{Num: base * 1000, Den: 1001}, nil
+			}
+		}
+	}
+	return FrameRate{Num: rate.Num().Int64(), Den: rate.Denom().Int64()}, nil
+}
+
+// Valid reports whether the rate is positive
+func (r FrameRate) Valid() bool {
+	return r.Num > 0 && r.Den > 0
+}
+
+func (r FrameRate) String() string {
+	if r.Den == 1 {
+		return fmt.Sprint(r.Num)
+	}
+	return fmt.Sprintf("%d/%d", r.Num, r.Den)
+}
+
+// Duration returns the time taken by the given number of frames, rounded
+// down to the nanosecond
+func (r FrameRate) Duration(frames int64) time.Duration {
+	scaled := frames * r.Den
+	return time.Duration(scaled/r.Num)*time.Second + time.Duration(scaled%r.Num)*time.Second/time.Duration(r.Num)
+}
+
+// frameRat returns the length of one frame in nanoseconds
+func (r FrameRate) frameRat() *big.Rat {
+	return new(big.Rat).SetFrac64(r.Den*int64(time.Second), r.Num)
+}
+
+// ratDuration rounds a number of nanoseconds to the nearest nanosecond
+func ratDuration(ns *big.Rat) time.Duration {
+	n := new(big.Int).Mul(ns.Num(), big.NewInt(2))
+	n.Add(n, ns.Denom())
+	n.Quo(n, new(big.Int).Mul(ns.Denom(), big.NewInt(2)))
+	return time.Duration(n.Int64())
+}
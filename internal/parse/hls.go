@@ -0,0 +1,296 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// mpegtsClock is the rate of MPEG-2 timestamps, and mpegtsWrap the value at
+// which their 33 bits roll over
+const (
+	mpegtsClock = 90000
+	mpegtsWrap  = 1 << 33
+)
+
+var (
+	hlsExtinfRegex = regexp.MustCompile(`^#EXTINF:\s*(\d+(?:\.\d+)?)`)
+	hlsMPEGTSRegex = regexp.MustCompile(`MPEGTS:(\d+)`)
+	hlsLocalRegex  = regexp.MustCompile(`LOCAL:((?:\d+:)?\d{2}:\d{2}\.\d{3})`)
+)
+
+// hlsSegment is a WebVTT segment listed in an HLS playlist
+type hlsSegment struct {
+	uri string
+	// start is where the segment begins on the timeline of the playlist
+	start, duration time.Duration
+	// discontinuity is set when the segment follows #EXT-X-DISCONTINUITY
+	discontinuity bool
+}
+
+// HLSReader reads the cues of an HLS subtitle playlist: every WebVTT
+// segment it lists is read in turn, and its cue times are moved onto one
+// timeline by its X-TIMESTAMP-MAP header. A cue repeated in the next
+// segment because it spans the boundary between them is read once, with a
+// diagnostic.
+type HLSReader struct {
+	playlist *LineReader
+	base     string
+	opts     Options
+	segments []hlsSegment
+	started  bool
+	next     int
+
+	// anchorTS and anchorStart tie an MPEG-2 timestamp to the timeline;
+	// they are set by the first mapped segment and at every discontinuity
+	anchored    bool
+	anchorTS    int64
+	anchorStart time.Duration
+	lastTS      int64
+
+	// held are the cues of the last segment read that may be repeated in
+	// the next one
+	held    []Cue
+	pending []Cue
+}
+
+// NewHLSReader returns a reader of the HLS subtitle playlist read from
+// reader. Segment URIs are resolved against base, the path or URL of the
+// playlist; "" resolves them against the working directory.
+func NewHLSReader(reader io.Reader, base string, opts Options) *HLSReader {
+	if base == StdinPath {
+		base = ""
+	}
+	return &HLSReader{playlist: NewLineReader(reader, opts.MaxLineSize), base: base, opts: opts}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *HLSReader) Next() (Cue, error) {
+	if !p.started {
+		p.started = true
+		if err := p.readPlaylist(); err != nil {
+			return Cue{}, err
+		}
+	}
+
+	for len(p.pending) == 0 {
+		if p.next == len(p.segments) {
+			if len(p.held) == 0 {
+				return Cue{}, io.EOF
+			}
+			p.pending, p.held = p.held, nil
+			break
+		}
+		if err := p.readSegment(p.segments[p.next]); err != nil {
+			return Cue{}, err
+		}
+		p.next++
+	}
+
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+// readPlaylist lists the segments of the playlist
+func (p *HLSReader) readPlaylist() error {
+	var start, duration time.Duration
+	discontinuity := false
+	for p.playlist.Scan() {
+		line := strings.TrimSpace(p.playlist.Text())
+		if p.playlist.Line() == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+			if line != "#EXTM3U" {
+				return errors.New("invalid HLS playlist: it does not start with #EXTM3U")
+			}
+			continue
+		}
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF"), strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			return errors.New("HLS master playlists are not read; pass the playlist of the subtitle rendition")
+		case line == "#EXT-X-DISCONTINUITY":
+			discontinuity = true
+		case strings.HasPrefix(line, "#EXTINF:"):
+			m := hlsExtinfRegex.FindStringSubmatch(line)
+			if m == nil {
+				return fmt.Errorf("invalid HLS playlist: line %d: %q has no duration", p.playlist.Line(), TextPreview(line))
+			}
+			seconds, _ := strconv.ParseFloat(m[1], 64)
+			duration = time.Duration(seconds * float64(time.Second))
+		case strings.HasPrefix(line, "#"):
+		default:
+			p.segments = append(p.segments, hlsSegment{uri: line, start: start, duration: duration, discontinuity: discontinuity})
+			start += duration
+			duration, discontinuity = 0, false
+		}
+	}
+	return p.playlist.Err()
+}
+
+// readSegment reads the cues of a segment onto the timeline
+func (p *HLSReader) readSegment(segment hlsSegment) error {
+	uri := p.resolve(segment.uri)
+	source, err := Open(uri, p.opts)
+	if err != nil {
+		return fmt.Errorf("HLS segment %s: %v", segment.uri, err)
+	}
+	defer source.Close()
+	dec, err := newDecoder(source, FormatWebVTT)
+	if err != nil {
+		return fmt.Errorf("HLS segment %s: %v", segment.uri, err)
+	}
+
+	reader := NewWebVTTReader(dec, p.opts.MaxLineSize)
+	var cues []Cue
+	var offset time.Duration
+	var diagnostics []Diagnostic
+	for {
+		cue, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("HLS segment %s: %v", segment.uri, err)
+		}
+		if len(cues) == 0 {
+			offset, diagnostics = p.offset(segment, reader.TimestampMap())
+		}
+		cue.StartTime += offset
+		cue.EndTime += offset
+		// Line numbers of a segment do not point into the playlist
+		cue.Line = 0
+		for i, diag := range cue.Diagnostics {
+			cue.Diagnostics[i] = Diagnostic{Message: fmt.Sprintf("%s line %d: %s", segment.uri, diag.Line, diag.Message)}
+		}
+		cue.Diagnostics = append(diagnostics, cue.Diagnostics...)
+		diagnostics = nil
+		cues = append(cues, cue)
+	}
+
+	// A cue that starts before the segment and matches one held from the
+	// previous segment was repeated across the boundary
+	held := p.held
+	p.held = nil
+	for _, cue := range cues {
+		if cue.StartTime <= segment.start && mergeRepeatedCue(held, cue, segment.uri) {
+			continue
+		}
+		held = append(held, cue)
+	}
+	// Cues are held from the first that reaches the end of the segment,
+	// keeping them in order
+	end := segment.start + segment.duration
+	kept := len(held)
+	for i, cue := range held {
+		if cue.EndTime >= end {
+			kept = i
+			break
+		}
+	}
+	p.pending = append(p.pending, held[:kept]...)
+	p.held = held[kept:]
+	return nil
+}
+
+// mergeRepeatedCue extends the held cue that cue repeats, if any, and
+// reports whether there was one
+func mergeRepeatedCue(held []Cue, cue Cue, uri string) bool {
+	for i := range held {
+		h := &held[i]
+		if h.Text != cue.Text || cue.StartTime > h.EndTime || h.StartTime > cue.EndTime {
+			continue
+		}
+		if cue.EndTime > h.EndTime {
+			h.EndTime = cue.EndTime
+		}
+		h.Diagnostics = append(h.Diagnostics, cue.Diagnostics...)
+		h.Diagnostics = append(h.Diagnostics, Diagnostic{Code: models.CodeDuplicateCue,
+			Message: fmt.Sprintf("cue is repeated in segment %s across the segment boundary and is counted once", uri)})
+		return true
+	}
+	return false
+}
+
+// offset returns what moves the cue times of a segment onto the timeline.
+// The first mapped segment, and the first after each discontinuity, ties
+// its MPEG-2 timestamp to where the segment starts; later segments are
+// placed by how far their timestamp is from it, allowing for rollover. A
+// segment without X-TIMESTAMP-MAP keeps its cue times.
+func (p *HLSReader) offset(segment hlsSegment, mapping string) (time.Duration, []Diagnostic) {
+	if mapping == "" {
+		return 0, nil
+	}
+	ts, local, err := parseTimestampMap(mapping)
+	if err != nil {
+		return 0, []Diagnostic{{Message: fmt.Sprintf("%s: %v; its cue times are kept", segment.uri, err)}}
+	}
+	if !p.anchored || segment.discontinuity {
+		p.anchored = true
+		p.anchorTS, p.anchorStart, p.lastTS = ts, segment.start, ts
+	} else {
+		for ts+mpegtsWrap/2 < p.lastTS {
+			ts += mpegtsWrap
+		}
+		p.lastTS = ts
+	}
+	return p.anchorStart + mpegtsDuration(ts-p.anchorTS) - local, nil
+}
+
+// parseTimestampMap reads an X-TIMESTAMP-MAP value such as
+// "MPEGTS:900000,LOCAL:00:00:00.000"
+func parseTimestampMap(value string) (int64, time.Duration, error) {
+	ts := hlsMPEGTSRegex.FindStringSubmatch(value)
+	local := hlsLocalRegex.FindStringSubmatch(value)
+	if ts == nil || local == nil {
+		return 0, 0, fmt.Errorf("X-TIMESTAMP-MAP %q is not valid", TextPreview(value))
+	}
+	mpegts, err := strconv.ParseInt(ts[1], 10, 64)
+	if err != nil || mpegts >= mpegtsWrap {
+		return 0, 0, fmt.Errorf("X-TIMESTAMP-MAP %q is not valid", TextPreview(value))
+	}
+	localTime := local[1]
+	if strings.Count(localTime, ":") == 1 {
+		localTime = "00:" + localTime
+	}
+	offset, err := parseWebVTTTime(localTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("X-TIMESTAMP-MAP %q is not valid", TextPreview(value))
+	}
+	return mpegts, offset, nil
+}
+
+// mpegtsDuration converts a number of MPEG-2 clock ticks to a duration
+func mpegtsDuration(ticks int64) time.Duration {
+	return time.Duration(ticks/mpegtsClock)*time.Second + time.Duration(ticks%mpegtsClock)*time.Second/mpegtsClock
+}
+
+// resolve returns the path or URL of a segment listed in the playlist
+func (p *HLSReader) resolve(uri string) string {
+	if IsURL(uri) || p.base == "" {
+		return uri
+	}
+	if IsURL(p.base) {
+		base, err := url.Parse(p.base)
+		if err != nil {
+			return uri
+		}
+		ref, err := url.Parse(uri)
+		if err != nil {
+			return uri
+		}
+		return base.ResolveReference(ref).String()
+	}
+	if filepath.IsAbs(uri) {
+		return uri
+	}
+	return filepath.Join(filepath.Dir(p.base), filepath.FromSlash(uri))
+}
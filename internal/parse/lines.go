@@ -0,0 +1,118 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxLineSize is the longest line accepted when no limit is set. It
+// leaves room for inline images in WebVTT NOTE blocks.
+const DefaultMaxLineSize = 8 << 20
+
+// lineBufferSize is the read buffer of a LineReader. Longer lines are
+// assembled from several reads.
+const lineBufferSize = 64 << 10
+
+// LineTooLongError reports a line longer than the maximum line size
+type LineTooLongError struct {
+	Line int
+	Max  int
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("line %d is longer than the maximum line size of %d bytes", e.Line, e.Max)
+}
+
+// LineReader reads lines of up to a maximum size. Unlike bufio.Scanner it
+// has no fixed token limit, and memory use is bounded by the longest line
+// rather than the size of the input. Line endings (\n or \r\n) are removed.
+type LineReader struct {
+	r    *bufio.Reader
+	max  int
+	line []byte
+	num  int
+	err  error
+	done bool
+}
+
+// NewLineReader returns a reader of lines from r. A maxLineSize of zero or
+// less uses DefaultMaxLineSize.
+func NewLineReader(r io.Reader, maxLineSize int) *LineReader {
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	return &LineReader{r: bufio.NewReaderSize(r, lineBufferSize), max: maxLineSize}
+}
+
+// Scan advances to the next line, returning false at the end of the input
+// or on an error
+func (l *LineReader) Scan() bool {
+	if l.done {
+		return false
+	}
+	// Release the buffer of an unusually long line
+	if cap(l.line) > 4*lineBufferSize {
+		l.line = nil
+	}
+	l.line = l.line[:0]
+
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		l.line = append(l.line, chunk...)
+		switch {
+		case err == bufio.ErrBufferFull:
+			if len(l.line) > l.max {
+				return l.fail(&LineTooLongError{Line: l.num + 1, Max: l.max})
+			}
+			continue
+		case err == io.EOF:
+			l.done = true
+			if len(l.line) == 0 {
+				return false
+			}
+		case err != nil:
+			return l.fail(err)
+		}
+
+		l.num++
+		if n := len(l.line); n > 0 && l.line[n-1] == '\n' {
+			l.line = l.line[:n-1]
+		}
+		if n := len(l.line); n > 0 && l.line[n-1] == '\r' {
+			l.line = l.line[:n-1]
+		}
+		if len(l.line) > l.max {
+			return l.fail(&LineTooLongError{Line: l.num, Max: l.max})
+		}
+		return true
+	}
+}
+
+func (l *LineReader) fail(err error) bool {
+	l.err = err
+	l.done = true
+	l.line = nil
+	return false
+}
+
+// Text returns the current line
+func (l *LineReader) Text() string {
+	return string(l.line)
+}
+
+// Bytes returns the current line. The slice is only valid until the next
+// call to Scan.
+func (l *LineReader) Bytes() []byte {
+	return l.line
+}
+
+// Line returns the 1-based number of the current line
+func (l *LineReader) Line() int {
+	return l.num
+}
+
+// Err returns the first error other than io.EOF
+func (l *LineReader) Err() error {
+	return l.err
+}
@@ -0,0 +1,152 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+var (
+	microDVDLineRegex  = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+	microDVDStyleRegex = regexp.MustCompile(`\{[A-Za-z]:[^}]*\}`)
+)
+
+func ParseMicroDVD(reader io.Reader, rate FrameRate) ([]models.CaptionEntry, error) {
+	return collect(NewMicroDVDReader(reader, DefaultMaxLineSize, rate))
+}
+
+// MicroDVDReader reads MicroDVD (.sub) cues one at a time. Cues are timed
+// in frames, `{start}{end}text`, and converted to time at the frame rate
+// given to the reader or, failing that, the one declared by a
+// `{1}{1}23.976` header line.
+type MicroDVDReader struct {
+	scanner *LineReader
+	// rate is the frame rate given to the reader, and header the rate
+	// declared by the file
+	rate, header FrameRate
+	started      bool
+	diagnostics  []Diagnostic
+}
+
+// NewMicroDVDReader returns a streaming MicroDVD parser. A zero rate uses
+// the rate declared by the file.
+func NewMicroDVDReader(reader io.Reader, maxLineSize int, rate FrameRate) *MicroDVDReader {
+	return &MicroDVDReader{scanner: NewLineReader(reader, maxLineSize), rate: rate}
+}
+
+// FrameRate returns the frame rate cues are converted at, once the first
+// cue has been read
+func (p *MicroDVDReader) FrameRate() FrameRate {
+	if p.rate.Valid() {
+		return p.rate
+	}
+	return p.header
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *MicroDVDReader) Next() (Cue, error) {
+	for p.scanner.Scan() {
+		lineNum := p.scanner.Line()
+		line := strings.TrimSpace(p.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		matches := microDVDLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			p.diagnose(lineNum, fmt.Sprintf("line %q is not a MicroDVD cue and is ignored", TextPreview(line)))
+			continue
+		}
+		start, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			p.diagnose(lineNum, fmt.Sprintf("start frame %q is not recognised; the cue is ignored", TextPreview(matches[1])))
+			continue
+		}
+		end := start
+		if matches[2] == "" {
+			p.diagnose(lineNum, "cue has no end frame")
+		} else if end, err = strconv.ParseInt(matches[2], 10, 64); err != nil {
+			p.diagnose(lineNum, fmt.Sprintf("end frame %q is not recognised; the cue is ignored", TextPreview(matches[2])))
+			continue
+		}
+
+		if !p.started {
+			p.started = true
+			if p.readHeader(lineNum, start, end, matches[3]) {
+				continue
+			}
+			p.checkRate(lineNum)
+		}
+
+		lines := microDVDText(matches[3])
+		if len(lines) == 0 {
+			continue
+		}
+		rate := p.FrameRate()
+		cue := Cue{
+			CaptionEntry: models.CaptionEntry{
+				StartTime: rate.Duration(start),
+				EndTime:   rate.Duration(end),
+				Text:      strings.Join(lines, " "),
+				Lines:     lines,
+				Line:      lineNum,
+			},
+			Diagnostics: p.diagnostics,
+		}
+		p.diagnostics = nil
+		return cue, nil
+	}
+	if err := p.scanner.Err(); err != nil {
+		return Cue{}, err
+	}
+	return Cue{}, io.EOF
+}
+
+// readHeader reports whether the first cue is a `{1}{1}23.976` header
+// declaring the frame rate rather than a caption
+func (p *MicroDVDReader) readHeader(lineNum int, start, end int64, text string) bool {
+	if start > 1 || end > 1 {
+		return false
+	}
+	rate, err := ParseFrameRate(text)
+	if err != nil {
+		return false
+	}
+	p.header = rate
+	p.checkRate(lineNum)
+	return true
+}
+
+// checkRate reports a frame rate that is missing, or given to the reader
+// and different from the one the file declares
+func (p *MicroDVDReader) checkRate(lineNum int) {
+	switch {
+	case p.rate.Valid() && p.header.Valid() && p.rate != p.header:
+		p.diagnose(lineNum, fmt.Sprintf("the file declares %s frames per second; %s is used instead", p.header, p.rate))
+	case !p.rate.Valid() && !p.header.Valid():
+		p.header = FrameRate23976
+		p.diagnose(lineNum, "the file declares no frame rate; 24000/1001 (23.976) frames per second is assumed")
+	}
+}
+
+func (p *MicroDVDReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
+
+// microDVDText splits cue text into its lines, removing style codes such
+// as {y:i} and the leading slash that marks an italic line
+func microDVDText(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "|") {
+		line = microDVDStyleRegex.ReplaceAllString(line, "")
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "/"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,604 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// Matroska element IDs
+const (
+	mkvEBML              = 0x1A45DFA3
+	mkvDocType           = 0x4282
+	mkvSegment           = 0x18538067
+	mkvInfo              = 0x1549A966
+	mkvTimestampScale    = 0x2AD7B1
+	mkvDuration          = 0x4489
+	mkvTracks            = 0x1654AE6B
+	mkvTrackEntry        = 0xAE
+	mkvTrackNumber       = 0xD7
+	mkvTrackType         = 0x83
+	mkvCodecID           = 0x86
+	mkvName              = 0x536E
+	mkvLanguage          = 0x22B59C
+	mkvLanguageBCP47     = 0x22B59D
+	mkvContentEncodings  = 0x6D80
+	mkvContentEncoding   = 0x6240
+	mkvContentCompress   = 0x5034
+	mkvContentCompAlgo   = 0x4254
+	mkvContentCompSet    = 0x4255
+	mkvContentEncryption = 0x5035
+	mkvCluster           = 0x1F43B675
+	mkvTimestamp         = 0xE7
+	mkvSimpleBlock       = 0xA3
+	mkvBlockGroup        = 0xA0
+	mkvBlock             = 0xA1
+	mkvBlockDuration     = 0x9B
+)
+
+// mkvTrackTypeSubtitle is the TrackType of subtitle tracks
+const mkvTrackTypeSubtitle = 0x11
+
+// mkvMaxElementSize bounds the elements read into memory: headers, track
+// lists and subtitle blocks
+const mkvMaxElementSize = 16 << 20
+
+// mkvTextCodecs are the subtitle codecs whose text can be read
+var mkvTextCodecs = map[string]bool{
+	"S_TEXT/UTF8":   true,
+	"S_TEXT/ASCII":  true,
+	"S_TEXT/ASS":    true,
+	"S_TEXT/SSA":    true,
+	"S_TEXT/WEBVTT": true,
+}
+
+// mkvTrack is a subtitle track of a Matroska file
+type mkvTrack struct {
+	containerTrack
+	// compression is the zlib (0) or header stripping (3) algorithm of
+	// the track's blocks, or -1
+	compression int
+	stripped    []byte
+	// encoded is set when the blocks are encrypted or compressed in a way
+	// that cannot be undone
+	encoded bool
+}
+
+// mkvSubtitle is a subtitle block whose cue is complete once the rest of its
+// block group has been read
+type mkvSubtitle struct {
+	track    *mkvTrack
+	start    time.Duration
+	duration time.Duration
+	data     []byte
+	timed    bool
+}
+
+// MatroskaReader reads the text subtitle tracks of a Matroska or WebM file.
+// The file is read from start to end, skipping the audio and video blocks
+// between subtitle blocks, so it needs no random access.
+type MatroskaReader struct {
+	r        *bufio.Reader
+	pos      int64
+	scale    time.Duration
+	duration time.Duration
+	tracks   map[uint64]*mkvTrack
+	listed   []models.Track
+
+	clusterTime int64
+	// group is the subtitle block of the block group being read, which
+	// ends at groupEnd
+	group    *mkvSubtitle
+	groupEnd int64
+	inGroup  bool
+
+	pending     []Cue
+	diagnostics []Diagnostic
+	eof         bool
+}
+
+// NewMatroskaReader reads the header and track list of a Matroska or WebM
+// file, up to its first cluster
+func NewMatroskaReader(reader io.Reader) (*MatroskaReader, error) {
+	p := &MatroskaReader{r: bufio.NewReader(reader), scale: time.Millisecond, tracks: map[uint64]*mkvTrack{}}
+
+	id, size, err := p.header()
+	if err != nil || id != mkvEBML {
+		return nil, errors.New("invalid Matroska: no EBML header")
+	}
+	head, err := p.read(size)
+	if err != nil {
+		return nil, p.fail(err)
+	}
+	docType := "matroska"
+	ebmlChildren(head, func(id uint32, body []byte) {
+		if id == mkvDocType {
+			docType = ebmlString(body)
+		}
+	})
+	if docType != "matroska" && docType != "webm" {
+		return nil, fmt.Errorf("invalid Matroska: document type %q", docType)
+	}
+
+	for {
+		id, size, err := p.header()
+		if err != nil {
+			return nil, p.fail(err)
+		}
+		switch id {
+		case mkvSegment:
+			// The segment is entered rather than read
+		case mkvInfo, mkvTracks:
+			body, err := p.read(size)
+			if err != nil {
+				return nil, p.fail(err)
+			}
+			if id == mkvInfo {
+				p.readInfo(body)
+			} else {
+				p.readTracks(body)
+			}
+		case mkvCluster:
+			p.clusterTime = 0
+			return p, nil
+		default:
+			if err := p.skip(size); err != nil {
+				return nil, p.fail(err)
+			}
+		}
+	}
+}
+
+// Tracks lists the subtitle tracks whose text can be read
+func (p *MatroskaReader) Tracks() []models.Track {
+	return p.listed
+}
+
+// Duration returns the duration the file declares, or 0
+func (p *MatroskaReader) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *MatroskaReader) describe(in *Input) {
+	in.Tracks = p.listed
+	in.Duration = p.duration
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *MatroskaReader) Next() (Cue, error) {
+	for len(p.pending) == 0 {
+		if p.eof {
+			return Cue{}, io.EOF
+		}
+		if err := p.step(); err != nil {
+			return Cue{}, err
+		}
+	}
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+// step reads the next element of the clusters
+func (p *MatroskaReader) step() error {
+	if p.inGroup && p.pos >= p.groupEnd {
+		p.inGroup = false
+		p.endBlock()
+	}
+
+	id, size, err := p.header()
+	if err == io.EOF {
+		p.eof = true
+		p.endBlock()
+		return nil
+	}
+	if err != nil {
+		return p.fail(err)
+	}
+
+	switch id {
+	case mkvSegment, mkvCluster:
+		p.clusterTime = 0
+	case mkvBlockGroup:
+		if size < 0 {
+			return errors.New("invalid Matroska: block group of unknown size")
+		}
+		p.endBlock()
+		p.inGroup, p.groupEnd = true, p.pos+size
+	case mkvTimestamp:
+		body, err := p.read(size)
+		if err != nil {
+			return p.fail(err)
+		}
+		p.clusterTime = int64(ebmlUint(body))
+	case mkvSimpleBlock, mkvBlock:
+		return p.readBlock(size, id == mkvBlock && p.inGroup)
+	case mkvBlockDuration:
+		body, err := p.read(size)
+		if err != nil {
+			return p.fail(err)
+		}
+		if p.group != nil && p.inGroup {
+			p.group.duration = time.Duration(ebmlUint(body)) * p.scale
+			p.group.timed = true
+		}
+	default:
+		if size < 0 {
+			return fmt.Errorf("invalid Matroska: element %X of unknown size", id)
+		}
+		if err := p.skip(size); err != nil {
+			return p.fail(err)
+		}
+	}
+	return nil
+}
+
+// readBlock reads a block of a subtitle track, skipping the blocks of
+// other tracks
+func (p *MatroskaReader) readBlock(size int64, grouped bool) error {
+	start := p.pos
+	number, _, err := p.vint()
+	if err != nil {
+		return p.fail(err)
+	}
+	track := p.tracks[number]
+	if track == nil || !track.readable {
+		return p.skip(size - (p.pos - start))
+	}
+
+	body, err := p.read(size - (p.pos - start))
+	if err != nil {
+		return p.fail(err)
+	}
+	if len(body) < 3 {
+		return errors.New("invalid Matroska: block too short")
+	}
+	block := &mkvSubtitle{
+		track: track,
+		start: time.Duration(p.clusterTime+int64(int16(binary.BigEndian.Uint16(body)))) * p.scale,
+		data:  body[3:],
+	}
+	if body[2]&0x06 != 0 {
+		p.diagnostics = append(p.diagnostics, Diagnostic{Message: fmt.Sprintf("subtitle track %s has a laced block at %s, which is ignored", track.ID, block.start)})
+		return nil
+	}
+
+	p.endBlock()
+	if grouped {
+		p.group = block
+		return nil
+	}
+	p.emit(block)
+	return nil
+}
+
+// endBlock completes the cue of the block group read so far
+func (p *MatroskaReader) endBlock() {
+	if p.group != nil {
+		p.emit(p.group)
+		p.group = nil
+	}
+}
+
+// emit queues the cue of a subtitle block
+func (p *MatroskaReader) emit(block *mkvSubtitle) {
+	data, err := block.track.decode(block.data)
+	if err != nil {
+		p.diagnostics = append(p.diagnostics, Diagnostic{Message: fmt.Sprintf("subtitle track %s: block at %s cannot be decoded: %v", block.track.ID, block.start, err)})
+		return
+	}
+
+	var lines []string
+	var metadata map[string]string
+	switch block.track.codec {
+	case "S_TEXT/ASS", "S_TEXT/SSA":
+		// ReadOrder, Layer, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+		fields := strings.SplitN(string(data), ",", 9)
+		if len(fields) < 9 {
+			p.diagnostics = append(p.diagnostics, Diagnostic{Message: fmt.Sprintf("subtitle track %s: block at %s has %d fields, expected 9, and is ignored", block.track.ID, block.start, len(fields))})
+			return
+		}
+		lines = ASSText(fields[8])
+		metadata = map[string]string{}
+		if style := strings.TrimSpace(fields[2]); style != "" {
+			metadata["style"] = style
+		}
+		if actor := strings.TrimSpace(fields[3]); actor != "" {
+			metadata["actor"] = actor
+		}
+		if len(metadata) == 0 {
+			metadata = nil
+		}
+	default:
+		lines = textLines(strings.ReplaceAll(string(data), "\r\n", "\n"))
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	if !block.timed {
+		p.diagnostics = append(p.diagnostics, Diagnostic{Message: fmt.Sprintf("subtitle track %s: block at %s has no duration", block.track.ID, block.start)})
+	}
+	p.pending = append(p.pending, Cue{
+		CaptionEntry: models.CaptionEntry{
+			StartTime: block.start,
+			EndTime:   block.start + block.duration,
+			Text:      strings.Join(lines, " "),
+			Lines:     lines,
+			Track:     block.track.ID,
+			Language:  block.track.Language,
+			Metadata:  metadata,
+		},
+		Diagnostics: p.diagnostics,
+	})
+	p.diagnostics = nil
+}
+
+// readInfo reads the timestamp scale and duration of the segment
+func (p *MatroskaReader) readInfo(body []byte) {
+	var duration float64
+	ebmlChildren(body, func(id uint32, value []byte) {
+		switch id {
+		case mkvTimestampScale:
+			if scale := ebmlUint(value); scale > 0 {
+				p.scale = time.Duration(scale)
+			}
+		case mkvDuration:
+			duration = ebmlFloat(value)
+		}
+	})
+	p.duration = time.Duration(duration * float64(p.scale))
+}
+
+// readTracks lists the subtitle tracks of the segment
+func (p *MatroskaReader) readTracks(body []byte) {
+	ebmlChildren(body, func(id uint32, entry []byte) {
+		if id != mkvTrackEntry {
+			return
+		}
+		// The language of a track is English unless it says otherwise
+		track := &mkvTrack{compression: -1}
+		language, bcp47 := "eng", ""
+		var kind uint64
+		var number uint64
+		ebmlChildren(entry, func(id uint32, value []byte) {
+			switch id {
+			case mkvTrackNumber:
+				number = ebmlUint(value)
+			case mkvTrackType:
+				kind = ebmlUint(value)
+			case mkvCodecID:
+				track.codec = ebmlString(value)
+			case mkvName:
+				track.Name = ebmlString(value)
+			case mkvLanguage:
+				language = ebmlString(value)
+			case mkvLanguageBCP47:
+				bcp47 = ebmlString(value)
+			case mkvContentEncodings:
+				track.readEncodings(value)
+			}
+		})
+		if kind != mkvTrackTypeSubtitle {
+			return
+		}
+
+		track.ID = strconv.FormatUint(number, 10)
+		track.Language = containerLanguage(language)
+		if bcp47 != "" && bcp47 != "und" {
+			track.Language = bcp47
+		}
+		track.readable = mkvTextCodecs[track.codec] && !track.encoded
+		p.tracks[number] = track
+		if !track.readable {
+			p.diagnostics = append(p.diagnostics, unreadableTrack(track.containerTrack))
+			return
+		}
+		p.listed = append(p.listed, track.Track)
+	})
+}
+
+// readEncodings reads how the blocks of a track are compressed. An
+// encrypted track cannot be read.
+func (t *mkvTrack) readEncodings(body []byte) {
+	ebmlChildren(body, func(id uint32, encoding []byte) {
+		if id != mkvContentEncoding {
+			return
+		}
+		ebmlChildren(encoding, func(id uint32, value []byte) {
+			switch id {
+			case mkvContentEncryption:
+				t.encoded = true
+			case mkvContentCompress:
+				t.compression = 0
+				ebmlChildren(value, func(id uint32, setting []byte) {
+					switch id {
+					case mkvContentCompAlgo:
+						t.compression = int(ebmlUint(setting))
+					case mkvContentCompSet:
+						t.stripped = setting
+					}
+				})
+			}
+		})
+	})
+	if t.compression != -1 && t.compression != 0 && t.compression != 3 {
+		t.encoded = true
+	}
+}
+
+// decode undoes the compression of a block of the track
+func (t *mkvTrack) decode(data []byte) ([]byte, error) {
+	switch t.compression {
+	case 0:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(io.LimitReader(zr, mkvMaxElementSize))
+	case 3:
+		return append(append([]byte{}, t.stripped...), data...), nil
+	}
+	return data, nil
+}
+
+// header reads the ID and size of the next element. The size is -1 when
+// it is unknown.
+func (p *MatroskaReader) header() (uint32, int64, error) {
+	first, err := p.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	p.pos++
+	length := bitsLeading(first) + 1
+	if length > 4 {
+		return 0, 0, errors.New("invalid Matroska: bad element ID")
+	}
+	id := uint32(first)
+	for i := 1; i < length; i++ {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		p.pos++
+		id = id<<8 | uint32(b)
+	}
+
+	size, length, err := p.vint()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if size == 1<<(7*uint(length))-1 {
+		return id, -1, nil
+	}
+	if size > math.MaxInt64/2 {
+		return 0, 0, errors.New("invalid Matroska: bad element size")
+	}
+	return id, int64(size), nil
+}
+
+// vint reads a variable-length integer without its length marker
+func (p *MatroskaReader) vint() (uint64, int, error) {
+	first, err := p.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	p.pos++
+	length := bitsLeading(first) + 1
+	if length > 8 {
+		return 0, 0, errors.New("invalid Matroska: bad variable-length integer")
+	}
+	value := uint64(first) & (0xFF >> uint(length))
+	for i := 1; i < length; i++ {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		p.pos++
+		value = value<<8 | uint64(b)
+	}
+	return value, length, nil
+}
+
+func (p *MatroskaReader) read(size int64) ([]byte, error) {
+	if size < 0 || size > mkvMaxElementSize {
+		return nil, fmt.Errorf("invalid Matroska: element of %d bytes at offset %d is too large", size, p.pos)
+	}
+	buf := make([]byte, size)
+	n, err := io.ReadFull(p.r, buf)
+	p.pos += int64(n)
+	return buf, err
+}
+
+func (p *MatroskaReader) skip(size int64) error {
+	if size < 0 {
+		return errors.New("invalid Matroska: element of unknown size")
+	}
+	n, err := io.CopyN(io.Discard, p.r, size)
+	p.pos += n
+	return err
+}
+
+// fail describes an error reading the file
+func (p *MatroskaReader) fail(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("invalid Matroska: file is truncated")
+	}
+	return err
+}
+
+// bitsLeading returns the number of leading zero bits of b
+func bitsLeading(b byte) int {
+	n := 0
+	for mask := byte(0x80); mask != 0 && b&mask == 0; mask >>= 1 {
+		n++
+	}
+	return n
+}
+
+// ebmlChildren calls fn with the ID and body of each element in data,
+// stopping at the first malformed one
+func ebmlChildren(data []byte, fn func(id uint32, body []byte)) {
+	for len(data) > 0 {
+		idLength := bitsLeading(data[0]) + 1
+		if idLength > 4 || len(data) < idLength+1 {
+			return
+		}
+		var id uint32
+		for _, b := range data[:idLength] {
+			id = id<<8 | uint32(b)
+		}
+		data = data[idLength:]
+
+		sizeLength := bitsLeading(data[0]) + 1
+		if sizeLength > 8 || len(data) < sizeLength {
+			return
+		}
+		size := uint64(data[0]) & (0xFF >> uint(sizeLength))
+		for _, b := range data[1:sizeLength] {
+			size = size<<8 | uint64(b)
+		}
+		data = data[sizeLength:]
+		if size > uint64(len(data)) {
+			return
+		}
+		fn(id, data[:size])
+		data = data[size:]
+	}
+}
+
+func ebmlUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func ebmlFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+func ebmlString(data []byte) string {
+	return strings.TrimRight(string(data), "\x00")
+}
@@ -0,0 +1,684 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// mp4MaxBoxSize bounds the boxes read into memory: the movie header, the
+// headers of fragments and subtitle samples
+const mp4MaxBoxSize = 64 << 20
+
+// mp4MaxSamples bounds the subtitle samples of a file, whose counts are
+// read from the file before any sample is
+const mp4MaxSamples = 1 << 20
+
+// mp4TextEntries are the sample entries of subtitle tracks whose text can
+// be read: 3GPP timed text and WebVTT
+var mp4TextEntries = map[string]bool{"tx3g": true, "wvtt": true}
+
+// mp4Sample locates a sample of a subtitle track in the file
+type mp4Sample struct {
+	offset     int64
+	size       uint32
+	start, dur uint64
+}
+
+// mp4Track is a subtitle track of an MP4 file
+type mp4Track struct {
+	containerTrack
+	number    uint32
+	timescale uint32
+	samples   []mp4Sample
+	// next is the decode time following the last sample read from a
+	// fragment, for a fragment that does not give its own
+	next uint64
+}
+
+// mp4TrackDefaults are the sample defaults a movie gives a fragmented
+// track
+type mp4TrackDefaults struct {
+	duration, size uint32
+}
+
+// MP4Reader reads the text subtitle tracks of an MP4 or QuickTime file: 3GPP
+// timed text (tx3g) and WebVTT (wvtt), plain or fragmented. Its sample
+// tables are read first and then each subtitle sample, track by track.
+type MP4Reader struct {
+	r        io.ReaderAt
+	size     int64
+	duration time.Duration
+	tracks   []*mp4Track
+	listed   []models.Track
+	// sampleCount is the number of subtitle samples located so far
+	sampleCount uint64
+
+	track, sample int
+	pending       []Cue
+	diagnostics   []Diagnostic
+}
+
+// NewMP4Reader reads the sample tables of the MP4 file of the given size
+// read from r
+func NewMP4Reader(r io.ReaderAt, size int64) (*MP4Reader, error) {
+	p := &MP4Reader{r: r, size: size}
+	var moov []byte
+	var fragments []int64
+	for offset := int64(0); offset < size; {
+		typ, header, boxSize, err := p.boxHeader(offset, size)
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "moov":
+			if moov, err = p.readAt(offset+header, boxSize-header); err != nil {
+				return nil, err
+			}
+		case "moof":
+			fragments = append(fragments, offset)
+		}
+		offset += boxSize
+	}
+	if moov == nil {
+		return nil, errors.New("invalid MP4: no movie box (moov)")
+	}
+
+	defaults, err := p.readMovie(moov)
+	if err != nil {
+		return nil, err
+	}
+	for _, offset := range fragments {
+		_, header, boxSize, err := p.boxHeader(offset, size)
+		if err != nil {
+			return nil, err
+		}
+		moof, err := p.readAt(offset+header, boxSize-header)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.readFragment(offset, moof, defaults); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Tracks lists the subtitle tracks whose text can be read
+func (p *MP4Reader) Tracks() []models.Track {
+	return p.listed
+}
+
+// Duration returns the duration the file declares, or 0
+func (p *MP4Reader) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *MP4Reader) describe(in *Input) {
+	in.Tracks = p.listed
+	in.Duration = p.duration
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *MP4Reader) Next() (Cue, error) {
+	for len(p.pending) == 0 {
+		if p.track == len(p.tracks) {
+			return Cue{}, io.EOF
+		}
+		track := p.tracks[p.track]
+		if p.sample == len(track.samples) {
+			p.track, p.sample = p.track+1, 0
+			continue
+		}
+		sample := track.samples[p.sample]
+		p.sample++
+		if sample.size == 0 {
+			continue
+		}
+		data, err := p.readAt(sample.offset, int64(sample.size))
+		if err != nil {
+			return Cue{}, err
+		}
+		p.emit(track, sample, data)
+	}
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+// emit queues the cues of a sample
+func (p *MP4Reader) emit(track *mp4Track, sample mp4Sample, data []byte) {
+	start := mp4Duration(sample.start, track.timescale)
+	end := mp4Duration(sample.start+sample.dur, track.timescale)
+
+	var texts []string
+	switch track.codec {
+	case "tx3g":
+		if len(data) < 2 {
+			return
+		}
+		length := int(binary.BigEndian.Uint16(data))
+		if length > len(data)-2 {
+			p.diagnostics = append(p.diagnostics, Diagnostic{Message: fmt.Sprintf("subtitle track %s: sample at %s is truncated", track.ID, start)})
+			length = len(data) - 2
+		}
+		texts = []string{tx3gText(data[2 : 2+length])}
+	case "wvtt":
+		// Each cue box holds the payload of one cue; empty boxes fill gaps
+		mp4Boxes(data, func(typ string, body []byte) {
+			if typ != "vttc" {
+				return
+			}
+			mp4Boxes(body, func(typ string, payload []byte) {
+				if typ == "payl" {
+					texts = append(texts, string(payload))
+				}
+			})
+		})
+	}
+
+	for _, text := range texts {
+		lines := textLines(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text))
+		if len(lines) == 0 {
+			continue
+		}
+		p.pending = append(p.pending, Cue{
+			CaptionEntry: models.CaptionEntry{
+				StartTime: start,
+				EndTime:   end,
+				Text:      strings.Join(lines, " "),
+				Lines:     lines,
+				Track:     track.ID,
+				Language:  track.Language,
+			},
+			Diagnostics: p.diagnostics,
+		})
+		p.diagnostics = nil
+	}
+}
+
+// tx3gText decodes the text of a timed text sample, which is UTF-8 unless
+// it starts with a UTF-16 byte order mark
+func tx3gText(data []byte) string {
+	if len(data) < 2 || data[0] != 0xFE || data[1] != 0xFF {
+		return string(data)
+	}
+	units := make([]uint16, (len(data)-2)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(data[2+2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// readMovie reads the duration and subtitle tracks of the movie box and
+// returns the sample defaults of fragmented tracks
+func (p *MP4Reader) readMovie(moov []byte) (map[uint32]mp4TrackDefaults, error) {
+	// Chapter titles are text tracks too, named by the chap reference of
+	// another track
+	chapters := map[uint32]bool{}
+	mp4Boxes(moov, func(typ string, trak []byte) {
+		if typ != "trak" {
+			return
+		}
+		mp4Boxes(trak, func(typ string, tref []byte) {
+			if typ != "tref" {
+				return
+			}
+			mp4Boxes(tref, func(typ string, ids []byte) {
+				for i := 0; typ == "chap" && i+4 <= len(ids); i += 4 {
+					chapters[binary.BigEndian.Uint32(ids[i:])] = true
+				}
+			})
+		})
+	})
+
+	defaults := map[uint32]mp4TrackDefaults{}
+	var err error
+	mp4Boxes(moov, func(typ string, body []byte) {
+		switch typ {
+		case "mvhd":
+			timescale, duration, ok := mp4TimeHeader(body)
+			if ok {
+				p.duration = mp4Duration(duration, timescale)
+			}
+		case "trak":
+			if err == nil {
+				err = p.readTrack(body, chapters)
+			}
+		case "mvex":
+			mp4Boxes(body, func(typ string, trex []byte) {
+				if typ == "trex" && len(trex) >= 20 {
+					defaults[binary.BigEndian.Uint32(trex[4:])] = mp4TrackDefaults{
+						duration: binary.BigEndian.Uint32(trex[12:]),
+						size:     binary.BigEndian.Uint32(trex[16:]),
+					}
+				}
+			})
+		}
+	})
+	return defaults, err
+}
+
+// readTrack adds a subtitle track and the samples of its sample table
+func (p *MP4Reader) readTrack(trak []byte, chapters map[uint32]bool) error {
+	track := &mp4Track{}
+	var handler, language, extended string
+	var stbl []byte
+	mp4Boxes(trak, func(typ string, body []byte) {
+		switch typ {
+		case "tkhd":
+			offset := 12
+			if len(body) > 0 && body[0] == 1 {
+				offset = 20
+			}
+			if len(body) >= offset+4 {
+				track.number = binary.BigEndian.Uint32(body[offset:])
+			}
+		case "mdia":
+			mp4Boxes(body, func(typ string, body []byte) {
+				switch typ {
+				case "mdhd":
+					track.timescale, _, _ = mp4TimeHeader(body)
+					language = mdhdLanguage(body)
+				case "elng":
+					if len(body) > 4 {
+						extended = strings.TrimRight(string(body[4:]), "\x00")
+					}
+				case "hdlr":
+					if len(body) >= 24 {
+						handler = string(body[8:12])
+						track.Name = hdlrName(body[24:])
+					}
+				case "minf":
+					mp4Boxes(body, func(typ string, body []byte) {
+						if typ == "stbl" {
+							stbl = body
+						}
+					})
+				}
+			})
+		}
+	})
+	if handler != "sbtl" && handler != "subt" && handler != "text" || chapters[track.number] {
+		return nil
+	}
+
+	track.ID = strconv.FormatUint(uint64(track.number), 10)
+	track.Language = containerLanguage(language)
+	if extended != "" && extended != "und" {
+		track.Language = extended
+	}
+	mp4Boxes(stbl, func(typ string, body []byte) {
+		if typ == "stsd" && len(body) >= 16 {
+			track.codec = string(body[12:16])
+		}
+	})
+	if track.timescale == 0 || !mp4TextEntries[track.codec] {
+		p.diagnostics = append(p.diagnostics, unreadableTrack(track.containerTrack))
+		return nil
+	}
+	track.readable = true
+	samples, err := p.sampleTable(track, stbl)
+	if err != nil {
+		return err
+	}
+	track.samples = samples
+	for _, sample := range track.samples {
+		track.next = sample.start + sample.dur
+	}
+	p.tracks = append(p.tracks, track)
+	p.listed = append(p.listed, track.Track)
+	return nil
+}
+
+// sampleTable locates the samples of the sample table box of a track
+func (p *MP4Reader) sampleTable(track *mp4Track, stbl []byte) ([]mp4Sample, error) {
+	var deltas, chunkRuns [][2]uint32
+	var sizes []uint32
+	var fixedSize, count uint32
+	var chunks []int64
+	mp4Boxes(stbl, func(typ string, body []byte) {
+		switch typ {
+		case "stts":
+			deltas = mp4Pairs(body)
+		case "stsc":
+			for _, entry := range mp4Entries(body, 12) {
+				chunkRuns = append(chunkRuns, [2]uint32{binary.BigEndian.Uint32(entry), binary.BigEndian.Uint32(entry[4:])})
+			}
+		case "stsz":
+			if len(body) < 12 {
+				return
+			}
+			fixedSize, count = binary.BigEndian.Uint32(body[4:]), binary.BigEndian.Uint32(body[8:])
+			if fixedSize == 0 {
+				for i := 0; i < int(count) && 12+4*i+4 <= len(body); i++ {
+					sizes = append(sizes, binary.BigEndian.Uint32(body[12+4*i:]))
+				}
+			}
+		case "stco":
+			for _, entry := range mp4Entries(body, 4) {
+				chunks = append(chunks, int64(binary.BigEndian.Uint32(entry)))
+			}
+		case "co64":
+			for _, entry := range mp4Entries(body, 8) {
+				chunks = append(chunks, int64(binary.BigEndian.Uint64(entry)))
+			}
+		}
+	})
+	if fixedSize == 0 {
+		count = uint32(len(sizes))
+	}
+	if err := p.reserveSamples(track, uint64(count), 0, fixedSize); err != nil {
+		return nil, err
+	}
+
+	samples := make([]mp4Sample, 0, count)
+	var decode uint64
+	for _, run := range deltas {
+		for i := uint32(0); i < run[0] && len(samples) < int(count); i++ {
+			size := fixedSize
+			if fixedSize == 0 {
+				size = sizes[len(samples)]
+			}
+			samples = append(samples, mp4Sample{size: size, start: decode, dur: uint64(run[1])})
+			decode += uint64(run[1])
+		}
+	}
+
+	// Samples are stored in chunks, consecutively within each
+	n := 0
+	for i, offset := range chunks {
+		perChunk := uint32(0)
+		for _, run := range chunkRuns {
+			if int(run[0]) <= i+1 {
+				perChunk = run[1]
+			}
+		}
+		for j := uint32(0); j < perChunk && n < len(samples); j++ {
+			samples[n].offset = offset
+			offset += int64(samples[n].size)
+			n++
+		}
+	}
+	return samples[:n], nil
+}
+
+// readFragment adds the samples of the subtitle tracks in a movie fragment
+// that starts at offset
+func (p *MP4Reader) readFragment(offset int64, moof []byte, defaults map[uint32]mp4TrackDefaults) error {
+	var err error
+	mp4Boxes(moof, func(typ string, traf []byte) {
+		if typ != "traf" || err != nil {
+			return
+		}
+		var track *mp4Track
+		base := offset
+		var trackDefaults mp4TrackDefaults
+		var decodeTime uint64
+		hasTime := false
+		mp4Boxes(traf, func(typ string, body []byte) {
+			switch typ {
+			case "tfhd":
+				if len(body) < 8 {
+					return
+				}
+				flags := binary.BigEndian.Uint32(body) & 0xFFFFFF
+				number := binary.BigEndian.Uint32(body[4:])
+				for _, t := range p.tracks {
+					if t.number == number {
+						track = t
+					}
+				}
+				trackDefaults = defaults[number]
+				rest := body[8:]
+				if flags&0x01 != 0 && len(rest) >= 8 {
+					base = int64(binary.BigEndian.Uint64(rest))
+					rest = rest[8:]
+				}
+				if flags&0x02 != 0 && len(rest) >= 4 {
+					rest = rest[4:]
+				}
+				if flags&0x08 != 0 && len(rest) >= 4 {
+					trackDefaults.duration = binary.BigEndian.Uint32(rest)
+					rest = rest[4:]
+				}
+				if flags&0x10 != 0 && len(rest) >= 4 {
+					trackDefaults.size = binary.BigEndian.Uint32(rest)
+				}
+			case "tfdt":
+				if len(body) >= 12 && body[0] == 1 {
+					decodeTime, hasTime = binary.BigEndian.Uint64(body[4:]), true
+				} else if len(body) >= 8 {
+					decodeTime, hasTime = uint64(binary.BigEndian.Uint32(body[4:])), true
+				}
+			case "trun":
+				if track == nil || err != nil {
+					return
+				}
+				if !hasTime {
+					decodeTime = track.next
+				}
+				decodeTime, err = p.addRun(track, body, base, decodeTime, trackDefaults)
+				track.next, hasTime = decodeTime, true
+			}
+		})
+	})
+	return err
+}
+
+// addRun adds the samples of a track run to a track and returns the decode
+// time that follows them
+func (p *MP4Reader) addRun(t *mp4Track, trun []byte, base int64, decodeTime uint64, defaults mp4TrackDefaults) (uint64, error) {
+	if len(trun) < 8 {
+		return decodeTime, nil
+	}
+	flags := binary.BigEndian.Uint32(trun) & 0xFFFFFF
+	count := binary.BigEndian.Uint32(trun[4:])
+	rest := trun[8:]
+	offset := base
+	if flags&0x01 != 0 && len(rest) >= 4 {
+		offset = base + int64(int32(binary.BigEndian.Uint32(rest)))
+		rest = rest[4:]
+	}
+	if flags&0x04 != 0 && len(rest) >= 4 {
+		rest = rest[4:]
+	}
+
+	// Each sample has a 4-byte entry per field the flags list
+	fields, size := 0, defaults.size
+	for _, field := range []uint32{0x100, 0x200, 0x400, 0x800} {
+		if flags&field != 0 {
+			fields++
+		}
+	}
+	if uint64(count)*uint64(4*fields) > uint64(len(rest)) {
+		return decodeTime, fmt.Errorf("invalid MP4: track run of %d samples of subtitle track %s is truncated", count, t.ID)
+	}
+	if flags&0x200 != 0 {
+		size = 0
+	}
+	if err := p.reserveSamples(t, uint64(count), offset, size); err != nil {
+		return decodeTime, err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		sample := mp4Sample{offset: offset, size: defaults.size, start: decodeTime, dur: uint64(defaults.duration)}
+		for _, field := range []uint32{0x100, 0x200, 0x400, 0x800} {
+			if flags&field == 0 {
+				continue
+			}
+			switch field {
+			case 0x100:
+				sample.dur = uint64(binary.BigEndian.Uint32(rest))
+			case 0x200:
+				sample.size = binary.BigEndian.Uint32(rest)
+			}
+			rest = rest[4:]
+		}
+		t.samples = append(t.samples, sample)
+		offset += int64(sample.size)
+		decodeTime += sample.dur
+	}
+	return decodeTime, nil
+}
+
+// reserveSamples counts the samples of a track about to be located, which
+// must fit in the file when each has the given size, before they are
+// allocated
+func (p *MP4Reader) reserveSamples(t *mp4Track, count uint64, offset int64, size uint32) error {
+	if count > mp4MaxSamples-p.sampleCount {
+		return fmt.Errorf("invalid MP4: subtitle track %s has more than %d samples", t.ID, mp4MaxSamples)
+	}
+	if size > 0 && count > 0 && (offset < 0 || offset >= p.size || count > uint64(p.size-offset)/uint64(size)) {
+		return fmt.Errorf("invalid MP4: %d samples of subtitle track %s do not fit in the file", count, t.ID)
+	}
+	p.sampleCount += count
+	return nil
+}
+
+// boxHeader reads the type, header length and size of the box at offset
+func (p *MP4Reader) boxHeader(offset, fileSize int64) (string, int64, int64, error) {
+	head := make([]byte, 16)
+	n, err := p.r.ReadAt(head, offset)
+	if n < 8 {
+		if err == nil || err == io.EOF {
+			err = errors.New("invalid MP4: file is truncated")
+		}
+		return "", 0, 0, err
+	}
+	typ := string(head[4:8])
+	size, header := int64(binary.BigEndian.Uint32(head)), int64(8)
+	switch size {
+	case 0:
+		size = fileSize - offset
+	case 1:
+		if n < 16 {
+			return "", 0, 0, errors.New("invalid MP4: file is truncated")
+		}
+		size, header = int64(binary.BigEndian.Uint64(head[8:])), 16
+	}
+	if size < header || size > fileSize-offset {
+		return "", 0, 0, fmt.Errorf("invalid MP4: box %q at offset %d has a bad size", typ, offset)
+	}
+	return typ, header, size, nil
+}
+
+func (p *MP4Reader) readAt(offset, size int64) ([]byte, error) {
+	if size > mp4MaxBoxSize {
+		return nil, fmt.Errorf("invalid MP4: %d bytes at offset %d is too large to read", size, offset)
+	}
+	buf := make([]byte, size)
+	if n, err := p.r.ReadAt(buf, offset); n < len(buf) {
+		if err == nil || err == io.EOF {
+			err = errors.New("invalid MP4: file is truncated")
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// mp4Boxes calls fn with the type and body of each box in data, stopping at
+// the first malformed one
+func mp4Boxes(data []byte, fn func(typ string, body []byte)) {
+	for len(data) >= 8 {
+		size, header := uint64(binary.BigEndian.Uint32(data)), uint64(8)
+		typ := string(data[4:8])
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size, header = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return
+		}
+		fn(typ, data[header:size])
+		data = data[size:]
+	}
+}
+
+// mp4TimeHeader reads the timescale and duration of a movie or media
+// header box, whose layout depends on its version
+func mp4TimeHeader(body []byte) (uint32, uint64, bool) {
+	if len(body) >= 32 && body[0] == 1 {
+		duration := binary.BigEndian.Uint64(body[24:])
+		return binary.BigEndian.Uint32(body[20:]), duration, duration != math.MaxUint64
+	}
+	if len(body) >= 20 {
+		duration := binary.BigEndian.Uint32(body[16:])
+		return binary.BigEndian.Uint32(body[12:]), uint64(duration), duration != math.MaxUint32
+	}
+	return 0, 0, false
+}
+
+// mdhdLanguage reads the ISO 639-2 language packed into a media header
+func mdhdLanguage(body []byte) string {
+	offset := 20
+	if len(body) > 0 && body[0] == 1 {
+		offset = 32
+	}
+	if len(body) < offset+2 {
+		return ""
+	}
+	packed := binary.BigEndian.Uint16(body[offset:])
+	if packed == 0 || packed == 0x7FFF {
+		return ""
+	}
+	return string([]byte{byte(packed>>10&0x1F) + 0x60, byte(packed>>5&0x1F) + 0x60, byte(packed&0x1F) + 0x60})
+}
+
+// hdlrName reads the name of a handler, a C string or, in QuickTime files,
+// a counted string
+func hdlrName(data []byte) string {
+	if len(data) > 0 && data[0] > 0 && int(data[0]) == len(data)-1 {
+		data = data[1:]
+	}
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// mp4Entries returns the fixed-size entries of a full box that counts them
+func mp4Entries(body []byte, size int) [][]byte {
+	if len(body) < 8 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(body[4:]))
+	var entries [][]byte
+	for i := 0; i < count && 8+(i+1)*size <= len(body); i++ {
+		entries = append(entries, body[8+i*size:8+(i+1)*size])
+	}
+	return entries
+}
+
+// mp4Pairs returns the entries of a full box that lists pairs of numbers
+func mp4Pairs(body []byte) [][2]uint32 {
+	var pairs [][2]uint32
+	for _, entry := range mp4Entries(body, 8) {
+		pairs = append(pairs, [2]uint32{binary.BigEndian.Uint32(entry), binary.BigEndian.Uint32(entry[4:])})
+	}
+	return pairs
+}
+
+// mp4Duration converts a time in timescale units to a duration
+func mp4Duration(value uint64, timescale uint32) time.Duration {
+	if timescale == 0 {
+		return 0
+	}
+	seconds := value / uint64(timescale)
+	rest := value % uint64(timescale)
+	return time.Duration(seconds)*time.Second + time.Duration(rest)*time.Second/time.Duration(timescale)
+}
@@ -0,0 +1,305 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+var (
+	samiAttrRegex  = regexp.MustCompile(`([A-Za-z_:][-\w:.]*)\s*(?:=\s*("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+	samiClassRegex = regexp.MustCompile(`\.([\w-]+)\s*\{([^}]*)\}`)
+	samiPropRegex  = regexp.MustCompile(`(?i)(name|lang)\s*:\s*([^;}]+)`)
+)
+
+func ParseSAMI(reader io.Reader) ([]models.CaptionEntry, error) {
+	return collect(NewSAMIReader(reader))
+}
+
+// samiToken is a tag or the text between tags
+type samiToken struct {
+	// tag is the lower-case tag name, prefixed with "/" for an end tag, or
+	// "" for text
+	tag   string
+	attrs map[string]string
+	text  string
+	line  int
+}
+
+// samiCaption is the caption of one class on screen
+type samiCaption struct {
+	start time.Duration
+	line  int
+	text  strings.Builder
+	lines []string
+}
+
+// SAMIReader reads SAMI (.smi) captions one at a time. Each language class
+// declared in the STYLE block, such as .KRCC or .ENCC, is a track of its
+// own: a <P Class=...> caption is shown from its <SYNC Start=...> until the
+// next SYNC with a caption of the same class. The loose HTML of these
+// files is tolerated: end tags are optional and attributes may be
+// unquoted.
+type SAMIReader struct {
+	reader *bufio.Reader
+	line   int
+	eof    bool
+
+	tracks  []models.Track
+	inStyle bool
+	style   strings.Builder
+
+	// sync is the start of the current SYNC, or -1 when it has none
+	sync    time.Duration
+	maxSync time.Duration
+	// open holds the caption of each class on screen
+	open    map[string]*samiCaption
+	current *samiCaption
+
+	pending     []Cue
+	diagnostics []Diagnostic
+}
+
+// NewSAMIReader returns a streaming SAMI parser
+func NewSAMIReader(reader io.Reader) *SAMIReader {
+	return &SAMIReader{reader: bufio.NewReader(reader), line: 1, sync: -1, open: map[string]*samiCaption{}}
+}
+
+// Tracks returns the tracks declared in the STYLE block followed by any
+// other class used so far
+func (p *SAMIReader) Tracks() []models.Track {
+	return p.tracks
+}
+
+// describe reports the tracks found so far
+func (p *SAMIReader) describe(in *Input) {
+	in.Tracks = p.tracks
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *SAMIReader) Next() (Cue, error) {
+	for len(p.pending) == 0 {
+		if p.eof {
+			return Cue{}, io.EOF
+		}
+		token, err := p.token()
+		if err == io.EOF {
+			p.eof = true
+			p.closeAll()
+			continue
+		}
+		if err != nil {
+			return Cue{}, err
+		}
+		p.handle(token)
+	}
+
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+func (p *SAMIReader) handle(token samiToken) {
+	if p.inStyle {
+		if token.tag == "/style" {
+			p.inStyle = false
+			p.declareClasses(p.style.String())
+		} else if token.tag == "" || strings.HasPrefix(token.tag, "!--") {
+			p.style.WriteString(token.text)
+		}
+		return
+	}
+
+	switch token.tag {
+	case "":
+		if p.current != nil {
+			p.current.text.WriteString(html.UnescapeString(token.text))
+		}
+	case "style":
+		p.inStyle = true
+	case "sync":
+		p.endParagraph()
+		start, err := strconv.ParseInt(strings.TrimSpace(token.attrs["start"]), 10, 64)
+		if err != nil || start < 0 {
+			p.diagnose(token.line, fmt.Sprintf("SYNC start %q is not a number of milliseconds; its captions are ignored", TextPreview(token.attrs["start"])))
+			p.sync = -1
+			return
+		}
+		p.sync = time.Duration(start) * time.Millisecond
+		if p.sync > p.maxSync {
+			p.maxSync = p.sync
+		}
+	case "p":
+		p.endParagraph()
+		if p.sync < 0 {
+			return
+		}
+		class := p.trackID(token.attrs["class"])
+		if caption, ok := p.open[class]; ok && caption.start != p.sync {
+			p.finish(class, caption, p.sync)
+			delete(p.open, class)
+		}
+		if _, ok := p.open[class]; !ok {
+			p.open[class] = &samiCaption{start: p.sync, line: token.line}
+		}
+		p.current = p.open[class]
+	case "br":
+		p.breakLine()
+	case "/p":
+		p.endParagraph()
+	case "/body", "/sami":
+		p.endParagraph()
+		p.closeAll()
+	}
+}
+
+// token reads the next tag or run of text
+func (p *SAMIReader) token() (samiToken, error) {
+	line := p.line
+	text, err := p.reader.ReadString('<')
+	if text != "" && text != "<" {
+		if err == nil {
+			// Push the '<' back so the next call reads the tag
+			text = text[:len(text)-1]
+			p.reader.UnreadByte()
+		}
+		p.line += strings.Count(text, "\n")
+		return samiToken{text: text, line: line}, nil
+	}
+	if err != nil {
+		return samiToken{}, err
+	}
+
+	tag, err := p.reader.ReadString('>')
+	if strings.HasPrefix(tag, "!--") {
+		for err == nil && !strings.HasSuffix(tag, "-->") {
+			var more string
+			more, err = p.reader.ReadString('>')
+			tag += more
+		}
+	}
+	p.line += strings.Count(tag, "\n")
+	if err != nil && err != io.EOF {
+		return samiToken{}, err
+	}
+
+	if strings.HasPrefix(tag, "!--") {
+		// Comments hide the STYLE rules from old browsers
+		return samiToken{tag: "!--", text: strings.TrimSuffix(strings.TrimPrefix(tag, "!--"), "-->"), line: line}, nil
+	}
+	tag = strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/")
+	name := tag
+	if i := strings.IndexAny(tag, " \t\r\n"); i >= 0 {
+		name = tag[:i]
+	}
+	token := samiToken{tag: strings.ToLower(name), attrs: map[string]string{}, line: line}
+	for _, m := range samiAttrRegex.FindAllStringSubmatch(tag[len(name):], -1) {
+		token.attrs[strings.ToLower(m[1])] = strings.Trim(m[2], `"'`)
+	}
+	return token, nil
+}
+
+// declareClasses reads the language classes of the STYLE block
+func (p *SAMIReader) declareClasses(style string) {
+	for _, m := range samiClassRegex.FindAllStringSubmatch(style, -1) {
+		track := models.Track{ID: m[1]}
+		for _, prop := range samiPropRegex.FindAllStringSubmatch(m[2], -1) {
+			value := strings.TrimSpace(prop[2])
+			if strings.EqualFold(prop[1], "name") {
+				track.Name = value
+			} else {
+				track.Language = value
+			}
+		}
+		if track.Name != "" || track.Language != "" {
+			p.tracks = append(p.tracks, track)
+		}
+	}
+}
+
+// trackID returns the ID of the track of a class, matching declared
+// classes regardless of case. A class that was not declared becomes a
+// track of its own.
+func (p *SAMIReader) trackID(class string) string {
+	class = strings.TrimSpace(class)
+	for _, track := range p.tracks {
+		if strings.EqualFold(track.ID, class) {
+			return track.ID
+		}
+	}
+	p.tracks = append(p.tracks, models.Track{ID: class})
+	return class
+}
+
+func (p *SAMIReader) language(id string) string {
+	for _, track := range p.tracks {
+		if track.ID == id {
+			return track.Language
+		}
+	}
+	return ""
+}
+
+func (p *SAMIReader) breakLine() {
+	caption := p.current
+	if caption == nil {
+		return
+	}
+	if line := strings.Join(strings.Fields(caption.text.String()), " "); line != "" {
+		caption.lines = append(caption.lines, line)
+	}
+	caption.text.Reset()
+}
+
+func (p *SAMIReader) endParagraph() {
+	p.breakLine()
+	p.current = nil
+}
+
+// finish completes the caption of a class at the given time. Captions
+// without text, such as the &nbsp; that clears the screen, are dropped.
+func (p *SAMIReader) finish(class string, caption *samiCaption, end time.Duration) {
+	if len(caption.lines) == 0 {
+		return
+	}
+	p.pending = append(p.pending, Cue{
+		CaptionEntry: models.CaptionEntry{
+			StartTime: caption.start,
+			EndTime:   end,
+			Text:      strings.Join(caption.lines, " "),
+			Lines:     caption.lines,
+			Line:      caption.line,
+			Language:  p.language(class),
+			Track:     class,
+		},
+		Diagnostics: p.diagnostics,
+	})
+	p.diagnostics = nil
+}
+
+// closeAll ends every caption still on screen at the last SYNC
+func (p *SAMIReader) closeAll() {
+	p.endParagraph()
+	for _, track := range p.tracks {
+		caption, ok := p.open[track.ID]
+		if !ok {
+			continue
+		}
+		delete(p.open, track.ID)
+		if len(caption.lines) > 0 {
+			p.diagnose(caption.line, "caption is still displayed at the end of the file")
+		}
+		p.finish(track.ID, caption, p.maxSync)
+	}
+}
+
+func (p *SAMIReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
@@ -0,0 +1,104 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+var sbvTimeRegex = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})\.(\d{3})\s*,\s*(\d+):(\d{2}):(\d{2})\.(\d{3})$`)
+
+func ParseSBV(reader io.Reader) ([]models.CaptionEntry, error) {
+	return parseSBV(reader, DefaultMaxLineSize)
+}
+
+func parseSBV(reader io.Reader, maxLineSize int) ([]models.CaptionEntry, error) {
+	return collect(NewSBVReader(reader, maxLineSize))
+}
+
+// SBVReader reads YouTube SBV cues one at a time. Each cue is a
+// "0:00:01.000,0:00:03.000" timing line followed by its text, and cues are
+// separated by blank lines.
+type SBVReader struct {
+	scanner     *LineReader
+	diagnostics []Diagnostic
+}
+
+// NewSBVReader returns a streaming SBV parser
+func NewSBVReader(reader io.Reader, maxLineSize int) *SBVReader {
+	return &SBVReader{scanner: NewLineReader(reader, maxLineSize)}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *SBVReader) Next() (Cue, error) {
+	var entry models.CaptionEntry
+	var textLines []string
+	// skipping is set inside a block without a timing line
+	skipping := false
+
+	for p.scanner.Scan() {
+		lineNum := p.scanner.Line()
+		line := strings.TrimSpace(p.scanner.Text())
+
+		if line == "" {
+			if len(textLines) > 0 {
+				return p.emit(entry, textLines), nil
+			}
+			entry.Line, skipping = 0, false
+			continue
+		}
+		if skipping {
+			continue
+		}
+
+		if entry.Line == 0 {
+			m := sbvTimeRegex.FindStringSubmatch(line)
+			if m == nil {
+				p.diagnose(lineNum, fmt.Sprintf("expected a timing line, found %q; the block is ignored", TextPreview(line)))
+				skipping = true
+				continue
+			}
+			entry.Line = lineNum
+			entry.StartTime = sbvTime(m[1:5])
+			entry.EndTime = sbvTime(m[5:9])
+			continue
+		}
+		textLines = append(textLines, line)
+	}
+
+	// Handle last caption if file doesn't end with empty line
+	if len(textLines) > 0 {
+		return p.emit(entry, textLines), nil
+	}
+	if err := p.scanner.Err(); err != nil {
+		return Cue{}, err
+	}
+	return Cue{}, io.EOF
+}
+
+func (p *SBVReader) emit(entry models.CaptionEntry, textLines []string) Cue {
+	entry.Text = strings.Join(textLines, " ")
+	entry.Lines = textLines
+	cue := Cue{CaptionEntry: entry, Diagnostics: p.diagnostics}
+	p.diagnostics = nil
+	return cue
+}
+
+func (p *SBVReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
+
+// sbvTime converts hours, minutes, seconds and milliseconds to a duration
+func sbvTime(parts []string) time.Duration {
+	var values [4]int64
+	for i, part := range parts {
+		values[i], _ = strconv.ParseInt(part, 10, 64)
+	}
+	return time.Duration(values[0])*time.Hour + time.Duration(values[1])*time.Minute +
+		time.Duration(values[2])*time.Second + time.Duration(values[3])*time.Millisecond
+}
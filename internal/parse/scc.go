@@ -0,0 +1,472 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// sccLineRegex matches a timecode followed by hex byte pairs. A semicolon
+// or other non-colon separator before the frames marks drop-frame
+// timecode.
+var sccLineRegex = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})([:;.,])(\d{2})\s+(.*)$`)
+
+// CEA-608 caption memory size
+const (
+	sccRows    = 15
+	sccColumns = 32
+)
+
+// sccMode is the CEA-608 caption style selected by the last control code
+type sccMode int
+
+const (
+	sccPopOn sccMode = iota
+	sccRollUp
+	sccPaintOn
+)
+
+// sccScreen is a CEA-608 caption memory, indexed by row and column
+type sccScreen [sccRows][sccColumns]rune
+
+// render returns the non-blank rows of the screen from top to bottom
+func (s *sccScreen) render() []string {
+	var lines []string
+	for _, row := range s {
+		var b strings.Builder
+		for _, r := range row {
+			if r == 0 {
+				r = ' '
+			}
+			b.WriteRune(r)
+		}
+		if line := strings.Join(strings.Fields(b.String()), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func ParseSCC(reader io.Reader) ([]models.CaptionEntry, error) {
+	return parseSCC(reader, DefaultMaxLineSize)
+}
+
+func parseSCC(reader io.Reader, maxLineSize int) ([]models.CaptionEntry, error) {
+	return collect(NewSCCReader(reader, maxLineSize))
+}
+
+// SCCReader decodes the CEA-608 captions of channel CC1 in a Scenarist SCC
+// file into display intervals: a cue starts when a caption appears on
+// screen and ends when the screen changes or is erased. Pop-on, roll-up
+// and paint-on captions are supported.
+type SCCReader struct {
+	scanner *LineReader
+
+	mode        sccMode
+	rollRows    int
+	displayed   sccScreen
+	undisplayed sccScreen
+	row, column int
+
+	// channel is the data channel selected by the last control code; only
+	// channel 1 is decoded
+	channel int
+	// lastControl is the previous byte pair when it was a control code, so
+	// that the redundant copy sent for reliability is skipped
+	lastControl [2]byte
+
+	// nextFrame is the frame at which the next byte pair is decoded
+	nextFrame int64
+	lineNum   int
+
+	// dirty is set when the displayed memory has changed since dirtyAt
+	dirty   bool
+	dirtyAt time.Duration
+	// shown is the caption on screen and when and where it appeared
+	shown     []string
+	shownAt   time.Duration
+	shownLine int
+
+	pending     []Cue
+	diagnostics []Diagnostic
+}
+
+// NewSCCReader returns a streaming SCC decoder
+func NewSCCReader(reader io.Reader, maxLineSize int) *SCCReader {
+	return &SCCReader{scanner: NewLineReader(reader, maxLineSize), rollRows: 2, row: sccRows - 1, channel: 1}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *SCCReader) Next() (Cue, error) {
+	for len(p.pending) == 0 {
+		if !p.scanner.Scan() {
+			if err := p.scanner.Err(); err != nil {
+				return Cue{}, err
+			}
+			// A caption still on screen ends with the last byte pair
+			if len(p.shown) > 0 {
+				p.diagnose(p.lineNum, "caption is still displayed at the end of the file")
+				p.show(nil, FrameRate2997.Duration(p.nextFrame))
+			}
+			if len(p.pending) == 0 {
+				return Cue{}, io.EOF
+			}
+			break
+		}
+		p.decodeLine(p.scanner.Line(), strings.TrimSpace(p.scanner.Text()))
+	}
+
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+// decodeLine decodes the byte pairs of one SCC line
+func (p *SCCReader) decodeLine(lineNum int, line string) {
+	if line == "" || (lineNum == 1 && strings.HasPrefix(line, "Scenarist_SCC")) {
+		return
+	}
+	m := sccLineRegex.FindStringSubmatch(line)
+	if m == nil {
+		p.diagnose(lineNum, fmt.Sprintf("line %q is not a timecode followed by byte pairs and is ignored", TextPreview(line)))
+		return
+	}
+
+	frame := sccFrame(m[1], m[2], m[3], m[5], m[4] != ":")
+	// Byte pairs are sent one per frame, so a line may start late when the
+	// previous one had more pairs than frames before this timecode
+	if frame > p.nextFrame {
+		p.nextFrame = frame
+	}
+	p.lineNum = lineNum
+
+	for _, word := range strings.Fields(m[6]) {
+		value, err := strconv.ParseUint(word, 16, 16)
+		if err != nil || len(word) != 4 {
+			p.diagnose(lineNum, fmt.Sprintf("%q is not a hex byte pair and is ignored", TextPreview(word)))
+			p.nextFrame++
+			continue
+		}
+		// Strip the odd parity bit of each byte
+		p.decodePair(byte(value>>8)&0x7f, byte(value)&0x7f, FrameRate2997.Duration(p.nextFrame))
+		p.nextFrame++
+	}
+	p.commit()
+}
+
+// sccFrame converts an SCC timecode to a frame count at 29.97 frames per
+// second. Drop-frame timecode skips frame numbers 0 and 1 at the start of
+// every minute except each tenth.
+func sccFrame(hh, mm, ss, ff string, dropFrame bool) int64 {
+	hours, _ := strconv.ParseInt(hh, 10, 64)
+	minutes, _ := strconv.ParseInt(mm, 10, 64)
+	seconds, _ := strconv.ParseInt(ss, 10, 64)
+	frames, _ := strconv.ParseInt(ff, 10, 64)
+
+	total := ((hours*60+minutes)*60+seconds)*30 + frames
+	if dropFrame {
+		totalMinutes := hours*60 + minutes
+		total -= 2 * (totalMinutes - totalMinutes/10)
+	}
+	return total
+}
+
+// decodePair applies one CEA-608 byte pair decoded at the given time
+func (p *SCCReader) decodePair(b1, b2 byte, at time.Duration) {
+	if b1 == 0 && b2 == 0 {
+		p.lastControl = [2]byte{}
+		return
+	}
+
+	if b1 >= 0x10 && b1 <= 0x1f {
+		pair := [2]byte{b1, b2}
+		if pair == p.lastControl {
+			p.lastControl = [2]byte{}
+			return
+		}
+		p.lastControl = pair
+		p.control(b1, b2, at)
+		return
+	}
+	p.lastControl = [2]byte{}
+
+	if p.channel != 1 {
+		return
+	}
+	for _, b := range []byte{b1, b2} {
+		if b >= 0x20 {
+			p.write(sccStandardChar(b), at)
+		}
+	}
+}
+
+// control applies a CEA-608 control code
+func (p *SCCReader) control(b1, b2 byte, at time.Duration) {
+	// Channel 2 codes differ from channel 1 codes in one bit
+	channel := 1
+	if b1&0x08 != 0 {
+		channel = 2
+	}
+	code := b1 &^ 0x08
+
+	switch {
+	case (code == 0x14 || code == 0x15) && b2 >= 0x20 && b2 <= 0x2f:
+		// 0x15 is the field 2 form used for channels 3 and 4
+		if code == 0x15 {
+			p.channel = channel + 2
+			return
+		}
+		p.channel = channel
+		p.command(b2, at)
+		return
+	case b2 >= 0x40 && b2 <= 0x7f:
+		p.channel = channel
+		if channel == 1 {
+			p.preamble(code, b2)
+		}
+		return
+	}
+
+	p.channel = channel
+	if channel != 1 {
+		return
+	}
+	switch {
+	case code == 0x11 && b2 >= 0x20 && b2 <= 0x2f:
+		// Mid-row style codes are shown as a space
+		p.write(' ', at)
+	case code == 0x11 && b2 >= 0x30 && b2 <= 0x3f:
+		p.write(sccSpecialChars[b2-0x30], at)
+	case (code == 0x12 || code == 0x13) && b2 >= 0x20 && b2 <= 0x3f:
+		// An extended character replaces the standard character sent
+		// before it as a fallback
+		p.backspace(at)
+		if code == 0x12 {
+			p.write(sccExtendedChars12[b2-0x20], at)
+		} else {
+			p.write(sccExtendedChars13[b2-0x20], at)
+		}
+	case code == 0x17 && b2 >= 0x21 && b2 <= 0x23:
+		// Tab offsets move the cursor right
+		p.column += int(b2 - 0x20)
+		if p.column >= sccColumns {
+			p.column = sccColumns - 1
+		}
+	}
+}
+
+// command applies a miscellaneous control code of channel 1 or 2
+func (p *SCCReader) command(b2 byte, at time.Duration) {
+	if p.channel != 1 {
+		return
+	}
+	switch b2 {
+	case 0x20: // RCL: resume caption loading
+		p.mode = sccPopOn
+	case 0x25, 0x26, 0x27: // RU2, RU3, RU4: roll-up captions
+		if p.mode != sccRollUp {
+			p.erase(&p.displayed, at)
+			p.undisplayed = sccScreen{}
+			p.row, p.column = sccRows-1, 0
+		}
+		p.mode = sccRollUp
+		p.rollRows = int(b2-0x25) + 2
+	case 0x29: // RDC: resume direct captioning
+		p.mode = sccPaintOn
+	case 0x21: // BS: backspace
+		p.backspace(at)
+	case 0x24: // DER: delete to end of row
+		target := p.target()
+		for c := p.column; c < sccColumns; c++ {
+			target[p.row][c] = 0
+		}
+		p.touch(target, at)
+	case 0x2c: // EDM: erase displayed memory
+		p.erase(&p.displayed, at)
+	case 0x2e: // ENM: erase non-displayed memory
+		p.undisplayed = sccScreen{}
+	case 0x2f: // EOC: end of caption, flipping the memories
+		p.flush()
+		p.displayed, p.undisplayed = p.undisplayed, p.displayed
+		p.touch(&p.displayed, at)
+		p.mode = sccPopOn
+	case 0x2d: // CR: carriage return
+		if p.mode == sccRollUp {
+			top := p.row - p.rollRows + 1
+			if top < 0 {
+				top = 0
+			}
+			for r := 0; r < sccRows; r++ {
+				switch {
+				case r >= top && r < p.row:
+					p.displayed[r] = p.displayed[r+1]
+				case r < top || r > p.row:
+					p.displayed[r] = [sccColumns]rune{}
+				}
+			}
+			p.displayed[p.row] = [sccColumns]rune{}
+			p.touch(&p.displayed, at)
+		}
+		p.column = 0
+	}
+}
+
+// sccPreambleRows maps the first byte of a preamble address code to the
+// 0-based rows it selects, for second bytes below and from 0x60
+var sccPreambleRows = map[byte][2]int{
+	0x11: {0, 1}, 0x12: {2, 3}, 0x15: {4, 5}, 0x16: {6, 7}, 0x17: {8, 9},
+	0x10: {10, 10}, 0x13: {11, 12}, 0x14: {13, 14},
+}
+
+// preamble applies a preamble address code, which moves the cursor to a
+// row and indent
+func (p *SCCReader) preamble(code, b2 byte) {
+	rows, ok := sccPreambleRows[code]
+	if !ok {
+		return
+	}
+	row := rows[0]
+	if b2 >= 0x60 {
+		row = rows[1]
+	}
+
+	if p.mode == sccRollUp && row != p.row {
+		// Roll-up captions move with their base row
+		moved := sccScreen{}
+		for r := 0; r < p.rollRows; r++ {
+			from, to := p.row-r, row-r
+			if from >= 0 && to >= 0 {
+				moved[to] = p.displayed[from]
+			}
+		}
+		p.displayed = moved
+	}
+	p.row, p.column = row, 0
+	if b2&0x10 != 0 {
+		p.column = int((b2&0x0e)>>1) * 4
+	}
+}
+
+// target returns the memory that characters are written to in the
+// current mode
+func (p *SCCReader) target() *sccScreen {
+	if p.mode == sccPopOn {
+		return &p.undisplayed
+	}
+	return &p.displayed
+}
+
+func (p *SCCReader) write(r rune, at time.Duration) {
+	target := p.target()
+	target[p.row][p.column] = r
+	if p.column < sccColumns-1 {
+		p.column++
+	}
+	p.touch(target, at)
+}
+
+func (p *SCCReader) backspace(at time.Duration) {
+	if p.column == 0 {
+		return
+	}
+	p.column--
+	target := p.target()
+	target[p.row][p.column] = 0
+	p.touch(target, at)
+}
+
+func (p *SCCReader) erase(screen *sccScreen, at time.Duration) {
+	p.flush()
+	*screen = sccScreen{}
+	p.touch(screen, at)
+}
+
+// touch records a change made at the given time if screen is on display
+func (p *SCCReader) touch(screen *sccScreen, at time.Duration) {
+	if screen == &p.displayed && !p.dirty {
+		p.dirty, p.dirtyAt = true, at
+	}
+}
+
+// flush commits a pending display change before another one starts
+func (p *SCCReader) flush() {
+	if p.dirty {
+		p.commit()
+	}
+}
+
+// commit turns the display changes made so far into a cue boundary
+func (p *SCCReader) commit() {
+	if !p.dirty {
+		return
+	}
+	p.dirty = false
+	p.show(p.displayed.render(), p.dirtyAt)
+}
+
+// show replaces the caption on screen at the given time, completing the
+// cue of the caption it replaces
+func (p *SCCReader) show(lines []string, at time.Duration) {
+	if strings.Join(lines, "\n") == strings.Join(p.shown, "\n") {
+		return
+	}
+	if len(p.shown) > 0 {
+		p.pending = append(p.pending, Cue{
+			CaptionEntry: models.CaptionEntry{
+				StartTime: p.shownAt,
+				EndTime:   at,
+				Text:      strings.Join(p.shown, " "),
+				Lines:     p.shown,
+				Line:      p.shownLine,
+			},
+			Diagnostics: p.diagnostics,
+		})
+		p.diagnostics = nil
+	}
+	p.shown, p.shownAt, p.shownLine = lines, at, p.lineNum
+}
+
+func (p *SCCReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
+
+// sccStandardChar maps a CEA-608 basic character to Unicode. It is ASCII
+// apart from a few accented letters and symbols.
+func sccStandardChar(b byte) rune {
+	switch b {
+	case 0x2a:
+		return 'á'
+	case 0x5c:
+		return 'é'
+	case 0x5e:
+		return 'í'
+	case 0x5f:
+		return 'ó'
+	case 0x60:
+		return 'ú'
+	case 0x7b:
+		return 'ç'
+	case 0x7c:
+		return '÷'
+	case 0x7d:
+		return 'Ñ'
+	case 0x7e:
+		return 'ñ'
+	case 0x7f:
+		return '█'
+	}
+	return rune(b)
+}
+
+// CEA-608 special and extended character sets. The transparent space is
+// read as a space.
+var (
+	sccSpecialChars    = []rune("®°½¿™¢£♪à èâêîôû")
+	sccExtendedChars12 = []rune("ÁÉÓÚÜü‘¡*'—©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»")
+	sccExtendedChars13 = []rune("ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤│ÅåØø┌┐└┘")
+)
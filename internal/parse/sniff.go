@@ -0,0 +1,147 @@
+package parse
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// FormatNames are the display names of caption formats
+var FormatNames = map[string]string{
+	FormatSRT:        "SRT",
+	FormatWebVTT:     "WebVTT",
+	FormatTTML:       "TTML",
+	FormatSCC:        "SCC",
+	FormatASS:        "ASS/SSA",
+	FormatSBV:        "SBV",
+	FormatTranscript: "transcript",
+	FormatSAMI:       "SAMI",
+	FormatSTL:        "EBU STL",
+	FormatMicroDVD:   "MicroDVD",
+	FormatASR:        "ASR JSON",
+	FormatHLS:        "HLS playlist",
+	FormatMatroska:   "Matroska",
+	FormatMP4:        "MP4",
+}
+
+// formatExtensions lists the file extensions used for each format
+var formatExtensions = map[string][]string{
+	FormatSRT:      {"srt"},
+	FormatWebVTT:   {"vtt", "webvtt"},
+	FormatTTML:     {"ttml", "dfxp", "xml"},
+	FormatSCC:      {"scc"},
+	FormatASS:      {"ass", "ssa"},
+	FormatSBV:      {"sbv"},
+	FormatSAMI:     {"smi", "sami"},
+	FormatSTL:      {"stl"},
+	FormatMicroDVD: {"sub"},
+	FormatASR:      {"json"},
+	FormatHLS:      {"m3u8"},
+	FormatMatroska: {"mkv", "mks", "mka", "webm"},
+	FormatMP4:      {"mp4", "m4v", "mov"},
+}
+
+// sniffSize is how much of the input is inspected to detect its format
+const sniffSize = 4096
+
+var (
+	srtHeadRegex        = regexp.MustCompile(`^\d+[ \t]*\r?\n[ \t]*\d{1,2}:\d{2}:\d{2},\d{3}[ \t]+-->[ \t]+\d{1,2}:\d{2}:\d{2},\d{3}`)
+	sbvHeadRegex        = regexp.MustCompile(`^\d+:\d{2}:\d{2}\.\d{3}[ \t]*,[ \t]*\d+:\d{2}:\d{2}\.\d{3}[ \t]*(\r?\n|$)`)
+	transcriptHeadRegex = regexp.MustCompile(`^\[(\d+:)?\d{1,2}:\d{2}(\.\d{1,3})?\]`)
+	microDVDHeadRegex   = regexp.MustCompile(`^\{\d+\}\{\d*\}`)
+	asrHeadRegex        = regexp.MustCompile(`^(\{\s*"|\[\s*[\{\]])`)
+	xmlRootRegex        = regexp.MustCompile(`<([A-Za-z_][\w.-]*:)?([A-Za-z_][\w.-]*)`)
+)
+
+// mp4HeadBoxes are the boxes an MP4 or QuickTime file starts with
+var mp4HeadBoxes = map[string]bool{"ftyp": true, "styp": true, "moov": true}
+
+// sniffers are tried in order on the start of the content, after any byte
+// order mark and leading white space
+var sniffers = []struct {
+	format string
+	match  func(head []byte) bool
+}{
+	{FormatSTL, func(head []byte) bool { return len(head) >= 11 && stlDFCRegex.Match(head[3:11]) }},
+	{FormatMatroska, func(head []byte) bool { return bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}) }},
+	{FormatMP4, func(head []byte) bool { return len(head) >= 8 && mp4HeadBoxes[string(head[4:8])] }},
+	{FormatWebVTT, func(head []byte) bool { return hasSignature(head, "WEBVTT") }},
+	{FormatHLS, func(head []byte) bool { return hasSignature(head, "#EXTM3U") }},
+	{FormatSCC, func(head []byte) bool { return bytes.HasPrefix(head, []byte("Scenarist_SCC")) }},
+	{FormatASS, func(head []byte) bool { return bytes.HasPrefix(bytes.ToLower(head), []byte("[script info]")) }},
+	{FormatTTML, func(head []byte) bool { return xmlRoot(head) == "tt" }},
+	{FormatSAMI, func(head []byte) bool { return xmlRoot(bytes.ToLower(head)) == "sami" }},
+	{FormatSRT, func(head []byte) bool { return srtHeadRegex.Match(head) }},
+	{FormatSBV, func(head []byte) bool { return sbvHeadRegex.Match(head) }},
+	{FormatTranscript, func(head []byte) bool { return transcriptHeadRegex.Match(head) }},
+	{FormatMicroDVD, func(head []byte) bool { return microDVDHeadRegex.Match(head) }},
+	{FormatASR, func(head []byte) bool { return asrHeadRegex.Match(head) }},
+}
+
+// IsBinaryFormat reports whether format is read as raw bytes rather than
+// as text converted to UTF-8
+func IsBinaryFormat(format string) bool {
+	return format == FormatSTL || IsContainerFormat(format)
+}
+
+// IsContainerFormat reports whether format is a media container whose
+// subtitle tracks are extracted
+func IsContainerFormat(format string) bool {
+	return format == FormatMatroska || format == FormatMP4
+}
+
+// Sniff returns the caption format of content judging by its first bytes,
+// or "" when it is not recognised
+func Sniff(head []byte) string {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	for _, sniffer := range sniffers {
+		if sniffer.match(head) {
+			return sniffer.format
+		}
+	}
+	return ""
+}
+
+// hasSignature reports whether head starts with signature followed by the
+// end of the line or white space
+func hasSignature(head []byte, signature string) bool {
+	if !bytes.HasPrefix(head, []byte(signature)) {
+		return false
+	}
+	rest := head[len(signature):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n'
+}
+
+// xmlRoot returns the local name of the first element of an XML document,
+// skipping the declaration, comments and doctype
+func xmlRoot(head []byte) string {
+	for len(head) > 0 && head[0] == '<' {
+		switch {
+		case bytes.HasPrefix(head, []byte("<?")):
+			end := bytes.Index(head, []byte("?>"))
+			if end < 0 {
+				return ""
+			}
+			head = head[end+2:]
+		case bytes.HasPrefix(head, []byte("<!--")):
+			end := bytes.Index(head, []byte("-->"))
+			if end < 0 {
+				return ""
+			}
+			head = head[end+3:]
+		case bytes.HasPrefix(head, []byte("<!")):
+			end := bytes.IndexByte(head, '>')
+			if end < 0 {
+				return ""
+			}
+			head = head[end+1:]
+		default:
+			if m := xmlRootRegex.FindSubmatch(head); m != nil {
+				return string(m[2])
+			}
+			return ""
+		}
+		head = bytes.TrimLeft(head, " \t\r\n")
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// StdinPath is the input name that reads captions from standard input
+const StdinPath = "-"
+
+// Defaults for remote and piped input
+const (
+	DefaultMaxInputSize = 50 << 20
+	DefaultFetchTimeout = 30 * time.Second
+)
+
+// Options control how caption input is read
+type Options struct {
+	// Format forces the caption format instead of detecting it
+	Format string
+	// MaxInputSize caps the bytes read from stdin or a URL. Zero uses
+	// DefaultMaxInputSize.
+	MaxInputSize int64
+	// FetchTimeout bounds the whole download of a URL, including the body.
+	// Zero uses DefaultFetchTimeout.
+	FetchTimeout time.Duration
+	// MaxLineSize is the longest line accepted, in bytes. Zero uses
+	// DefaultMaxLineSize.
+	MaxLineSize int
+	// FrameRate converts the frames of frame-based formats such as
+	// MicroDVD to time. Zero uses the rate the input declares.
+	FrameRate FrameRate
+	// ASRMapping locates the timed items of an ASR transcript. Nil
+	// recognises the Whisper, AWS Transcribe and generic shapes.
+	ASRMapping *ASRMapping
+	// Track is the ID or language of the only caption track to read from
+	// an input that holds several, such as a container file
+	Track string
+	// Stdin replaces os.Stdin, mainly for tests
+	Stdin io.Reader
+}
+
+// IsURL reports whether input is an http or https URL
+func IsURL(input string) bool {
+	lower := strings.ToLower(input)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// Open returns a reader for a local file, stdin ("-") or an http(s) URL.
+// Stdin and URL input is limited to opts.MaxInputSize bytes.
+func Open(input string, opts Options) (io.ReadCloser, error) {
+	maxSize := opts.MaxInputSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxInputSize
+	}
+
+	switch {
+	case input == StdinPath:
+		stdin := opts.Stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		return &limitedReader{r: io.NopCloser(stdin), remaining: maxSize, limit: maxSize, name: "stdin"}, nil
+	case IsURL(input):
+		return fetch(input, maxSize, opts.FetchTimeout)
+	}
+	return os.Open(input)
+}
+
+func fetch(rawURL string, maxSize int64, timeout time.Duration) (io.ReadCloser, error) {
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: server returned %s", DisplayName(rawURL), resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %d bytes exceeds the %d byte input limit", DisplayName(rawURL), resp.ContentLength, maxSize)
+	}
+	return &limitedReader{r: resp.Body, remaining: maxSize, limit: maxSize, name: DisplayName(rawURL)}, nil
+}
+
+// DisplayName returns input as it should appear in reports and errors. The
+// query string of a URL, which holds the signature of presigned URLs, is
+// dropped.
+func DisplayName(input string) string {
+	if !IsURL(input) {
+		return input
+	}
+	if i := strings.IndexAny(input, "?#"); i >= 0 {
+		return input[:i]
+	}
+	return input
+}
+
+// limitedReader fails once more than limit bytes have been read, rather
+// than silently truncating the input like io.LimitReader
+type limitedReader struct {
+	r         io.ReadCloser
+	remaining int64
+	limit     int64
+	name      string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.tooLarge()
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), l.tooLarge()
+	}
+	return n, err
+}
+
+func (l *limitedReader) tooLarge() error {
+	return fmt.Errorf("reading %s: input exceeds the %d byte limit", l.name, l.limit)
+}
+
+func (l *limitedReader) Close() error {
+	return l.r.Close()
+}
@@ -1,7 +1,6 @@
 package parse
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"regexp"
@@ -12,24 +11,47 @@ import (
 	"github.com/theCompanyDream/srt-test/internal/models"
 )
 
+var (
+	srtTimeRegex     = regexp.MustCompile(`(\d{2}:\d{2}:\d{2},\d{3})\s+-->\s+(\d{2}:\d{2}:\d{2},\d{3})`)
+	srtSequenceRegex = regexp.MustCompile(`^\d+$`)
+)
+
 func ParseSRT(reader io.Reader) ([]models.CaptionEntry, error) {
-	scanner := bufio.NewScanner(reader)
-	var captions []models.CaptionEntry
-	var currentEntry models.CaptionEntry
+	return parseSRT(reader, DefaultMaxLineSize)
+}
+
+func parseSRT(reader io.Reader, maxLineSize int) ([]models.CaptionEntry, error) {
+	return collect(NewSRTReader(reader, maxLineSize))
+}
+
+// SRTReader reads SRT cues one at a time
+type SRTReader struct {
+	scanner      *LineReader
+	currentEntry models.CaptionEntry
+	// timed is set once the current block has a timing line
+	timed       bool
+	diagnostics []Diagnostic
+}
+
+// NewSRTReader returns a streaming SRT parser
+func NewSRTReader(reader io.Reader, maxLineSize int) *SRTReader {
+	return &SRTReader{scanner: NewLineReader(reader, maxLineSize)}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *SRTReader) Next() (Cue, error) {
 	var textLines []string
+	textStart := 0
 	expectingSequence := true
 
-	timeRegex := regexp.MustCompile(`(\d{2}:\d{2}:\d{2},\d{3})\s+-->\s+(\d{2}:\d{2}:\d{2},\d{3})`)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	for p.scanner.Scan() {
+		lineNum := p.scanner.Line()
+		line := strings.TrimSpace(p.scanner.Text())
 
 		// Empty line indicates end of caption block
 		if line == "" {
 			if len(textLines) > 0 {
-				currentEntry.Text = strings.Join(textLines, " ")
-				captions = append(captions, currentEntry)
-				textLines = nil
+				return p.emit(textLines, textStart), nil
 			}
 			expectingSequence = true
 			continue
@@ -38,33 +60,63 @@ func ParseSRT(reader io.Reader) ([]models.CaptionEntry, error) {
 		// Skip sequence number
 		if expectingSequence {
 			expectingSequence = false
+			if !srtSequenceRegex.MatchString(line) {
+				p.diagnose(lineNum, fmt.Sprintf("expected a sequence number, found %q", TextPreview(line)))
+			}
 			continue
 		}
 
 		// Check if line contains timing
-		if matches := timeRegex.FindStringSubmatch(line); len(matches) == 3 {
+		if matches := srtTimeRegex.FindStringSubmatch(line); len(matches) == 3 {
 			var err error
-			currentEntry.StartTime, err = ParseSRTTime(matches[1])
+			p.currentEntry.Line = lineNum
+			p.currentEntry.StartTime, err = ParseSRTTime(matches[1])
 			if err != nil {
-				return nil, fmt.Errorf("error parsing start time: %v", err)
+				return Cue{}, fmt.Errorf("error parsing start time: %v", err)
 			}
-			currentEntry.EndTime, err = ParseSRTTime(matches[2])
+			p.currentEntry.EndTime, err = ParseSRTTime(matches[2])
 			if err != nil {
-				return nil, fmt.Errorf("error parsing end time: %v", err)
+				return Cue{}, fmt.Errorf("error parsing end time: %v", err)
 			}
+			p.timed = true
 		} else {
 			// This is text content
+			if strings.Contains(line, "-->") {
+				p.diagnose(lineNum, fmt.Sprintf("timing line %q is not recognised and is read as text", TextPreview(line)))
+			}
+			if len(textLines) == 0 {
+				textStart = lineNum
+			}
 			textLines = append(textLines, line)
 		}
 	}
 
 	// Handle last caption if file doesn't end with empty line
 	if len(textLines) > 0 {
-		currentEntry.Text = strings.Join(textLines, " ")
-		captions = append(captions, currentEntry)
+		return p.emit(textLines, textStart), nil
+	}
+	if err := p.scanner.Err(); err != nil {
+		return Cue{}, err
 	}
+	return Cue{}, io.EOF
+}
+
+func (p *SRTReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
 
-	return captions, scanner.Err()
+// emit completes the cue of the current block. A block without a timing
+// line keeps the timing of the previous cue.
+func (p *SRTReader) emit(textLines []string, textStart int) Cue {
+	if !p.timed {
+		p.diagnose(textStart, "cue has no timing line")
+	}
+	p.currentEntry.Text = strings.Join(textLines, " ")
+	p.currentEntry.Lines = textLines
+	cue := Cue{CaptionEntry: p.currentEntry, Diagnostics: p.diagnostics}
+	p.timed = false
+	p.diagnostics = nil
+	return cue
 }
 
 func ParseSRTTime(timeStr string) (time.Duration, error) {
@@ -0,0 +1,302 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// Sizes of the blocks of an EBU STL file
+const (
+	stlGSISize = 1024
+	stlTTISize = 128
+)
+
+// Extension block numbers of TTI blocks with a special meaning
+const (
+	stlLastBlock = 0xFF
+	stlUserData  = 0xFE
+)
+
+// Control codes of the text field
+const (
+	stlNewline = 0x8A
+	stlUnused  = 0x8F
+)
+
+var stlDFCRegex = regexp.MustCompile(`^STL(\d\d)\.01$`)
+
+// stlFrameRates maps the rate of the disk format code to a frame rate.
+// Tech 3264 defines STL25.01 and STL30.01; the others are written by
+// common subtitling software.
+var stlFrameRates = map[string]FrameRate{
+	"23": FrameRate23976,
+	"24": FrameRate24,
+	"25": FrameRate25,
+	"29": FrameRate2997,
+	"30": FrameRate30,
+	"50": {Num: 50, Den: 1},
+	"60": {Num: 60, Den: 1},
+}
+
+// stlCodeTables names the character code tables of the CCT field
+var stlCodeTables = map[string]string{
+	"00": "ISO 6937",
+	"01": "ISO-8859-5",
+	"02": "ISO-8859-6",
+	"03": "ISO-8859-7",
+	"04": "ISO-8859-8",
+}
+
+// stlLanguages maps the language codes of Tech 3264 Appendix 3 to ISO
+// 639-1 codes. Code 00 means the language is unknown.
+var stlLanguages = map[string]string{
+	"01": "sq", "02": "br", "03": "ca", "04": "hr", "05": "cy", "06": "cs", "07": "da", "08": "de",
+	"09": "en", "0A": "es", "0B": "eo", "0C": "et", "0D": "eu", "0E": "fo", "0F": "fr", "10": "fy",
+	"11": "ga", "12": "gd", "13": "gl", "14": "is", "15": "it", "16": "se", "17": "la", "18": "lv",
+	"19": "lb", "1A": "lt", "1B": "hu", "1C": "mt", "1D": "nl", "1E": "no", "1F": "oc", "20": "pl",
+	"21": "pt", "22": "ro", "23": "rm", "24": "sr", "25": "sk", "26": "sl", "27": "fi", "28": "sv",
+	"29": "tr", "2A": "nl", "2B": "wa",
+	"45": "zu", "46": "vi", "47": "uz", "48": "ur", "49": "uk", "4A": "th", "4B": "te", "4C": "tt",
+	"4D": "ta", "4E": "tg", "4F": "sw", "51": "so", "52": "si", "53": "sn", "54": "sh", "56": "ru",
+	"57": "qu", "58": "ps", "59": "pa", "5A": "fa", "5C": "or", "5D": "ne", "5E": "nd", "5F": "mr",
+	"60": "mo", "61": "ms", "62": "mg", "63": "mk", "64": "lo", "65": "ko", "66": "km", "67": "kk",
+	"68": "kn", "69": "ja", "6A": "id", "6B": "hi", "6C": "he", "6D": "ha", "6E": "gn", "6F": "gu",
+	"70": "el", "71": "ka", "72": "ff", "74": "cv", "75": "zh", "76": "my", "77": "bg", "78": "bn",
+	"79": "be", "7A": "bm", "7B": "az", "7C": "as", "7D": "hy", "7E": "ar", "7F": "am",
+}
+
+func ParseSTL(reader io.Reader) ([]models.CaptionEntry, error) {
+	return collect(NewSTLReader(reader))
+}
+
+// stlSubtitle is a subtitle whose extension blocks are still being read
+type stlSubtitle struct {
+	number int
+	start  time.Duration
+	end    time.Duration
+	text   []byte
+}
+
+// STLReader reads EBU Tech 3264 (.stl) binary subtitles one at a time. The
+// General Subtitle Information block gives the frame rate, the character
+// code table and the language; each Text and Timing Information block
+// holds a subtitle, or part of one when its text continues in extension
+// blocks.
+type STLReader struct {
+	reader io.Reader
+	header bool
+	eof    bool
+
+	frameRate FrameRate
+	codeTable string
+	language  string
+	// programmeStart is the start-of-programme timecode, subtracted from
+	// the timecodes of the subtitles when they start after it
+	programmeStart time.Duration
+	offset         time.Duration
+	timed          bool
+
+	current     *stlSubtitle
+	pending     []Cue
+	diagnostics []Diagnostic
+}
+
+// NewSTLReader returns a streaming EBU STL parser
+func NewSTLReader(reader io.Reader) *STLReader {
+	return &STLReader{reader: reader}
+}
+
+// describe reports the language and code table declared by the header
+func (p *STLReader) describe(in *Input) {
+	in.Language = p.language
+	if name, ok := stlCodeTables[p.codeTable]; ok {
+		in.Encoding = name
+	}
+}
+
+// Next returns the next subtitle, or io.EOF after the last one
+func (p *STLReader) Next() (Cue, error) {
+	if !p.header {
+		if err := p.readHeader(); err != nil {
+			return Cue{}, err
+		}
+	}
+
+	block := make([]byte, stlTTISize)
+	for len(p.pending) == 0 {
+		if p.eof {
+			return Cue{}, io.EOF
+		}
+		n, err := io.ReadFull(p.reader, block)
+		switch {
+		case err == nil:
+			p.block(block)
+			continue
+		case err == io.ErrUnexpectedEOF:
+			p.diagnose(fmt.Sprintf("the last TTI block is %d bytes long, expected %d, and is ignored", n, stlTTISize))
+		case err != io.EOF:
+			return Cue{}, err
+		}
+		p.eof = true
+		if p.current != nil {
+			p.diagnose(fmt.Sprintf("subtitle %d has no last extension block", p.current.number))
+			p.finish()
+		}
+	}
+
+	cue := p.pending[0]
+	p.pending = p.pending[1:]
+	return cue, nil
+}
+
+// readHeader reads the General Subtitle Information block
+func (p *STLReader) readHeader() error {
+	p.header = true
+	gsi := make([]byte, stlGSISize)
+	if n, err := io.ReadFull(p.reader, gsi); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("invalid EBU STL: the GSI block is %d bytes long, expected %d", n, stlGSISize)
+		}
+		return err
+	}
+
+	dfc := stlDFCRegex.FindStringSubmatch(string(gsi[3:11]))
+	if dfc == nil {
+		return fmt.Errorf("invalid EBU STL: disk format code %q is not recognised", TextPreview(string(gsi[3:11])))
+	}
+	p.frameRate = stlFrameRates[dfc[1]]
+	if !p.frameRate.Valid() {
+		p.frameRate = FrameRate25
+		p.diagnose(fmt.Sprintf("disk format code %q has an unknown frame rate; 25 frames per second is assumed", string(gsi[3:11])))
+	}
+
+	p.codeTable = string(gsi[12:14])
+	if _, ok := stlCodeTables[p.codeTable]; !ok {
+		p.diagnose(fmt.Sprintf("character code table %q is not recognised; the Latin table is assumed", TextPreview(p.codeTable)))
+		p.codeTable = "00"
+	}
+	p.language = stlLanguages[strings.ToUpper(string(gsi[14:16]))]
+
+	if tcp := strings.TrimSpace(string(gsi[224:232])); tcp != "" {
+		start, ok := p.parseTimecode(tcp)
+		if !ok {
+			p.diagnose(fmt.Sprintf("start-of-programme timecode %q is not recognised and is ignored", TextPreview(tcp)))
+		}
+		p.programmeStart = start
+	}
+	return nil
+}
+
+// parseTimecode reads an HHMMSSFF timecode of the header
+func (p *STLReader) parseTimecode(tc string) (time.Duration, bool) {
+	if len(tc) != 8 {
+		return 0, false
+	}
+	var parts [4]int
+	for i := range parts {
+		n, err := strconv.Atoi(tc[i*2 : i*2+2])
+		if err != nil {
+			return 0, false
+		}
+		parts[i] = n
+	}
+	return p.timecode(parts[0], parts[1], parts[2], parts[3])
+}
+
+// timecode converts a timecode to the time since midnight. Frames count
+// at the nominal, whole rate, as non-drop-frame timecode does.
+func (p *STLReader) timecode(hours, minutes, seconds, frames int) (time.Duration, bool) {
+	nominal := int((p.frameRate.Num + p.frameRate.Den - 1) / p.frameRate.Den)
+	if hours > 23 || minutes > 59 || seconds > 59 || frames >= nominal {
+		return 0, false
+	}
+	total := int64(((hours*60+minutes)*60+seconds)*nominal + frames)
+	return p.frameRate.Duration(total), true
+}
+
+// block reads one TTI block, completing a subtitle at its last block
+func (p *STLReader) block(block []byte) {
+	number := int(block[1]) | int(block[2])<<8
+	ebn := block[3]
+	if ebn == stlUserData || block[15] != 0 {
+		// User data and comments are not displayed
+		return
+	}
+
+	if p.current != nil && p.current.number != number {
+		p.diagnose(fmt.Sprintf("subtitle %d has no last extension block", p.current.number))
+		p.finish()
+	}
+	if p.current == nil {
+		p.current = &stlSubtitle{number: number}
+		p.current.start = p.blockTime(number, "in", block[5:9])
+		p.current.end = p.blockTime(number, "out", block[9:13])
+	}
+
+	text := block[16:]
+	for i, b := range text {
+		if b == stlUnused {
+			text = text[:i]
+			break
+		}
+	}
+	p.current.text = append(p.current.text, text...)
+	if ebn == stlLastBlock {
+		p.finish()
+	}
+}
+
+// blockTime converts the time code in or out of a TTI block
+func (p *STLReader) blockTime(number int, which string, tc []byte) time.Duration {
+	t, ok := p.timecode(int(tc[0]), int(tc[1]), int(tc[2]), int(tc[3]))
+	if !ok {
+		p.diagnose(fmt.Sprintf("subtitle %d: time code %s %02d:%02d:%02d:%02d is not valid", number, which, tc[0], tc[1], tc[2], tc[3]))
+		return 0
+	}
+	if !p.timed {
+		// Timecodes count from the start of the programme, unless the first
+		// subtitle shows they were written from zero
+		p.timed = true
+		if t >= p.programmeStart {
+			p.offset = p.programmeStart
+		}
+	}
+	if t < p.offset {
+		p.diagnose(fmt.Sprintf("subtitle %d: time code %s is before the start of the programme", number, which))
+		return 0
+	}
+	return t - p.offset
+}
+
+// finish completes the current subtitle. Subtitles without text are
+// skipped.
+func (p *STLReader) finish() {
+	subtitle := p.current
+	p.current = nil
+	lines := stlText(subtitle.text, p.codeTable)
+	if len(lines) == 0 {
+		return
+	}
+	p.pending = append(p.pending, Cue{
+		CaptionEntry: models.CaptionEntry{
+			StartTime: subtitle.start,
+			EndTime:   subtitle.end,
+			Text:      strings.Join(lines, " "),
+			Lines:     lines,
+			Language:  p.language,
+		},
+		Diagnostics: p.diagnostics,
+	})
+	p.diagnostics = nil
+}
+
+func (p *STLReader) diagnose(message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Message: message})
+}
@@ -0,0 +1,134 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// transcriptLineRegex matches a line starting with a [MM:SS] or [H:MM:SS]
+// timestamp, optionally with a fraction of a second
+var transcriptLineRegex = regexp.MustCompile(`^\[(?:(\d+):)?(\d{1,2}):(\d{2})(?:\.(\d{1,3}))?\]\s*(.*)$`)
+
+func ParseTranscript(reader io.Reader) ([]models.CaptionEntry, error) {
+	return parseTranscript(reader, DefaultMaxLineSize)
+}
+
+func parseTranscript(reader io.Reader, maxLineSize int) ([]models.CaptionEntry, error) {
+	return collect(NewTranscriptReader(reader, maxLineSize))
+}
+
+// TranscriptReader reads plain transcripts in which each entry starts with
+// a timestamp such as "[00:01] text". An entry lasts until the next
+// timestamp; lines without a timestamp continue the entry before them. The
+// last entry is given the average duration of the others.
+type TranscriptReader struct {
+	scanner *LineReader
+	// entry is the entry being read, which ends when the next one starts
+	entry       *Cue
+	entries     int
+	total       time.Duration
+	diagnostics []Diagnostic
+	done        bool
+}
+
+// NewTranscriptReader returns a streaming transcript parser
+func NewTranscriptReader(reader io.Reader, maxLineSize int) *TranscriptReader {
+	return &TranscriptReader{scanner: NewLineReader(reader, maxLineSize)}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *TranscriptReader) Next() (Cue, error) {
+	for !p.done && p.scanner.Scan() {
+		lineNum := p.scanner.Line()
+		line := strings.TrimSpace(p.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		m := transcriptLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			if p.entry == nil {
+				p.diagnose(lineNum, fmt.Sprintf("text %q has no timestamp before it and is ignored", TextPreview(line)))
+				continue
+			}
+			p.entry.Lines = append(p.entry.Lines, line)
+			continue
+		}
+
+		start := transcriptTime(m[1], m[2], m[3], m[4])
+		next := &Cue{CaptionEntry: models.CaptionEntry{StartTime: start, Line: lineNum}}
+		if m[5] != "" {
+			next.Lines = []string{m[5]}
+		}
+		previous := p.entry
+		p.entry = next
+		if previous == nil {
+			continue
+		}
+		cue, ok := p.finish(previous, start)
+		if start < previous.StartTime {
+			p.diagnose(lineNum, fmt.Sprintf("timestamp %q is earlier than the one before it", m[0][:strings.IndexByte(m[0], ']')+1]))
+		}
+		if ok {
+			return cue, nil
+		}
+	}
+	if !p.done {
+		if err := p.scanner.Err(); err != nil {
+			return Cue{}, err
+		}
+		p.done = true
+	}
+
+	// The last entry has no timestamp after it
+	if last := p.entry; last != nil {
+		p.entry = nil
+		end := last.StartTime
+		if p.entries > 0 {
+			end += p.total / time.Duration(p.entries)
+		} else {
+			p.diagnose(last.Line, "entry has no end time")
+		}
+		if cue, ok := p.finish(last, end); ok {
+			return cue, nil
+		}
+	}
+	return Cue{}, io.EOF
+}
+
+// finish completes an entry that ends at the given time. Entries without
+// text are dropped.
+func (p *TranscriptReader) finish(entry *Cue, end time.Duration) (Cue, bool) {
+	if len(entry.Lines) == 0 {
+		return Cue{}, false
+	}
+	entry.EndTime = end
+	entry.Text = strings.Join(entry.Lines, " ")
+	if end > entry.StartTime {
+		p.entries++
+		p.total += end - entry.StartTime
+	}
+	entry.Diagnostics = p.diagnostics
+	p.diagnostics = nil
+	return *entry, true
+}
+
+func (p *TranscriptReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
+
+// transcriptTime converts the parts of a transcript timestamp to a duration
+func transcriptTime(hh, mm, ss, fraction string) time.Duration {
+	hours, _ := strconv.ParseInt(hh, 10, 64)
+	minutes, _ := strconv.ParseInt(mm, 10, 64)
+	seconds, _ := strconv.ParseInt(ss, 10, 64)
+	milliseconds, _ := strconv.ParseInt(fraction+strings.Repeat("0", 3-len(fraction)), 10, 64)
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(milliseconds)*time.Millisecond
+}
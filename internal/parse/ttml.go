@@ -0,0 +1,367 @@
+package parse
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+var (
+	// ttmlClockRegex matches hh:mm:ss, hh:mm:ss.fraction and
+	// hh:mm:ss:frames(.subframes) clock times
+	ttmlClockRegex = regexp.MustCompile(`^(\d{2,}):(\d{2}):(\d{2})(?:(\.\d+)|:(\d{2,})(?:\.(\d+))?)?$`)
+	// ttmlOffsetRegex matches offset times such as 1.5s, 100ms or 48f
+	ttmlOffsetRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|ms|m|s|f|t)$`)
+)
+
+func ParseTTML(reader io.Reader) ([]models.CaptionEntry, error) {
+	return collect(NewTTMLReader(reader))
+}
+
+// ttmlTiming holds the ttp: parameters of a document that time expressions
+// in frames and ticks depend on
+type ttmlTiming struct {
+	frameRate    FrameRate
+	subFrameRate int64
+	tickRate     int64
+}
+
+// ttmlScope is the resolved timing and language of an open element
+type ttmlScope struct {
+	begin time.Duration
+	end   time.Duration
+	// bounded is set when end is known
+	bounded bool
+	// timed is set when the element or an ancestor has timing
+	timed bool
+	lang  string
+}
+
+// ttmlParagraph collects the content of the <p> being read
+type ttmlParagraph struct {
+	depth int
+	scope ttmlScope
+	line  int
+	lines []string
+	text  strings.Builder
+	// spans is the extent of timed spans, used when the paragraph itself
+	// has no timing
+	spans     ttmlScope
+	skipDepth int
+}
+
+// TTMLReader reads TTML, DFXP and IMSC1 text paragraphs one at a time. Every
+// <p> is a cue; its timing is inherited from enclosing <body>, <div> and
+// <p> elements, or taken from its timed <span> children when it has none.
+type TTMLReader struct {
+	decoder     *xml.Decoder
+	timing      ttmlTiming
+	rooted      bool
+	scopes      []ttmlScope
+	para        *ttmlParagraph
+	diagnostics []Diagnostic
+}
+
+// NewTTMLReader returns a streaming TTML parser
+func NewTTMLReader(reader io.Reader) *TTMLReader {
+	decoder := xml.NewDecoder(reader)
+	// Authoring tools often write HTML entities such as &nbsp;
+	decoder.Entity = xml.HTMLEntity
+	// Input reaches the parser decoded to UTF-8 whatever the declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return &TTMLReader{decoder: decoder}
+}
+
+// Next returns the next cue, or io.EOF after the last one
+func (p *TTMLReader) Next() (Cue, error) {
+	for {
+		token, err := p.decoder.Token()
+		if err == io.EOF {
+			return Cue{}, io.EOF
+		}
+		if err != nil {
+			return Cue{}, fmt.Errorf("invalid TTML: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			p.start(t)
+		case xml.EndElement:
+			if cue, ok := p.end(); ok {
+				return cue, nil
+			}
+		case xml.CharData:
+			if p.para != nil && p.para.skipDepth == 0 {
+				p.para.text.Write(t)
+			}
+		}
+	}
+}
+
+func (p *TTMLReader) start(element xml.StartElement) {
+	line, _ := p.decoder.InputPos()
+	if !p.rooted {
+		p.rooted = true
+		p.timing = p.readTiming(element, line)
+	}
+
+	parent := ttmlScope{}
+	if len(p.scopes) > 0 {
+		parent = p.scopes[len(p.scopes)-1]
+	}
+	scope, timed := p.resolve(element, parent, line)
+	p.scopes = append(p.scopes, scope)
+
+	para := p.para
+	switch {
+	case para != nil && para.skipDepth > 0:
+		para.skipDepth++
+	case para != nil && element.Name.Local == "br":
+		p.breakLine()
+	case para != nil && element.Name.Local == "metadata":
+		para.skipDepth = 1
+	case para != nil && timed:
+		// A timed span widens the extent used by an untimed paragraph
+		if !para.spans.timed || scope.begin < para.spans.begin {
+			para.spans.begin = scope.begin
+		}
+		if scope.bounded && (!para.spans.bounded || scope.end > para.spans.end) {
+			para.spans.end, para.spans.bounded = scope.end, true
+		}
+		para.spans.timed = true
+	case para == nil && element.Name.Local == "p":
+		p.para = &ttmlParagraph{depth: len(p.scopes), scope: scope, line: line}
+	}
+}
+
+func (p *TTMLReader) end() (Cue, bool) {
+	depth := len(p.scopes)
+	p.scopes = p.scopes[:depth-1]
+
+	para := p.para
+	if para == nil {
+		return Cue{}, false
+	}
+	if para.skipDepth > 0 {
+		para.skipDepth--
+		return Cue{}, false
+	}
+	if depth != para.depth {
+		return Cue{}, false
+	}
+
+	p.breakLine()
+	p.para = nil
+	if len(para.lines) == 0 {
+		return Cue{}, false
+	}
+
+	timing := para.scope
+	if !timing.timed && para.spans.timed {
+		timing.begin, timing.end, timing.bounded = para.spans.begin, para.spans.end, para.spans.bounded
+		timing.timed = true
+	}
+	if !timing.timed {
+		p.diagnose(para.line, "cue has no timing")
+	}
+	if !timing.bounded {
+		p.diagnose(para.line, "cue has no end time")
+		timing.end = timing.begin
+	}
+
+	cue := Cue{
+		CaptionEntry: models.CaptionEntry{
+			StartTime: timing.begin,
+			EndTime:   timing.end,
+			Text:      strings.Join(para.lines, " "),
+			Lines:     para.lines,
+			Line:      para.line,
+			Language:  timing.lang,
+		},
+		Diagnostics: p.diagnostics,
+	}
+	p.diagnostics = nil
+	return cue, true
+}
+
+// breakLine ends the current line of the paragraph being read. White space
+// is collapsed and empty lines are dropped.
+func (p *TTMLReader) breakLine() {
+	para := p.para
+	if para == nil {
+		return
+	}
+	if line := strings.Join(strings.Fields(para.text.String()), " "); line != "" {
+		para.lines = append(para.lines, line)
+	}
+	para.text.Reset()
+}
+
+// resolve computes the timing of an element from its parent's and its own
+// begin, end and dur attributes. The second result reports whether the
+// element has timing of its own.
+func (p *TTMLReader) resolve(element xml.StartElement, parent ttmlScope, line int) (ttmlScope, bool) {
+	scope := parent
+	var begin, end, dur time.Duration
+	var hasBegin, hasEnd, hasDur bool
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "lang" && (attr.Name.Space == xmlNamespace || attr.Name.Space == "xml") {
+			scope.lang = attr.Value
+			continue
+		}
+		if attr.Name.Space != "" {
+			continue
+		}
+		var target *time.Duration
+		var present *bool
+		switch attr.Name.Local {
+		case "begin":
+			target, present = &begin, &hasBegin
+		case "end":
+			target, present = &end, &hasEnd
+		case "dur":
+			target, present = &dur, &hasDur
+		default:
+			continue
+		}
+		value, err := p.timing.parse(strings.TrimSpace(attr.Value))
+		if err != nil {
+			p.diagnose(line, fmt.Sprintf("%s time %q is not recognised and is ignored", attr.Name.Local, TextPreview(attr.Value)))
+			continue
+		}
+		*target, *present = value, true
+	}
+	if !hasBegin && !hasEnd && !hasDur {
+		return scope, false
+	}
+
+	// Times are relative to the begin of the parent
+	scope.begin = parent.begin + begin
+	switch {
+	case hasEnd:
+		scope.end, scope.bounded = parent.begin+end, true
+	case hasDur:
+		scope.end, scope.bounded = scope.begin+dur, true
+	}
+	if parent.bounded {
+		if !scope.bounded || scope.end > parent.end {
+			scope.end, scope.bounded = parent.end, true
+		}
+	}
+	if scope.end < scope.begin {
+		scope.end = scope.begin
+	}
+	scope.timed = true
+	return scope, true
+}
+
+// readTiming reads the frame, sub-frame and tick rates from the root element
+func (p *TTMLReader) readTiming(root xml.StartElement, line int) ttmlTiming {
+	frameRate, multiplier := int64(0), FrameRate{Num: 1, Den: 1}
+	timing := ttmlTiming{subFrameRate: 1}
+	for _, attr := range root.Attr {
+		value := strings.TrimSpace(attr.Value)
+		var err error
+		switch attr.Name.Local {
+		case "frameRate":
+			frameRate, err = strconv.ParseInt(value, 10, 64)
+		case "frameRateMultiplier":
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				err = fmt.Errorf("expected two numbers")
+				break
+			}
+			if multiplier.Num, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+				multiplier.Den, err = strconv.ParseInt(fields[1], 10, 64)
+			}
+		case "subFrameRate":
+			timing.subFrameRate, err = strconv.ParseInt(value, 10, 64)
+		case "tickRate":
+			timing.tickRate, err = strconv.ParseInt(value, 10, 64)
+		default:
+			continue
+		}
+		if err != nil {
+			p.diagnose(line, fmt.Sprintf("%s %q is not recognised and is ignored", attr.Name.Local, TextPreview(attr.Value)))
+		}
+	}
+
+	if timing.subFrameRate <= 0 {
+		timing.subFrameRate = 1
+	}
+	// Without a tick rate, a tick is a sub-frame when the frame rate is
+	// given and a second otherwise
+	if timing.tickRate <= 0 {
+		timing.tickRate = 1
+		if frameRate > 0 {
+			timing.tickRate = frameRate * timing.subFrameRate
+		}
+	}
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	if !multiplier.Valid() {
+		multiplier = FrameRate{Num: 1, Den: 1}
+	}
+	timing.frameRate = FrameRate{Num: frameRate * multiplier.Num, Den: multiplier.Den}
+	return timing
+}
+
+// parse converts a TTML clock time or offset time to a duration
+func (t ttmlTiming) parse(value string) (time.Duration, error) {
+	if m := ttmlClockRegex.FindStringSubmatch(value); m != nil {
+		hours, _ := strconv.ParseInt(m[1], 10, 64)
+		minutes, _ := strconv.ParseInt(m[2], 10, 64)
+		seconds, _ := strconv.ParseInt(m[3], 10, 64)
+		ns := new(big.Rat).SetInt64(((hours*60+minutes)*60 + seconds) * int64(time.Second))
+		switch {
+		case m[4] != "":
+			fraction, _ := new(big.Rat).SetString("0" + m[4])
+			ns.Add(ns, fraction.Mul(fraction, big.NewRat(int64(time.Second), 1)))
+		case m[5] != "":
+			frames, _ := new(big.Rat).SetString(m[5])
+			if m[6] != "" {
+				sub, _ := new(big.Rat).SetString(m[6])
+				frames.Add(frames, sub.Quo(sub, big.NewRat(t.subFrameRate, 1)))
+			}
+			ns.Add(ns, frames.Mul(frames, t.frameRate.frameRat()))
+		}
+		return ratDuration(ns), nil
+	}
+
+	if m := ttmlOffsetRegex.FindStringSubmatch(value); m != nil {
+		count, _ := new(big.Rat).SetString(m[1])
+		var unit *big.Rat
+		switch m[2] {
+		case "h":
+			unit = big.NewRat(int64(time.Hour), 1)
+		case "m":
+			unit = big.NewRat(int64(time.Minute), 1)
+		case "s":
+			unit = big.NewRat(int64(time.Second), 1)
+		case "ms":
+			unit = big.NewRat(int64(time.Millisecond), 1)
+		case "f":
+			unit = t.frameRate.frameRat()
+		case "t":
+			unit = big.NewRat(int64(time.Second), t.tickRate)
+		}
+		return ratDuration(count.Mul(count, unit)), nil
+	}
+	return 0, fmt.Errorf("invalid time expression: %s", value)
+}
+
+func (p *TTMLReader) diagnose(line int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: line, Message: message})
+}
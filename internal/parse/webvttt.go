@@ -1,7 +1,6 @@
 package parse
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"regexp"
@@ -12,60 +11,139 @@ import (
 	"github.com/theCompanyDream/srt-test/internal/models"
 )
 
+var webVTTTimeRegex = regexp.MustCompile(`(\d{2}:\d{2}:\d{2}\.\d{3})\s+-->\s+(\d{2}:\d{2}:\d{2}\.\d{3})`)
+
 func ParseWebVTT(reader io.Reader) ([]models.CaptionEntry, error) {
-	scanner := bufio.NewScanner(reader)
-	var captions []models.CaptionEntry
-	var currentEntry models.CaptionEntry
-	var textLines []string
-	inHeader := true
+	return parseWebVTT(reader, DefaultMaxLineSize)
+}
 
-	timeRegex := regexp.MustCompile(`(\d{2}:\d{2}:\d{2}\.\d{3})\s+-->\s+(\d{2}:\d{2}:\d{2}\.\d{3})`)
+func parseWebVTT(reader io.Reader, maxLineSize int) ([]models.CaptionEntry, error) {
+	return collect(NewWebVTTReader(reader, maxLineSize))
+}
+
+// WebVTTReader reads WebVTT cues one at a time
+type WebVTTReader struct {
+	scanner      *LineReader
+	currentEntry models.CaptionEntry
+	blockStart   bool
+	// skipping is set inside the header and NOTE, STYLE and REGION blocks
+	skipping bool
+	// timed is set once the current block has a timing line
+	timed bool
+	// header is set inside the WEBVTT header block
+	header       bool
+	timestampMap string
+	diagnostics  []Diagnostic
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+// NewWebVTTReader returns a streaming WebVTT parser
+func NewWebVTTReader(reader io.Reader, maxLineSize int) *WebVTTReader {
+	return &WebVTTReader{scanner: NewLineReader(reader, maxLineSize), blockStart: true}
+}
 
-		// Skip header
-		if inHeader {
-			if line == "" || strings.HasPrefix(line, "WEBVTT") || strings.HasPrefix(line, "NOTE") {
-				continue
+// Next returns the next cue, or io.EOF after the last one
+func (p *WebVTTReader) Next() (Cue, error) {
+	var textLines []string
+	textStart := 0
+
+	for p.scanner.Scan() {
+		lineNum := p.scanner.Line()
+		line := strings.TrimSpace(p.scanner.Text())
+
+		// Skip the header and metadata blocks, which may contain long
+		// lines such as inline images
+		if line != "" && p.blockStart {
+			p.blockStart = false
+			p.header = strings.HasPrefix(line, "WEBVTT")
+			p.skipping = p.header || isWebVTTMetadataBlock(line)
+		}
+		if p.skipping && line != "" {
+			if value, ok := strings.CutPrefix(line, "X-TIMESTAMP-MAP="); ok && p.header {
+				p.timestampMap = value
 			}
-			inHeader = false
+			continue
 		}
 
 		// Empty line indicates end of caption block
 		if line == "" {
+			p.blockStart, p.skipping = true, false
 			if len(textLines) > 0 {
-				currentEntry.Text = strings.Join(textLines, " ")
-				captions = append(captions, currentEntry)
-				textLines = nil
+				return p.emit(textLines, textStart), nil
 			}
 			continue
 		}
 
 		// Check if line contains timing
-		if matches := timeRegex.FindStringSubmatch(line); len(matches) == 3 {
+		if matches := webVTTTimeRegex.FindStringSubmatch(line); len(matches) == 3 {
 			var err error
-			currentEntry.StartTime, err = parseWebVTTTime(matches[1])
+			p.currentEntry.Line = lineNum
+			p.currentEntry.StartTime, err = parseWebVTTTime(matches[1])
 			if err != nil {
-				return nil, fmt.Errorf("error parsing start time: %v", err)
+				return Cue{}, fmt.Errorf("error parsing start time: %v", err)
 			}
-			currentEntry.EndTime, err = parseWebVTTTime(matches[2])
+			p.currentEntry.EndTime, err = parseWebVTTTime(matches[2])
 			if err != nil {
-				return nil, fmt.Errorf("error parsing end time: %v", err)
+				return Cue{}, fmt.Errorf("error parsing end time: %v", err)
+			}
+			// A line right before the timing line in the same block is the
+			// cue identifier, not text
+			if !p.timed && len(textLines) == 1 {
+				textLines = nil
 			}
+			p.timed = true
 		} else {
 			// This is text content
+			if strings.Contains(line, "-->") {
+				p.diagnostics = append(p.diagnostics, Diagnostic{Line: lineNum,
+					Message: fmt.Sprintf("timing line %q is not recognised and is read as text", TextPreview(line))})
+			}
+			if len(textLines) == 0 {
+				textStart = lineNum
+			}
 			textLines = append(textLines, line)
 		}
 	}
 
 	// Handle last caption if file doesn't end with empty line
 	if len(textLines) > 0 {
-		currentEntry.Text = strings.Join(textLines, " ")
-		captions = append(captions, currentEntry)
+		return p.emit(textLines, textStart), nil
+	}
+	if err := p.scanner.Err(); err != nil {
+		return Cue{}, err
+	}
+	return Cue{}, io.EOF
+}
+
+// TimestampMap returns the value of the X-TIMESTAMP-MAP header that maps
+// the cue times of an HLS segment to MPEG-2 timestamps, or "" without one.
+// It is known once Next has been called.
+func (p *WebVTTReader) TimestampMap() string {
+	return p.timestampMap
+}
+
+// emit completes the cue of the current block. A block without a timing
+// line keeps the timing of the previous cue.
+func (p *WebVTTReader) emit(textLines []string, textStart int) Cue {
+	if !p.timed {
+		p.diagnostics = append(p.diagnostics, Diagnostic{Line: textStart, Message: "cue has no timing line"})
 	}
+	p.currentEntry.Text = strings.Join(textLines, " ")
+	p.currentEntry.Lines = textLines
+	cue := Cue{CaptionEntry: p.currentEntry, Diagnostics: p.diagnostics}
+	p.timed = false
+	p.diagnostics = nil
+	return cue
+}
 
-	return captions, scanner.Err()
+// isWebVTTMetadataBlock reports whether line starts a block that holds no
+// cue
+func isWebVTTMetadataBlock(line string) bool {
+	for _, keyword := range []string{"NOTE", "STYLE", "REGION"} {
+		if line == keyword || strings.HasPrefix(line, keyword+" ") || strings.HasPrefix(line, keyword+"\t") {
+			return true
+		}
+	}
+	return false
 }
 
 func parseWebVTTTime(timeStr string) (time.Duration, error) {
@@ -0,0 +1,311 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+// htmlSpan is an interval positioned on the timeline as percentages
+type htmlSpan struct {
+	Left  string
+	Width string
+	Class string
+	Label string
+	Title string
+	ID    string
+}
+
+type htmlFinding struct {
+	ID       string
+	Code     string
+	Type     string
+	Severity string
+	Text     string
+	Timing   string
+	Excerpt  string
+	Marker   *htmlSpan
+	CueID    string
+}
+
+type htmlTick struct {
+	Left  string
+	Label string
+}
+
+type htmlView struct {
+	Report   *models.Report
+	Coverage string
+	Required string
+	Range    string
+	Ticks    []htmlTick
+	Cues     []htmlSpan
+	Gaps     []htmlSpan
+	Overlaps []htmlSpan
+	Windows  []htmlSpan
+	Findings []htmlFinding
+}
+
+// timelineScale converts durations to percentages of the validation range
+type timelineScale struct {
+	start, end time.Duration
+}
+
+func (s timelineScale) span(start, end time.Duration) (htmlSpan, bool) {
+	start = utils.MaxDuration(start, s.start)
+	end = utils.MinDuration(end, s.end)
+	total := s.end - s.start
+	if total <= 0 || end < start {
+		return htmlSpan{}, false
+	}
+	width := float64(end-start) / float64(total) * 100
+	return htmlSpan{
+		Left:  fmt.Sprintf("%.4f%%", float64(start-s.start)/float64(total)*100),
+		Width: fmt.Sprintf("%.4f%%", width),
+	}, true
+}
+
+func spanTitle(start, end time.Duration, text string) string {
+	title := fmt.Sprintf("%s --> %s", utils.FormatTimestamp(start), utils.FormatTimestamp(end))
+	if text != "" {
+		title += "\n" + text
+	}
+	return title
+}
+
+// WriteHTMLFile writes the HTML report to path
+func WriteHTMLFile(path string, report *models.Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteHTML(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteHTML renders a self-contained HTML page with the coverage timeline
+// of the validation range: cue bars, uncovered gaps, overlaps, per-window
+// language results and markers linking findings to their position
+func WriteHTML(w io.Writer, report *models.Report) error {
+	scale := timelineScale{start: report.Start, end: report.End}
+	view := htmlView{
+		Report:   report,
+		Coverage: fmt.Sprintf("%.1f%%", report.Metrics.Coverage*100),
+		Required: fmt.Sprintf("%.1f%%", report.Config.Rules.Coverage*100),
+		Range:    fmt.Sprintf("%s – %s", utils.FormatTimestamp(report.Start), utils.FormatTimestamp(report.End)),
+	}
+
+	const tickCount = 10
+	for i := 0; i <= tickCount; i++ {
+		at := report.Start + (report.End-report.Start)*time.Duration(i)/tickCount
+		view.Ticks = append(view.Ticks, htmlTick{
+			Left:  fmt.Sprintf("%.4f%%", float64(i)*100/tickCount),
+			Label: utils.FormatTimestamp(at),
+		})
+	}
+
+	for i, cue := range report.Cues {
+		if span, ok := scale.span(cue.StartTime, cue.EndTime); ok {
+			span.ID = fmt.Sprintf("cue-%d", i+1)
+			span.Title = fmt.Sprintf("Cue %d: %s", i+1, spanTitle(cue.StartTime, cue.EndTime, parse.TextPreview(cue.Text)))
+			view.Cues = append(view.Cues, span)
+		}
+	}
+	if report.Cues != nil {
+		for _, gap := range utils.CoverageGaps(report.Cues, report.Start, report.End) {
+			if span, ok := scale.span(gap.Start, gap.End); ok {
+				span.Title = "Uncovered: " + spanTitle(gap.Start, gap.End, "")
+				view.Gaps = append(view.Gaps, span)
+			}
+		}
+		for _, overlap := range utils.Overlaps(report.Cues) {
+			if span, ok := scale.span(overlap.Start, overlap.End); ok {
+				span.Title = "Overlap: " + spanTitle(overlap.Start, overlap.End, "")
+				view.Overlaps = append(view.Overlaps, span)
+			}
+		}
+	}
+
+	for _, window := range report.LanguageWindows {
+		span, ok := scale.span(window.Start, window.End)
+		if !ok {
+			continue
+		}
+		switch {
+		case window.Error != "":
+			span.Class, span.Label = "error", "?"
+			span.Title = spanTitle(window.Start, window.End, "Detection failed: "+window.Error)
+		case window.Language == "":
+			span.Class, span.Label = "empty", ""
+			span.Title = spanTitle(window.Start, window.End, "No captions")
+		case window.Matches:
+			span.Class, span.Label = "match", window.Language
+			span.Title = spanTitle(window.Start, window.End, window.Language)
+		default:
+			span.Class, span.Label = "mismatch", window.Language
+			span.Title = spanTitle(window.Start, window.End, window.Language+", expected "+report.Config.Language)
+		}
+		view.Windows = append(view.Windows, span)
+	}
+
+	for i, finding := range report.Findings {
+		item := htmlFinding{
+			ID:       fmt.Sprintf("finding-%d", i+1),
+			Code:     finding.Code,
+			Type:     finding.Type,
+			Severity: string(finding.Severity),
+			Text:     finding.Description,
+		}
+		if loc := finding.Location; loc != nil {
+			item.Timing = fmt.Sprintf("%s --> %s", utils.FormatTimestamp(loc.Start), utils.FormatTimestamp(loc.End))
+			item.Excerpt = loc.Excerpt
+			if loc.Cue > 0 {
+				item.CueID = fmt.Sprintf("cue-%d", loc.Cue)
+			}
+			if span, ok := scale.span(loc.Start, loc.End); ok {
+				span.ID = item.ID
+				span.Class = item.Severity
+				span.Title = fmt.Sprintf("%s %s\n%s", finding.Code, finding.Type, item.Timing)
+				item.Marker = &span
+			}
+		}
+		view.Findings = append(view.Findings, item)
+	}
+
+	return htmlTemplate.Execute(w, view)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Caption validation: {{.Report.File}}</title>
+<style>
+  body { font: 14px/1.45 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #1f2328; }
+  h1 { font-size: 20px; margin: 0 0 12px; word-break: break-all; }
+  h2 { font-size: 16px; margin: 28px 0 8px; }
+  .summary { display: grid; grid-template-columns: max-content auto; gap: 4px 16px; }
+  .summary dt { font-weight: 600; }
+  .summary dd { margin: 0; }
+  .verdict { font-weight: 700; text-transform: uppercase; }
+  .verdict.pass { color: #1a7f37; } .verdict.fail { color: #cf222e; } .verdict.error { color: #9a6700; }
+  .controls { margin: 8px 0; }
+  .scroller { overflow-x: auto; border: 1px solid #d0d7de; border-radius: 6px; padding: 8px 0; }
+  .timeline { position: relative; min-width: 100%; }
+  .lane { position: relative; height: 22px; margin: 4px 0; background: #f6f8fa; }
+  .lane-label { position: sticky; left: 0; z-index: 2; display: inline-block; font-size: 11px; padding: 3px 6px; background: rgba(255,255,255,.85); color: #57606a; }
+  .bar { position: absolute; top: 0; bottom: 0; min-width: 1px; box-sizing: border-box; overflow: hidden; font-size: 11px; white-space: nowrap; }
+  .cue { background: #54aeff; border-right: 1px solid #fff; }
+  .gap { background: repeating-linear-gradient(45deg, #ffd8d3, #ffd8d3 4px, #fff 4px, #fff 8px); }
+  .overlap { background: #bf8700; }
+  .window { border-right: 1px solid #fff; padding: 3px 4px; }
+  .window.match { background: #aceebb; } .window.mismatch { background: #ff8182; }
+  .window.error { background: #d4a72c; } .window.empty { background: #eaeef2; }
+  .marker { cursor: pointer; opacity: .85; min-width: 3px; }
+  .marker.error { background: #cf222e; } .marker.warning { background: #d4a72c; } .marker.info { background: #0969da; }
+  .ticks { position: relative; height: 18px; font-size: 11px; color: #57606a; }
+  .ticks span { position: absolute; transform: translateX(-50%); white-space: nowrap; }
+  .ticks span:first-child { transform: none; } .ticks span:last-child { transform: translateX(-100%); }
+  .selected { outline: 3px solid #8250df; z-index: 3; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #d0d7de; vertical-align: top; }
+  tr.finding { cursor: pointer; }
+  tr.finding:hover, tr.finding.selected { background: #f3e8ff; outline: none; }
+  .sev { font-weight: 600; } .sev.error { color: #cf222e; } .sev.warning { color: #9a6700; } .sev.info { color: #0969da; }
+  .excerpt { color: #57606a; font-style: italic; }
+  code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Report.File}}</h1>
+<dl class="summary">
+  {{- if .Report.Format}}<dt>Format</dt><dd>{{.Report.Format}}</dd>{{end}}
+  <dt>Range</dt><dd>{{.Range}}</dd>
+  <dt>Cues</dt><dd>{{.Report.Metrics.CueCount}}</dd>
+  <dt>Coverage</dt><dd>{{.Coverage}} (required {{.Required}})</dd>
+  <dt>Language</dt><dd>{{with .Report.Metrics.DetectedLanguage}}{{.}}{{else}}unknown{{end}} (expected {{.Report.Config.Language}})</dd>
+  {{- if .Report.Config.Profile}}<dt>Profile</dt><dd>{{.Report.Config.Profile}}</dd>{{end}}
+  <dt>Verdict</dt><dd><span class="verdict {{.Report.Verdict}}">{{.Report.Verdict}}</span>{{with .Report.Reason}} – {{.}}{{end}}</dd>
+</dl>
+
+<h2>Timeline</h2>
+<div class="controls"><label>Zoom <input id="zoom" type="range" min="1" max="50" value="1"></label></div>
+<div class="scroller">
+  <div class="timeline" id="timeline">
+    <div class="ticks">{{range .Ticks}}<span style="left: {{.Left}}">{{.Label}}</span>{{end}}</div>
+    <div class="lane"><span class="lane-label">Cues</span>{{range .Cues}}<div class="bar cue" id="{{.ID}}" style="left: {{.Left}}; width: {{.Width}}" title="{{.Title}}"></div>{{end}}</div>
+    <div class="lane"><span class="lane-label">Gaps</span>{{range .Gaps}}<div class="bar gap" style="left: {{.Left}}; width: {{.Width}}" title="{{.Title}}"></div>{{end}}</div>
+    <div class="lane"><span class="lane-label">Overlaps</span>{{range .Overlaps}}<div class="bar overlap" style="left: {{.Left}}; width: {{.Width}}" title="{{.Title}}"></div>{{end}}</div>
+    {{- if .Windows}}
+    <div class="lane"><span class="lane-label">Language</span>{{range .Windows}}<div class="bar window {{.Class}}" style="left: {{.Left}}; width: {{.Width}}" title="{{.Title}}">{{.Label}}</div>{{end}}</div>
+    {{- end}}
+    <div class="lane"><span class="lane-label">Findings</span>{{range .Findings}}{{with .Marker}}<div class="bar marker {{.Class}}" data-finding="{{.ID}}" style="left: {{.Left}}; width: {{.Width}}" title="{{.Title}}"></div>{{end}}{{end}}</div>
+  </div>
+</div>
+
+<h2>Findings ({{len .Findings}})</h2>
+{{- if .Findings}}
+<table>
+  <thead><tr><th>Severity</th><th>Code</th><th>Timing</th><th>Finding</th></tr></thead>
+  <tbody>
+  {{- range .Findings}}
+    <tr class="finding" id="{{.ID}}" data-cue="{{.CueID}}">
+      <td class="sev {{.Severity}}">{{.Severity}}</td>
+      <td><code>{{.Code}}</code><br>{{.Type}}</td>
+      <td><code>{{.Timing}}</code></td>
+      <td>{{.Text}}{{with .Excerpt}}<div class="excerpt">{{.}}</div>{{end}}</td>
+    </tr>
+  {{- end}}
+  </tbody>
+</table>
+{{- else}}
+<p>No findings.</p>
+{{- end}}
+
+<script>
+(function () {
+  var timeline = document.getElementById("timeline");
+  document.getElementById("zoom").addEventListener("input", function (e) {
+    timeline.style.width = (e.target.value * 100) + "%";
+  });
+
+  function select(findingId) {
+    document.querySelectorAll(".selected").forEach(function (el) { el.classList.remove("selected"); });
+    var row = document.getElementById(findingId);
+    if (!row) { return; }
+    row.classList.add("selected");
+    var marker = document.querySelector('.marker[data-finding="' + findingId + '"]');
+    var cue = row.dataset.cue ? document.getElementById(row.dataset.cue) : null;
+    [marker, cue].forEach(function (el) {
+      if (el) {
+        el.classList.add("selected");
+        el.scrollIntoView({ behavior: "smooth", block: "nearest", inline: "center" });
+      }
+    });
+    return row;
+  }
+
+  document.querySelectorAll("tr.finding").forEach(function (row) {
+    row.addEventListener("click", function () { select(row.id); });
+  });
+  document.querySelectorAll(".marker").forEach(function (marker) {
+    marker.addEventListener("click", function () {
+      var row = select(marker.dataset.finding);
+      if (row) { row.scrollIntoView({ behavior: "smooth", block: "center" }); }
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit renders one test case per check. A check fails when one of
+// its findings reaches the fail-on severity; other findings are attached
+// as system output.
+func writeJUnit(w io.Writer, report *models.Report) error {
+	return writeJUnitSuites(w, []junitTestSuite{junitSuite(report)})
+}
+
+// junitSuite builds the test suite of one caption file
+func junitSuite(report *models.Report) junitTestSuite {
+	name := report.File
+	if report.Track != nil {
+		name += " [" + report.Track.ID + "]"
+	}
+	suite := junitTestSuite{
+		Name: name,
+		Properties: []junitProperty{
+			{Name: "verdict", Value: string(report.Verdict)},
+			{Name: "cue_count", Value: fmt.Sprint(report.Metrics.CueCount)},
+			{Name: "coverage", Value: fmt.Sprintf("%.4f", report.Metrics.Coverage)},
+			{Name: "detected_language", Value: report.Metrics.DetectedLanguage},
+		},
+	}
+
+	for _, check := range report.Checks {
+		testCase := junitTestCase{Name: check, ClassName: name}
+		var failures, notes []string
+		var failureType, errorType string
+		for _, finding := range report.Findings {
+			if models.CheckForCode(finding.Code) != check {
+				continue
+			}
+			line := fmt.Sprintf("[%s %s] %s", finding.Severity, finding.Code, finding.Description)
+			switch {
+			case finding.Code == models.CodeParseError || finding.Code == models.CodeLanguageDetection:
+				errorType = finding.Code
+				failures = append(failures, line)
+			case failing(report, finding):
+				if failureType == "" {
+					failureType = finding.Code
+				}
+				failures = append(failures, line)
+			default:
+				notes = append(notes, line)
+			}
+		}
+
+		if len(failures) > 0 {
+			problem := &junitProblem{
+				Message: fmt.Sprintf("%d finding(s) in %s", len(failures), check),
+				Body:    strings.Join(failures, "\n"),
+			}
+			if errorType != "" {
+				problem.Type = errorType
+				testCase.Error = problem
+				suite.Errors++
+			} else {
+				problem.Type = failureType
+				testCase.Failure = problem
+				suite.Failures++
+			}
+		}
+		testCase.SystemOut = strings.Join(notes, "\n")
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Tests = len(suite.Cases)
+	return suite
+}
+
+func writeJUnitSuites(w io.Writer, list []junitTestSuite) error {
+	suites := junitTestSuites{Name: "caption-validator", Suites: list}
+	for _, suite := range list {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// Output formats accepted by --format
+const (
+	FormatJSONLines = "jsonl"
+	FormatJSON      = "json"
+	FormatJUnit     = "junit"
+	FormatSARIF     = "sarif"
+	FormatText      = "text"
+)
+
+// Formats lists every supported output format
+var Formats = []string{FormatJSONLines, FormatJSON, FormatJUnit, FormatSARIF, FormatText}
+
+// IsValidFormat reports whether format is a supported output format
+func IsValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders a report in the given format. Text output is colored when
+// w is a terminal.
+func Write(w io.Writer, format string, report *models.Report) error {
+	switch format {
+	case FormatJSONLines, "":
+		return writeJSONLines(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatJUnit:
+		return writeJUnit(w, report)
+	case FormatSARIF:
+		return writeSARIF(w, report)
+	case FormatText:
+		return WriteText(w, report, UseColor(w))
+	}
+	return fmt.Errorf("unsupported output format %q (expected %s)", format, strings.Join(Formats, ", "))
+}
+
+// WriteBatch renders the combined report of several caption files. JSON
+// lines carry the file of each finding, JUnit has one suite per file,
+// SARIF has one run covering every file and text ends with totals.
+func WriteBatch(w io.Writer, format string, batch *models.BatchReport) error {
+	switch format {
+	case FormatJSONLines, "":
+		return writeBatchJSONLines(w, batch)
+	case FormatJSON:
+		for _, report := range batch.Files {
+			if report.Findings == nil {
+				report.Findings = []models.ValidationError{}
+			}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(batch)
+	case FormatJUnit:
+		suites := make([]junitTestSuite, 0, len(batch.Files))
+		for _, report := range batch.Files {
+			suites = append(suites, junitSuite(report))
+		}
+		return writeJUnitSuites(w, suites)
+	case FormatSARIF:
+		return writeSARIFRun(w, batch.Files)
+	case FormatText:
+		return WriteBatchText(w, batch, UseColor(w))
+	}
+	return fmt.Errorf("unsupported output format %q (expected %s)", format, strings.Join(Formats, ", "))
+}
+
+// fileFinding is a finding tagged with the file and track it was found in
+type fileFinding struct {
+	File  string `json:"file"`
+	Track string `json:"track,omitempty"`
+	models.ValidationError
+}
+
+func writeBatchJSONLines(w io.Writer, batch *models.BatchReport) error {
+	for _, report := range batch.Files {
+		for _, finding := range report.Findings {
+			line := fileFinding{File: report.File, ValidationError: finding}
+			if report.Track != nil {
+				line.Track = report.Track.ID
+			}
+			jsonBytes, err := json.Marshal(line)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, string(jsonBytes)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeJSONLines prints one JSON object per finding
+func writeJSONLines(w io.Writer, report *models.Report) error {
+	for _, finding := range report.Findings {
+		jsonBytes, err := json.Marshal(finding)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(jsonBytes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, report *models.Report) error {
+	if report.Findings == nil {
+		report.Findings = []models.ValidationError{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// failing reports whether a finding counts against the report's verdict
+func failing(report *models.Report, finding models.ValidationError) bool {
+	threshold := report.Config.FailOn.Rank()
+	return threshold > 0 && finding.Severity.Rank() >= threshold
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "caption-validator"
+	toolURI      = "https://github.com/theCompanyDream/srt-test"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps a finding severity to a SARIF result level
+func sarifLevel(severity models.Severity) string {
+	switch severity {
+	case models.SeverityError:
+		return "error"
+	case models.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
+
+// writeSARIF renders findings as SARIF 2.1.0 results so they can be
+// uploaded as code-scanning alerts against the caption file
+func writeSARIF(w io.Writer, report *models.Report) error {
+	return writeSARIFRun(w, []*models.Report{report})
+}
+
+// writeSARIFRun renders the findings of every report as a single run
+func writeSARIFRun(w io.Writer, reports []*models.Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]sarifRule)
+	for _, report := range reports {
+		for _, finding := range report.Findings {
+			if _, ok := rules[finding.Code]; !ok {
+				rules[finding.Code] = sarifRule{
+					ID:               finding.Code,
+					Name:             finding.Type,
+					ShortDescription: sarifMessage{Text: models.CodeSummaries[finding.Code]},
+				}
+			}
+
+			// Findings without a source line point at the top of the file
+			line := 1
+			if finding.Location != nil && finding.Location.Line > 0 {
+				line = finding.Location.Line
+			}
+			message := finding.Description
+			if report.Track != nil {
+				message = fmt.Sprintf("[%s] %s", report.Track.ID, message)
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  finding.Code,
+				Level:   sarifLevel(finding.Severity),
+				Message: sarifMessage{Text: message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(report.File)},
+					Region:           sarifRegion{StartLine: line},
+				}}},
+			})
+		}
+	}
+
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// textWriter renders the human-readable report, optionally with colors
+type textWriter struct {
+	w     io.Writer
+	color bool
+	err   error
+}
+
+// UseColor reports whether output to w should be colored: w must be a
+// terminal and neither NO_COLOR nor TERM=dumb may be set
+func UseColor(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// WriteText renders a summary header followed by findings grouped by rule
+func WriteText(w io.Writer, report *models.Report, color bool) error {
+	t := &textWriter{w: w, color: color}
+	t.report(report)
+	return t.err
+}
+
+// WriteBatchText renders the text report of every file followed by totals
+func WriteBatchText(w io.Writer, batch *models.BatchReport, color bool) error {
+	t := &textWriter{w: w, color: color}
+	for i, report := range batch.Files {
+		if i > 0 {
+			t.printf("\n%s\n\n", t.paint(ansiDim, strings.Repeat("-", 60)))
+		}
+		t.report(report)
+	}
+
+	t.printf("\n%s\n\n", t.paint(ansiDim, strings.Repeat("=", 60)))
+	totals := batch.Totals
+	t.field("Files", fmt.Sprintf("%d (%s, %s, %s)", totals.Files,
+		t.paint(ansiGreen, fmt.Sprintf("%d passed", totals.Passed)),
+		t.paint(ansiRed, fmt.Sprintf("%d failed", totals.Failed)),
+		t.paint(ansiYellow, fmt.Sprintf("%d errors", totals.Errored))))
+	t.field("Findings", fmt.Sprint(totals.Findings))
+	t.field("Verdict", t.verdict(batch.Verdict, batch.Reason))
+	return t.err
+}
+
+// report writes the summary and findings of one file
+func (t *textWriter) report(report *models.Report) {
+	t.field("File", report.File)
+	if report.Track != nil {
+		t.field("Track", trackName(report.Track))
+	}
+	if report.Format != "" {
+		t.field("Format", report.Format)
+	}
+	t.field("Cues", fmt.Sprint(report.Metrics.CueCount))
+	t.field("Coverage", fmt.Sprintf("%.1f%% (required %.1f%%)", report.Metrics.Coverage*100, report.Config.Rules.Coverage*100))
+	language := report.Metrics.DetectedLanguage
+	if language == "" {
+		language = "unknown"
+	}
+	t.field("Language", fmt.Sprintf("%s (expected %s)", language, report.Config.Language))
+	if report.Config.Profile != "" {
+		t.field("Profile", report.Config.Profile)
+	}
+	t.field("Verdict", t.verdict(report.Verdict, report.Reason))
+
+	for _, group := range groupFindings(report.Findings) {
+		first := group[0]
+		noun := "findings"
+		if len(group) == 1 {
+			noun = "finding"
+		}
+		t.printf("\n%s %s\n", t.paint(ansiBold, fmt.Sprintf("%s (%s)", first.Type, first.Code)), t.paint(ansiDim, fmt.Sprintf("%d %s", len(group), noun)))
+
+		for _, finding := range group {
+			severity := t.paint(severityColor(finding.Severity), fmt.Sprintf("%-7s", finding.Severity))
+			if loc := finding.Location; loc != nil {
+				timing := fmt.Sprintf("%s --> %s", utils.FormatTimestamp(loc.Start), utils.FormatTimestamp(loc.End))
+				t.printf("  %s  %s  %s  %q\n", severity, t.paint(ansiCyan, timing), t.paint(ansiDim, fmt.Sprintf("cue %d", loc.Cue)), loc.Excerpt)
+				t.printf("           %s\n", finding.Description)
+			} else {
+				t.printf("  %s  %s\n", severity, finding.Description)
+			}
+		}
+	}
+
+	if len(report.Findings) == 0 {
+		t.printf("\n%s\n", t.paint(ansiGreen, "No findings"))
+	}
+}
+
+// trackName describes a caption track by its ID, name and language
+func trackName(track *models.Track) string {
+	var details []string
+	for _, detail := range []string{track.Name, track.Language} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) == 0 {
+		return track.ID
+	}
+	return fmt.Sprintf("%s (%s)", track.ID, strings.Join(details, ", "))
+}
+
+// groupFindings buckets findings by code, in code order, keeping the
+// original order within each bucket
+func groupFindings(findings []models.ValidationError) [][]models.ValidationError {
+	index := make(map[string]int)
+	var groups [][]models.ValidationError
+	for _, finding := range findings {
+		i, ok := index[finding.Code]
+		if !ok {
+			i = len(groups)
+			index[finding.Code] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], finding)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i][0].Code < groups[j][0].Code
+	})
+	return groups
+}
+
+func severityColor(severity models.Severity) string {
+	switch severity {
+	case models.SeverityError:
+		return ansiRed
+	case models.SeverityWarning:
+		return ansiYellow
+	}
+	return ansiCyan
+}
+
+func (t *textWriter) verdict(v models.Verdict, reason string) string {
+	verdict := strings.ToUpper(string(v))
+	if reason != "" {
+		verdict += " - " + reason
+	}
+	switch v {
+	case models.VerdictPass:
+		return t.paint(ansiGreen+ansiBold, verdict)
+	case models.VerdictFail:
+		return t.paint(ansiRed+ansiBold, verdict)
+	}
+	return t.paint(ansiYellow+ansiBold, verdict)
+}
+
+func (t *textWriter) field(name, value string) {
+	t.printf("%s %s\n", t.paint(ansiBold, fmt.Sprintf("%-9s", name+":")), value)
+}
+
+func (t *textWriter) paint(code, s string) string {
+	if !t.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (t *textWriter) printf(format string, args ...interface{}) {
+	if t.err == nil {
+		_, t.err = fmt.Fprintf(t.w, format, args...)
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// FormatTimestamp renders a duration as HH:MM:SS.mmm
+func FormatTimestamp(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%s%02d:%02d:%02d.%03d", sign, ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// describeCue identifies a cue by its 1-based index and timing
+func describeCue(index int, start, end time.Duration) string {
+	return fmt.Sprintf("Cue %d (%s --> %s)", index+1, FormatTimestamp(start), FormatTimestamp(end))
+}
+
+// cueFinding builds a finding located at the cue with the given index
+func cueFinding(findingType, code string, severity models.Severity, index int, caption models.CaptionEntry, description string) models.ValidationError {
+	return models.ValidationError{
+		Type:        findingType,
+		Description: description,
+		Code:        code,
+		Severity:    severity,
+		Location: &models.Location{
+			Cue:     index + 1,
+			Start:   caption.StartTime,
+			End:     caption.EndTime,
+			Line:    caption.Line,
+			Excerpt: parse.TextPreview(caption.Text),
+		},
+	}
+}
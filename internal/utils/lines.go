@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// DefaultLineLimits applies to any language without its own entry
+var DefaultLineLimits = models.LineLimits{MaxCharsPerLine: 42, MaxLines: 2}
+
+// LanguageLineLimits holds the common style-guide limits for languages
+// that differ from DefaultLineLimits. CJK limits count full-width characters.
+var LanguageLineLimits = map[string]models.LineLimits{
+	"ja":      {MaxCharsPerLine: 13, MaxLines: 2},
+	"ko":      {MaxCharsPerLine: 16, MaxLines: 2},
+	"zh":      {MaxCharsPerLine: 16, MaxLines: 2},
+	"zh-hant": {MaxCharsPerLine: 16, MaxLines: 2},
+	"th":      {MaxCharsPerLine: 35, MaxLines: 2},
+}
+
+var markupRegex = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+
+// LineLimitsFor resolves the limits for a language tag such as "ja-JP".
+// The full tag is looked up before its primary subtag, and entries in
+// overrides take precedence over LanguageLineLimits. Limits left unset fall
+// back to defaults, such as those of a style-guide profile, and then to
+// DefaultLineLimits.
+func LineLimitsFor(lang string, defaults models.LineLimits, overrides map[string]models.LineLimits) models.LineLimits {
+	tag := strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	keys := []string{tag}
+	if i := strings.Index(tag, "-"); i > 0 {
+		keys = append(keys, tag[:i])
+	}
+
+	limits := MergeLineLimits(DefaultLineLimits, defaults)
+	for _, table := range []map[string]models.LineLimits{LanguageLineLimits, overrides} {
+		for _, key := range keys {
+			if entry, ok := table[key]; ok {
+				limits = MergeLineLimits(limits, entry)
+				break
+			}
+		}
+	}
+	return limits
+}
+
+// MergeLineLimits returns base with every limit set in override replaced
+func MergeLineLimits(base, override models.LineLimits) models.LineLimits {
+	if override.MaxCharsPerLine > 0 {
+		base.MaxCharsPerLine = override.MaxCharsPerLine
+	}
+	if override.MaxLines > 0 {
+		base.MaxLines = override.MaxLines
+	}
+	return base
+}
+
+// CountChars returns the number of visible characters in a caption line.
+// Formatting tags are ignored, every rune counts once regardless of its
+// byte length or display width, and combining marks add nothing.
+func CountChars(line string) int {
+	count := 0
+	for _, r := range markupRegex.ReplaceAllString(line, "") {
+		if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// ValidateLineLimits reports every cue that has more lines than allowed or
+// a line longer than allowed. Zero limits are not checked.
+func ValidateLineLimits(captions []models.CaptionEntry, limits models.LineLimits) []models.ValidationError {
+	return runCheck(NewLineLimitsCheck(limits), captions)
+}
+
+// NewLineLimitsCheck returns the incremental form of ValidateLineLimits
+func NewLineLimitsCheck(limits models.LineLimits) CueCheck {
+	return &cueCheck{check: func(i int, caption models.CaptionEntry) []models.ValidationError {
+		return checkLineLimits(i, caption, limits)
+	}}
+}
+
+func checkLineLimits(i int, caption models.CaptionEntry, limits models.LineLimits) []models.ValidationError {
+	var validationErrors []models.ValidationError
+	lines := caption.Lines
+	if lines == nil && strings.TrimSpace(caption.Text) != "" {
+		lines = []string{caption.Text}
+	}
+
+	if limits.MaxLines > 0 && len(lines) > limits.MaxLines {
+		validationErrors = append(validationErrors, cueFinding("too_many_lines", models.CodeTooManyLines, models.SeverityWarning, i, caption,
+			fmt.Sprintf("%s has %d lines, max %d: %q",
+				describeCue(i, caption.StartTime, caption.EndTime), len(lines), limits.MaxLines, parse.TextPreview(caption.Text))))
+	}
+
+	if limits.MaxCharsPerLine <= 0 {
+		return validationErrors
+	}
+	for n, line := range lines {
+		if chars := CountChars(line); chars > limits.MaxCharsPerLine {
+			finding := cueFinding("line_too_long", models.CodeLineTooLong, models.SeverityWarning, i, caption,
+				fmt.Sprintf("%s line %d has %d characters, max %d: %q",
+					describeCue(i, caption.StartTime, caption.EndTime), n+1, chars, limits.MaxCharsPerLine, parse.TextPreview(line)))
+			// Cue text starts on the line after the timing line
+			if caption.Line > 0 && caption.Lines != nil {
+				finding.Location.Line = caption.Line + 1 + n
+			}
+			validationErrors = append(validationErrors, finding)
+		}
+	}
+	return validationErrors
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// ApplySeverities replaces the default severity of each finding with the
+// override keyed by its code, or failing that by its type. Findings
+// overridden to "none" are dropped.
+func ApplySeverities(findings []models.ValidationError, overrides map[string]models.Severity) []models.ValidationError {
+	if len(overrides) == 0 {
+		return findings
+	}
+
+	var result []models.ValidationError
+	for _, finding := range findings {
+		severity, ok := overrides[finding.Code]
+		if !ok {
+			severity, ok = overrides[finding.Type]
+		}
+		if ok {
+			if severity == models.SeverityNone {
+				continue
+			}
+			finding.Severity = severity
+		}
+		result = append(result, finding)
+	}
+	return result
+}
+
+// FailsThreshold reports whether any finding is at or above failOn.
+// A threshold of "none" never fails.
+func FailsThreshold(findings []models.ValidationError, failOn models.Severity) bool {
+	if failOn.Rank() == 0 {
+		return false
+	}
+	for _, finding := range findings {
+		if finding.Severity.Rank() >= failOn.Rank() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package utils
+
+import "github.com/theCompanyDream/srt-test/internal/models"
+
+// CueCheck runs a rule over cues added one at a time, so that a caption
+// stream can be validated without holding every cue in memory
+type CueCheck interface {
+	// Add checks the cue with the given 0-based index. Cues must be added
+	// in input order.
+	Add(index int, caption models.CaptionEntry)
+	// Findings returns the findings of every cue added so far
+	Findings() []models.ValidationError
+}
+
+// cueCheck implements CueCheck with a function run on every cue. A nil
+// check finds nothing.
+type cueCheck struct {
+	check    func(index int, caption models.CaptionEntry) []models.ValidationError
+	findings []models.ValidationError
+}
+
+func (c *cueCheck) Add(index int, caption models.CaptionEntry) {
+	if c.check != nil {
+		c.findings = append(c.findings, c.check(index, caption)...)
+	}
+}
+
+func (c *cueCheck) Findings() []models.ValidationError {
+	return c.findings
+}
+
+// runCheck adds every caption to check and returns its findings
+func runCheck(check CueCheck, captions []models.CaptionEntry) []models.ValidationError {
+	for i, caption := range captions {
+		check.Add(i, caption)
+	}
+	return check.Findings()
+}
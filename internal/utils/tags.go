@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// tagRegex matches opening and closing formatting tags such as <i>, </b>,
+// <font color="red"> or the WebVTT <c.yellow> and <v Speaker> spans
+var tagRegex = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9]*)[^>]*>`)
+
+// CaptionTags returns the distinct tag names used in a cue, lower-cased
+func CaptionTags(text string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range tagRegex.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(match[1])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ValidateTags reports every cue using a formatting tag that is not in
+// allowed. A nil allowed list disables the check.
+func ValidateTags(captions []models.CaptionEntry, allowed []string) []models.ValidationError {
+	return runCheck(NewTagCheck(allowed), captions)
+}
+
+// NewTagCheck returns the incremental form of ValidateTags
+func NewTagCheck(allowed []string) CueCheck {
+	if allowed == nil {
+		return &cueCheck{}
+	}
+
+	permitted := make(map[string]bool)
+	for _, tag := range allowed {
+		permitted[strings.ToLower(strings.TrimSpace(tag))] = true
+	}
+
+	return &cueCheck{check: func(i int, caption models.CaptionEntry) []models.ValidationError {
+		var validationErrors []models.ValidationError
+		for _, tag := range CaptionTags(caption.Text) {
+			if permitted[tag] {
+				continue
+			}
+			validationErrors = append(validationErrors, cueFinding("tag_not_allowed", models.CodeTagNotAllowed, models.SeverityWarning, i, caption,
+				fmt.Sprintf("%s uses the <%s> tag, which is not allowed: %q",
+					describeCue(i, caption.StartTime, caption.EndTime), tag, parse.TextPreview(caption.Text))))
+		}
+		return validationErrors
+	}}
+}
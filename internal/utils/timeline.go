@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+// CoverageGaps returns the parts of [tStart, tEnd] where no cue is shown
+func CoverageGaps(captions []models.CaptionEntry, tStart, tEnd time.Duration) []models.Interval {
+	var spans []models.Interval
+	for _, caption := range captions {
+		start := MaxDuration(caption.StartTime, tStart)
+		end := MinDuration(caption.EndTime, tEnd)
+		if start < end {
+			spans = append(spans, models.Interval{Start: start, End: end})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	var gaps []models.Interval
+	cursor := tStart
+	for _, span := range spans {
+		if span.Start > cursor {
+			gaps = append(gaps, models.Interval{Start: cursor, End: span.Start})
+		}
+		cursor = MaxDuration(cursor, span.End)
+	}
+	if cursor < tEnd {
+		gaps = append(gaps, models.Interval{Start: cursor, End: tEnd})
+	}
+	return gaps
+}
+
+// Overlaps returns the spans where two or more cues are shown at once
+func Overlaps(captions []models.CaptionEntry) []models.Interval {
+	type event struct {
+		at    time.Duration
+		delta int
+	}
+	var events []event
+	for _, caption := range captions {
+		if caption.StartTime < caption.EndTime {
+			events = append(events, event{caption.StartTime, 1}, event{caption.EndTime, -1})
+		}
+	}
+	// Ends sort before starts at the same instant, so chained cues do not overlap
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].delta < events[j].delta
+	})
+
+	var overlaps []models.Interval
+	active := 0
+	var overlapStart time.Duration
+	for _, e := range events {
+		before := active
+		active += e.delta
+		if before < 2 && active >= 2 {
+			overlapStart = e.at
+		} else if before >= 2 && active < 2 && e.at > overlapStart {
+			overlaps = append(overlaps, models.Interval{Start: overlapStart, End: e.at})
+		}
+	}
+	return overlaps
+}
+
+// DetectLanguageWindows splits [tStart, tEnd] into windows of the given
+// size and detects the language of the cues starting in each one. Windows
+// without text are returned with no language.
+func DetectLanguageWindows(captions []models.CaptionEntry, tStart, tEnd, window time.Duration, endpoint, expected string) []models.LanguageWindow {
+	texts := NewWindowTexts(tStart, tEnd, window)
+	for _, caption := range captions {
+		texts.Add(caption)
+	}
+	return texts.Detect(endpoint, expected)
+}
+
+// MinLanguageWindow is the shortest language window accepted, below which
+// a window holds too little text to detect a language in
+const MinLanguageWindow = 10 * time.Second
+
+// MaxLanguageWindows caps the windows a range is split into, since each one
+// with text costs a detector request
+const MaxLanguageWindows = 1000
+
+// CheckLanguageWindow reports a language window that is too short, or that
+// splits [tStart, tEnd] into more than MaxLanguageWindows windows. A zero
+// window disables language windows and is always accepted.
+func CheckLanguageWindow(tStart, tEnd, window time.Duration) error {
+	if window <= 0 {
+		return nil
+	}
+	if window < MinLanguageWindow {
+		return fmt.Errorf("language window %v is shorter than the minimum of %v", window, MinLanguageWindow)
+	}
+	if windows := (tEnd - tStart + window - 1) / window; windows > MaxLanguageWindows {
+		return fmt.Errorf("language window %v splits %v into %d windows, more than the maximum of %d", window, tEnd-tStart, windows, MaxLanguageWindows)
+	}
+	return nil
+}
+
+// WindowTexts gathers the text of cues added one at a time into the
+// windows of a range, by the window each cue starts in
+type WindowTexts struct {
+	start, end, window time.Duration
+	parts              [][]string
+}
+
+// NewWindowTexts returns empty windows of the given size over [tStart, tEnd]
+func NewWindowTexts(tStart, tEnd, window time.Duration) *WindowTexts {
+	return &WindowTexts{start: tStart, end: tEnd, window: window}
+}
+
+// Add appends the text of a cue to the window it starts in
+func (w *WindowTexts) Add(caption models.CaptionEntry) {
+	if w.window <= 0 || caption.StartTime < w.start || caption.StartTime >= w.end || strings.TrimSpace(caption.Text) == "" {
+		return
+	}
+	i := int((caption.StartTime - w.start) / w.window)
+	for len(w.parts) <= i {
+		w.parts = append(w.parts, nil)
+	}
+	w.parts[i] = append(w.parts[i], caption.Text)
+}
+
+// Detect asks the endpoint for the language of every window that has text
+func (w *WindowTexts) Detect(endpoint, expected string) []models.LanguageWindow {
+	var windows []models.LanguageWindow
+	if w.window <= 0 {
+		return windows
+	}
+
+	for i, start := 0, w.start; start < w.end; i, start = i+1, start+w.window {
+		result := models.LanguageWindow{Start: start, End: MinDuration(start+w.window, w.end)}
+
+		if i < len(w.parts) && len(w.parts[i]) > 0 {
+			lang, err := DetectLanguage(strings.Join(w.parts[i], " "), endpoint)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Language = lang
+				result.Matches = LanguageMatches(lang, expected)
+			}
+		}
+		windows = append(windows, result)
+	}
+	return windows
+}
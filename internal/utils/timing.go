@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// ValidateCueDurations reports every cue displayed for less than minDuration
+// or longer than maxDuration. A zero bound is not checked.
+func ValidateCueDurations(captions []models.CaptionEntry, minDuration, maxDuration time.Duration) []models.ValidationError {
+	return runCheck(NewDurationCheck(minDuration, maxDuration), captions)
+}
+
+// NewDurationCheck returns the incremental form of ValidateCueDurations
+func NewDurationCheck(minDuration, maxDuration time.Duration) CueCheck {
+	return &cueCheck{check: func(i int, caption models.CaptionEntry) []models.ValidationError {
+		var validationErrors []models.ValidationError
+		duration := caption.EndTime - caption.StartTime
+
+		if minDuration > 0 && duration < minDuration {
+			validationErrors = append(validationErrors, cueFinding("cue_too_short", models.CodeCueTooShort, models.SeverityWarning, i, caption,
+				fmt.Sprintf("%s is displayed for %v, min %v: %q",
+					describeCue(i, caption.StartTime, caption.EndTime), duration, minDuration, parse.TextPreview(caption.Text))))
+		}
+
+		if maxDuration > 0 && duration > maxDuration {
+			validationErrors = append(validationErrors, cueFinding("cue_too_long", models.CodeCueTooLong, models.SeverityWarning, i, caption,
+				fmt.Sprintf("%s is displayed for %v, max %v: %q",
+					describeCue(i, caption.StartTime, caption.EndTime), duration, maxDuration, parse.TextPreview(caption.Text))))
+		}
+		return validationErrors
+	}}
+}
+
+// ValidateGaps reports consecutive cues that overlap or are separated by a
+// gap shorter than minGap. Chained cues with no gap at all are allowed, and
+// a zero minGap checks only for overlaps.
+func ValidateGaps(captions []models.CaptionEntry, minGap time.Duration) []models.ValidationError {
+	return runCheck(NewGapCheck(minGap), captions)
+}
+
+// NewGapCheck returns the incremental form of ValidateGaps. Only the
+// previous cue is kept.
+func NewGapCheck(minGap time.Duration) CueCheck {
+	var prev *models.CaptionEntry
+	return &cueCheck{check: func(i int, caption models.CaptionEntry) []models.ValidationError {
+		var validationErrors []models.ValidationError
+		if prev != nil {
+			gap := caption.StartTime - prev.EndTime
+
+			switch {
+			case gap < 0:
+				validationErrors = append(validationErrors, cueFinding("cue_overlap", models.CodeCueOverlap, models.SeverityWarning, i, caption,
+					fmt.Sprintf("%s overlaps the previous cue by %v: %q",
+						describeCue(i, caption.StartTime, caption.EndTime), -gap, parse.TextPreview(caption.Text))))
+			case minGap > 0 && gap > 0 && gap < minGap:
+				validationErrors = append(validationErrors, cueFinding("gap_too_short", models.CodeGapTooShort, models.SeverityWarning, i, caption,
+					fmt.Sprintf("%s starts %v after the previous cue, min gap %v: %q",
+						describeCue(i, caption.StartTime, caption.EndTime), gap, minGap, parse.TextPreview(caption.Text))))
+			}
+		}
+		prev = &caption
+		return validationErrors
+	}}
+}
+
+// ReadingSpeed returns the characters per second needed to read a cue
+func ReadingSpeed(caption models.CaptionEntry) float64 {
+	duration := caption.EndTime - caption.StartTime
+	if duration <= 0 {
+		return 0
+	}
+
+	lines := caption.Lines
+	if lines == nil {
+		lines = []string{caption.Text}
+	}
+	chars := 0
+	for _, line := range lines {
+		chars += CountChars(line)
+	}
+	return float64(chars) / duration.Seconds()
+}
+
+// ValidateReadingSpeed reports every cue that needs more than maxCPS
+// characters per second to read
+func ValidateReadingSpeed(captions []models.CaptionEntry, maxCPS float64) []models.ValidationError {
+	return runCheck(NewReadingSpeedCheck(maxCPS), captions)
+}
+
+// NewReadingSpeedCheck returns the incremental form of ValidateReadingSpeed
+func NewReadingSpeedCheck(maxCPS float64) CueCheck {
+	if maxCPS <= 0 {
+		return &cueCheck{}
+	}
+
+	return &cueCheck{check: func(i int, caption models.CaptionEntry) []models.ValidationError {
+		if cps := ReadingSpeed(caption); cps > maxCPS {
+			return []models.ValidationError{cueFinding("reading_speed_too_high", models.CodeReadingSpeed, models.SeverityWarning, i, caption,
+				fmt.Sprintf("%s needs %.1f characters per second, max %.1f: %q",
+					describeCue(i, caption.StartTime, caption.EndTime), cps, maxCPS, parse.TextPreview(caption.Text)))}
+		}
+		return nil
+	}}
+}
@@ -5,73 +5,112 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/theCompanyDream/srt-test/internal/models"
 )
 
-func IsValidFileType(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	return ext == ".vtt" || ext == ".srt"
+// Coverage sums the time of a range shown by cues added one at a time.
+// Time shown by overlapping cues counts once per cue.
+type Coverage struct {
+	start, end time.Duration
+	covered    time.Duration
 }
 
-func ValidateCoverage(captions []models.CaptionEntry, tStart, tEnd time.Duration, requiredCoverage float64) bool {
-	totalRange := tEnd - tStart
-	if totalRange <= 0 {
-		return false
-	}
+// NewCoverage returns an empty coverage sum for [tStart, tEnd]
+func NewCoverage(tStart, tEnd time.Duration) *Coverage {
+	return &Coverage{start: tStart, end: tEnd}
+}
+
+// Add counts the part of the range a cue is shown for
+func (c *Coverage) Add(caption models.CaptionEntry) {
+	// Calculate overlap with the specified range
+	overlapStart := MaxDuration(caption.StartTime, c.start)
+	overlapEnd := MinDuration(caption.EndTime, c.end)
 
-	var coveredDuration time.Duration
-	for _, caption := range captions {
-		// Calculate overlap with the specified range
-		overlapStart := MaxDuration(caption.StartTime, tStart)
-		overlapEnd := MinDuration(caption.EndTime, tEnd)
+	if overlapStart < overlapEnd {
+		c.covered += overlapEnd - overlapStart
+	}
+}
 
-		if overlapStart < overlapEnd {
-			coveredDuration += overlapEnd - overlapStart
-		}
+// Meets reports whether the range is covered at least to the required
+// fraction. An empty range is never covered.
+func (c *Coverage) Meets(requiredCoverage float64) bool {
+	if c.end-c.start <= 0 {
+		return false
 	}
+	return c.Ratio() >= requiredCoverage
+}
 
-	actualCoverage := float64(coveredDuration) / float64(totalRange)
-	return actualCoverage >= requiredCoverage
+// Ratio returns the fraction of the range covered so far
+func (c *Coverage) Ratio() float64 {
+	totalRange := c.end - c.start
+	if totalRange <= 0 {
+		return 0
+	}
+	return float64(c.covered) / float64(totalRange)
 }
 
-func ValidateLanguage(text, endpoint string) bool {
+// ValidateLanguageFor checks that the endpoint detects the expected language
+func ValidateLanguageFor(text, endpoint, expected string) bool {
 	if text == "" {
 		return false
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Post(endpoint, "text/plain", strings.NewReader(text))
+	lang, err := DetectLanguage(text, endpoint)
 	if err != nil {
 		return false
 	}
+	return LanguageMatches(lang, expected)
+}
+
+// detectorClient is shared by every detection request so that connections
+// to the endpoint are reused across cues, windows and files
+var detectorClient = newDetectorClient()
+
+func newDetectorClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 64
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
+
+// DetectLanguage asks the endpoint for the language of text. An error means
+// the detector could not be reached or gave an unusable answer.
+func DetectLanguage(text, endpoint string) (string, error) {
+	resp, err := detectorClient.Post(endpoint, "text/plain", strings.NewReader(text))
+	if err != nil {
+		return "", err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false
+		return "", fmt.Errorf("language detection endpoint returned %s", resp.Status)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return false
+		return "", err
 	}
 
 	var langResp models.LangResponse
 	if err := json.Unmarshal(body, &langResp); err != nil {
-		return false
+		return "", fmt.Errorf("invalid language detection response: %v", err)
 	}
-
-	return langResp.Lang == "en-US"
+	return langResp.Lang, nil
 }
 
-func PrintValidationError(errorType, description string) {
-	validationError := models.ValidationError{
-		Type:        errorType,
-		Description: description,
+// LanguageMatches reports whether a detected language tag is the expected
+// one. Case and the separator are ignored, and an expected language
+// without a region, such as "en", matches any region of it.
+func LanguageMatches(detected, expected string) bool {
+	normalize := func(tag string) string {
+		return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	}
+	detected, expected = normalize(detected), normalize(expected)
+	if detected == expected {
+		return true
 	}
-	jsonBytes, _ := json.Marshal(validationError)
-	fmt.Println(string(jsonBytes))
+	primary, _, _ := strings.Cut(detected, "-")
+	return !strings.Contains(expected, "-") && primary == expected
 }
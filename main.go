@@ -1,57 +1,106 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/theCompanyDream/srt-test/internal/cmd"
 	"github.com/theCompanyDream/srt-test/internal/models"
-	"github.com/theCompanyDream/srt-test/internal/parse"
-	"github.com/theCompanyDream/srt-test/internal/utils"
+	"github.com/theCompanyDream/srt-test/internal/report"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run validates the caption file and returns the process exit code.
+// Whenever the code is not cmd.ExitPass a reason is printed on stderr.
+func run() int {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		return cmd.RunConfigCommand(os.Args[2:], os.Stdout, os.Stderr)
+	}
+
 	config, err := cmd.ParseFlags()
+	if errors.Is(err, flag.ErrHelp) {
+		return cmd.ExitPass
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
-		os.Exit(1)
+		return cmd.ExitUsage
 	}
 
-	// Validate file type
-	if !utils.IsValidFileType(config.FilePath) {
-		os.Exit(1)
+	if cmd.IsBatch(config.Inputs) {
+		return runBatch(config)
 	}
 
-	// Parse caption file
-	captions, err := parse.ParseCaptionFile(config.FilePath)
-	if err != nil {
-		utils.PrintValidationError("file_parse_error", fmt.Sprintf("Failed to parse caption file: %v", err))
-		os.Exit(0)
+	results := cmd.ValidateTracks(config)
+	if len(results) > 1 {
+		// Each track of the file is reported like a file of a batch
+		if config.HTMLPath != "" {
+			fmt.Fprintln(os.Stderr, "Error: --html requires a single caption track; choose one with --track")
+			return cmd.ExitUsage
+		}
+		return writeBatch(config, cmd.CombineReports(results))
+	}
+	result := results[0]
+	if result.ExitCode == cmd.ExitUsage {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", result.Reason)
+		return result.ExitCode
+	}
+	if err := report.Write(os.Stdout, config.Format, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		return cmd.ExitOutputFailed
 	}
 
-	var validationErrors []models.ValidationError
+	if config.HTMLPath != "" {
+		if err := report.WriteHTMLFile(config.HTMLPath, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing HTML report: %v\n", err)
+			return cmd.ExitOutputFailed
+		}
+	}
+
+	switch result.ExitCode {
+	case cmd.ExitPass:
+	case cmd.ExitValidationFailed:
+		fmt.Fprintf(os.Stderr, "Validation failed: %s\n", result.Reason)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", result.Reason)
+	}
+	return result.ExitCode
+}
 
-	// Validate coverage
-	if !utils.ValidateCoverage(captions, config.TStart, config.TEnd, config.Coverage) {
-		validationErrors = append(validationErrors, models.ValidationError{
-			Type:        "insufficient_coverage",
-			Description: fmt.Sprintf("Captions do not cover required %.1f%% of time range %v to %v", config.Coverage*100, config.TStart, config.TEnd),
-		})
+// runBatch validates every file named by the --file values and writes one
+// combined report
+func runBatch(config *models.Config) int {
+	if config.HTMLPath != "" {
+		fmt.Fprintln(os.Stderr, "Error parsing flags: --html requires a single caption file")
+		return cmd.ExitUsage
 	}
 
-	// Extract and validate language
-	allText := parse.ExtractAllText(captions)
-	if !utils.ValidateLanguage(allText, config.Endpoint) {
-		validationErrors = append(validationErrors, models.ValidationError{
-			Type:        "invalid_language",
-			Description: "Caption language is not en-US or language detection failed",
-		})
+	files, err := cmd.ExpandInputs(config.Inputs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cmd.ExitInputUnreadable
 	}
 
-	// Print validation errors
-	for _, err := range validationErrors {
-		utils.PrintValidationError(err.Type, err.Description)
+	return writeBatch(config, cmd.ValidateBatch(config, files))
+}
+
+// writeBatch writes a combined report and returns its exit code
+func writeBatch(config *models.Config, batch *models.BatchReport) int {
+	if err := report.WriteBatch(os.Stdout, config.Format, batch); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		return cmd.ExitOutputFailed
 	}
 
-	os.Exit(0)
+	switch batch.ExitCode {
+	case cmd.ExitPass:
+	case cmd.ExitValidationFailed:
+		fmt.Fprintf(os.Stderr, "Validation failed: %s\n", batch.Reason)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", batch.Reason)
+	}
+	return batch.ExitCode
 }
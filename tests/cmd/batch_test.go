@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/cmd"
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+const batchSRT = `1
+00:00:00,000 --> 00:00:04,000
+Hello and welcome
+`
+
+func TestIsBatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.srt")
+	require.NoError(t, os.WriteFile(file, []byte(batchSRT), 0o644))
+
+	assert.False(t, cmd.IsBatch([]string{file}))
+	assert.False(t, cmd.IsBatch([]string{filepath.Join(dir, "missing.srt")}))
+	assert.True(t, cmd.IsBatch([]string{file, file}))
+	assert.True(t, cmd.IsBatch([]string{dir}))
+	assert.True(t, cmd.IsBatch([]string{filepath.Join(dir, "*.srt")}))
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.srt", "b.vtt", "notes.txt", "season1/e1.srt", "season1/e2.vtt", "season2/e1.SRT", "media/film.mkv", "media/subs.m3u8", "media/transcript.json"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(batchSRT), 0o644))
+	}
+
+	t.Run("directories are searched recursively for caption files", func(t *testing.T) {
+		files, err := cmd.ExpandInputs([]string{filepath.Join(dir, "season1"), filepath.Join(dir, "season2")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "season1", "e1.srt"),
+			filepath.Join(dir, "season1", "e2.vtt"),
+			filepath.Join(dir, "season2", "e1.SRT"),
+		}, files)
+	})
+
+	t.Run("directories include containers and playlists but not generic JSON", func(t *testing.T) {
+		files, err := cmd.ExpandInputs([]string{filepath.Join(dir, "media")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "media", "film.mkv"),
+			filepath.Join(dir, "media", "subs.m3u8"),
+		}, files)
+	})
+
+	t.Run("globs are expanded and duplicates dropped", func(t *testing.T) {
+		files, err := cmd.ExpandInputs([]string{filepath.Join(dir, "*.srt"), filepath.Join(dir, "a.srt"), filepath.Join(dir, "*")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "a.srt"),
+			filepath.Join(dir, "b.vtt"),
+			filepath.Join(dir, "notes.txt"),
+		}, files)
+	})
+
+	t.Run("plain paths are kept even when missing", func(t *testing.T) {
+		files, err := cmd.ExpandInputs([]string{"missing.srt"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"missing.srt"}, files)
+	})
+
+	t.Run("a glob without matches is an error", func(t *testing.T) {
+		_, err := cmd.ExpandInputs([]string{filepath.Join(dir, "*.ttml")})
+		assert.ErrorContains(t, err, "no files match")
+	})
+
+	t.Run("a directory without caption files is an error", func(t *testing.T) {
+		_, err := cmd.ExpandInputs([]string{t.TempDir()})
+		assert.ErrorContains(t, err, "no caption files found")
+	})
+}
+
+func TestValidateBatch(t *testing.T) {
+	var requests, active, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		json.NewEncoder(w).Encode(models.LangResponse{Lang: "en-US"})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.srt", "b.srt", "c.srt", "d.srt", "e.srt"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(batchSRT), 0o644))
+		files = append(files, path)
+	}
+	files = append(files, filepath.Join(dir, "missing.srt"))
+
+	config := &models.Config{
+		TEnd:     4 * time.Second,
+		Endpoint: server.URL,
+		Language: "en-US",
+		Rules:    models.Rules{Coverage: 0.8},
+		FailOn:   models.SeverityError,
+		Jobs:     2,
+	}
+	batch := cmd.ValidateBatch(config, files)
+
+	require.Len(t, batch.Files, len(files))
+	for i, report := range batch.Files {
+		assert.Equal(t, files[i], report.File)
+	}
+	assert.Equal(t, models.BatchTotals{Files: 6, Passed: 5, Errored: 1, Findings: 1}, batch.Totals)
+	assert.Equal(t, models.VerdictError, batch.Verdict)
+	assert.Equal(t, cmd.ExitInputUnreadable, batch.ExitCode)
+	assert.Equal(t, "1 of 6 files could not be validated", batch.Reason)
+
+	assert.Equal(t, int32(5), atomic.LoadInt32(&requests))
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+}
+
+func TestCombineReports(t *testing.T) {
+	batch := cmd.CombineReports([]*models.Report{
+		{Verdict: models.VerdictPass, ExitCode: cmd.ExitPass},
+		{Verdict: models.VerdictFail, ExitCode: cmd.ExitValidationFailed, Findings: []models.ValidationError{{}, {}}},
+	})
+	assert.Equal(t, models.BatchTotals{Files: 2, Passed: 1, Failed: 1, Findings: 2}, batch.Totals)
+	assert.Equal(t, models.VerdictFail, batch.Verdict)
+	assert.Equal(t, cmd.ExitValidationFailed, batch.ExitCode)
+	assert.Equal(t, "1 of 2 files failed validation", batch.Reason)
+
+	batch = cmd.CombineReports([]*models.Report{
+		{Verdict: models.VerdictError, ExitCode: cmd.ExitDetectorUnavailable},
+		{Verdict: models.VerdictError, ExitCode: cmd.ExitInputUnreadable},
+		{Verdict: models.VerdictFail, ExitCode: cmd.ExitValidationFailed},
+	})
+	assert.Equal(t, cmd.ExitDetectorUnavailable, batch.ExitCode)
+	assert.Equal(t, "2 of 3 files could not be validated, 1 failed validation", batch.Reason)
+
+	assert.Equal(t, models.VerdictPass, cmd.CombineReports(nil).Verdict)
+}
+
+func TestParseArgs_MultipleFiles(t *testing.T) {
+	config, err := cmd.ParseArgs([]string{"--file=a.srt", "--file=dir", "--end=10s", "--endpoint=http://x", "--jobs=3"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.srt", "dir"}, config.Inputs)
+	assert.Equal(t, "a.srt", config.FilePath)
+	assert.Equal(t, 3, config.Jobs)
+
+	path := writeFile(t, "config.yaml", "file: [x.srt, 'captions/*.vtt']\nend: 10s\nendpoint: http://x\njobs: 4\n")
+	config, err = cmd.ParseArgs([]string{"--config", path})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x.srt", "captions/*.vtt"}, config.Inputs)
+	assert.Equal(t, 4, config.Jobs)
+
+	_, err = cmd.ParseArgs([]string{"--file=a.srt", "--end=10s", "--endpoint=http://x", "--jobs=0"})
+	assert.ErrorContains(t, err, "jobs must be at least 1")
+}
+
+func TestParseArgs_InputSources(t *testing.T) {
+	config, err := cmd.ParseArgs([]string{"--file=-", "--input-format=VTT", "--end=10s", "--endpoint=http://x", "--max-input-size=2MB", "--fetch-timeout=5s"})
+	require.NoError(t, err)
+	assert.Equal(t, "vtt", config.InputFormat)
+	assert.Equal(t, int64(2<<20), config.MaxInputSize)
+	assert.Equal(t, 5*time.Second, config.FetchTimeout)
+
+	_, err = cmd.ParseArgs([]string{"--file=a.txt", "--input-format=docx", "--end=10s", "--endpoint=http://x"})
+	assert.ErrorContains(t, err, "unsupported input format")
+
+	_, err = cmd.ParseArgs([]string{"--file=a.srt", "--end=48h", "--endpoint=http://x", "--language-window=1m"})
+	assert.ErrorContains(t, err, "more than the maximum of 1000")
+
+	// The duration of container files stands in for the end time
+	config, err = cmd.ParseArgs([]string{"--file=film.mkv", "--file=clip.MP4", "--endpoint=http://x", "--track=fr"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), config.TEnd)
+	assert.Equal(t, "fr", config.Track)
+	_, err = cmd.ParseArgs([]string{"--file=film.mkv", "--file=a.srt", "--endpoint=http://x"})
+	assert.ErrorContains(t, err, "end time is required")
+
+	assert.False(t, cmd.IsBatch([]string{"https://bucket.example.com/a.srt?X-Amz-Signature=abc"}))
+	assert.False(t, cmd.IsBatch([]string{"-"}))
+}
+
+func TestParseByteSize(t *testing.T) {
+	for value, expected := range map[string]int64{"100": 100, "512KB": 512 << 10, "10mb": 10 << 20, "1 GB": 1 << 30, "7B": 7} {
+		size, err := cmd.ParseByteSize(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+	_, err := cmd.ParseByteSize("lots")
+	assert.Error(t, err)
+
+	// Sizes that overflow are rejected rather than wrapped
+	_, err = cmd.ParseByteSize("99999999999GB")
+	assert.EqualError(t, err, `size "99999999999GB" is too large`)
+	size, err := cmd.ParseByteSize("8589934591GB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8589934591)<<30, size)
+
+	_, err = cmd.ParseArgs([]string{"--file=-", "--end=10s", "--endpoint=http://x", "--max-input-size=99999999999GB"})
+	assert.ErrorContains(t, err, "invalid max input size")
+}
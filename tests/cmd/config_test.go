@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/cmd"
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+func TestParseArgs_ConfigFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+file: movie.srt
+start: 30s
+end: 10m
+endpoint: http://localhost:8080/detect
+lang: de-DE
+profile: house
+rules:
+  coverage: 0.85
+  min_gap: 100ms
+profiles:
+  house:
+    extends: broadcast
+    max_cps: 16
+`)
+
+	t.Run("file values are used", func(t *testing.T) {
+		config, err := cmd.ParseArgs([]string{"--config", path})
+		require.NoError(t, err)
+		assert.Equal(t, "movie.srt", config.FilePath)
+		assert.Equal(t, 30*time.Second, config.TStart)
+		assert.Equal(t, 10*time.Minute, config.TEnd)
+		assert.Equal(t, "http://localhost:8080/detect", config.Endpoint)
+		assert.Equal(t, "de-DE", config.Language)
+		assert.Equal(t, "house", config.Profile)
+		assert.Equal(t, 0.85, config.Rules.Coverage)
+		assert.Equal(t, 16.0, config.Rules.MaxCPS)
+		assert.Equal(t, 100*time.Millisecond, config.Rules.MinGap)
+		assert.Equal(t, 37, config.Rules.MaxCharsPerLine)
+	})
+
+	t.Run("flags override file values", func(t *testing.T) {
+		config, err := cmd.ParseArgs([]string{"--config", path, "--file=other.vtt", "--end=20m", "--coverage=0.5", "--profile=streaming-kids"})
+		require.NoError(t, err)
+		assert.Equal(t, "other.vtt", config.FilePath)
+		assert.Equal(t, 20*time.Minute, config.TEnd)
+		assert.Equal(t, 0.5, config.Rules.Coverage)
+		assert.Equal(t, 17.0, config.Rules.MaxCPS)
+		assert.Equal(t, 100*time.Millisecond, config.Rules.MinGap)
+	})
+
+	t.Run("fail-on and severities", func(t *testing.T) {
+		severityPath := writeFile(t, "severity.yaml", "end: 1m\nendpoint: http://x\nfile: a.srt\nfail_on: warning\nrules:\n  severities:\n    CV401: error\n")
+		config, err := cmd.ParseArgs([]string{"--config", severityPath})
+		require.NoError(t, err)
+		assert.Equal(t, models.SeverityWarning, config.FailOn)
+		assert.Equal(t, models.SeverityError, config.Rules.Severities[models.CodeLineTooLong])
+
+		config, err = cmd.ParseArgs([]string{"--config", severityPath, "--fail-on=None"})
+		require.NoError(t, err)
+		assert.Equal(t, models.SeverityNone, config.FailOn)
+
+		_, err = cmd.ParseArgs([]string{"--config", severityPath, "--fail-on=fatal"})
+		assert.ErrorContains(t, err, "invalid fail-on threshold")
+	})
+
+	t.Run("json config", func(t *testing.T) {
+		jsonPath := writeFile(t, "config.json", `{"file": "a.vtt", "end": "1m", "endpoint": "http://x", "rules": {"max_duration": "6s"}}`)
+		config, err := cmd.ParseArgs([]string{"--config", jsonPath})
+		require.NoError(t, err)
+		assert.Equal(t, "a.vtt", config.FilePath)
+		assert.Equal(t, 6*time.Second, config.Rules.MaxDuration)
+	})
+
+	t.Run("unknown keys are errors", func(t *testing.T) {
+		typoPath := writeFile(t, "typo.yaml", "file: a.srt\nend: 1m\nendpoint: http://x\nformt: text\nrules:\n  covrage: 0.5\n")
+		_, err := cmd.ParseArgs([]string{"--config", typoPath})
+		assert.EqualError(t, err, "invalid config file "+typoPath+`: line 4: unknown key "formt"; line 6: unknown key "covrage"`)
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		_, err := cmd.ParseArgs([]string{"--config", "does-not-exist.yaml"})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		path := writeFile(t, "ok.yaml", "end: 5m\nprofile: broadcast\nrules:\n  max_cps: 15\n")
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("unknown keys at every level", func(t *testing.T) {
+		path := writeFile(t, "bad.yaml", `
+end: 5m
+endpiont: http://typo
+rules:
+  max_cpss: 15
+profiles:
+  house:
+    extend: broadcast
+`)
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`line 3: unknown key "endpiont"`,
+			`line 5: unknown key "max_cpss"`,
+			`line 8: unknown key "extend"`,
+		}, problems)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		path := writeFile(t, "values.yaml", "start: soon\nfps: fast\nasr_mapping: units=min\nrules:\n  coverage: 2\n")
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`invalid start time format: time: invalid duration "soon"`,
+			`invalid fps: invalid frame rate "fast"`,
+			`invalid asr_mapping: invalid units "min" (expected s or ms)`,
+			"coverage must be between 0.0 and 1.0",
+		}, problems)
+	})
+
+	t.Run("values a run rejects", func(t *testing.T) {
+		path := writeFile(t, "run.yaml", "file: a.srt\nendpoint: http://x\nstart: 20s\nend: 10s\nlanguage_window: 1s\nmax_line_size: \"0\"\nfetch_timeout: -1s\n")
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"start time must be less than end time",
+			"language window 1s is shorter than the minimum of 10s",
+			`invalid max line size "0"`,
+			`invalid fetch timeout "-1s"`,
+		}, problems)
+
+		_, err = cmd.ParseArgs([]string{"--config", path})
+		assert.EqualError(t, err, "start time must be less than end time")
+	})
+
+	t.Run("invalid severities", func(t *testing.T) {
+		path := writeFile(t, "severity.yaml", "fail_on: fatal\nrules:\n  severities:\n    CV401: critical\n")
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		require.Len(t, problems, 2)
+		assert.Contains(t, problems[0], `unknown severity "critical"`)
+		assert.Contains(t, problems[1], "invalid fail_on threshold")
+	})
+
+	t.Run("syntax error is reported once", func(t *testing.T) {
+		path := writeFile(t, "syntax.yaml", "end: [5m\n")
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		assert.Len(t, problems, 1)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		path := writeFile(t, "profile.yaml", "profiles:\n  house:\n    extends: cinema\n")
+		problems, err := cmd.ValidateConfigFile(path)
+		require.NoError(t, err)
+		assert.Len(t, problems, 1)
+		assert.Contains(t, problems[0], `unknown profile "cinema"`)
+	})
+}
+
+func TestRunConfigCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	path := writeFile(t, "ok.yaml", "end: 5m\n")
+
+	assert.Equal(t, cmd.ExitPass, cmd.RunConfigCommand([]string{"validate", path}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "OK")
+
+	stderr.Reset()
+	assert.Equal(t, cmd.ExitUsage, cmd.RunConfigCommand([]string{"check"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "usage")
+}
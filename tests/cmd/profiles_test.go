@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/cmd"
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestResolveProfile_Builtin(t *testing.T) {
+	rules, err := cmd.ResolveProfile("broadcast", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, rules.Coverage)
+	assert.Equal(t, 37, rules.MaxCharsPerLine)
+	assert.Equal(t, []string{"i"}, rules.AllowedTags)
+
+	rules, err = cmd.ResolveProfile("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, cmd.DefaultRules(), rules)
+
+	_, err = cmd.ResolveProfile("cinema", nil)
+	assert.ErrorContains(t, err, `unknown profile "cinema"`)
+}
+
+func TestResolveProfile_Custom(t *testing.T) {
+	path := writeFile(t, "profiles.yaml", `
+profiles:
+  broadcast:
+    max_cps: 15
+    language_line_limits:
+      ja: {max_chars_per_line: 12}
+  platform-x:
+    extends: streaming-kids
+    max_chars_per_line: 32
+    allowed_tags: []
+  platform-y:
+    extends: platform-x
+    min_gap: 120ms
+  scratch:
+    coverage: 0.5
+  loop-a:
+    extends: loop-b
+  loop-b:
+    extends: loop-a
+`)
+	custom, err := cmd.LoadProfiles(path)
+	require.NoError(t, err)
+
+	t.Run("overriding a built-in keeps its other rules", func(t *testing.T) {
+		rules, err := cmd.ResolveProfile("broadcast", custom)
+		require.NoError(t, err)
+		assert.Equal(t, 15.0, rules.MaxCPS)
+		assert.Equal(t, 0.9, rules.Coverage)
+		assert.Equal(t, models.LineLimits{MaxCharsPerLine: 12}, rules.LanguageLineLimits["ja"])
+	})
+
+	t.Run("extending a profile", func(t *testing.T) {
+		rules, err := cmd.ResolveProfile("platform-y", custom)
+		require.NoError(t, err)
+		assert.Equal(t, 17.0, rules.MaxCPS)
+		assert.Equal(t, 32, rules.MaxCharsPerLine)
+		assert.Equal(t, 120*time.Millisecond, rules.MinGap)
+		assert.NotNil(t, rules.AllowedTags)
+		assert.Empty(t, rules.AllowedTags)
+	})
+
+	t.Run("new profile starts from the defaults", func(t *testing.T) {
+		rules, err := cmd.ResolveProfile("scratch", custom)
+		require.NoError(t, err)
+		assert.Equal(t, 0.5, rules.Coverage)
+		assert.Equal(t, cmd.DefaultRules().MinDuration, rules.MinDuration)
+	})
+
+	t.Run("extends cycle is an error", func(t *testing.T) {
+		_, err := cmd.ResolveProfile("loop-a", custom)
+		assert.ErrorContains(t, err, "extends itself")
+	})
+
+	t.Run("built-in profiles are not modified", func(t *testing.T) {
+		assert.Equal(t, 17.0, cmd.BuiltinProfiles["broadcast"].MaxCPS)
+		assert.Nil(t, cmd.BuiltinProfiles["broadcast"].LanguageLineLimits)
+	})
+}
+
+func TestParseArgs_ProfilePrecedence(t *testing.T) {
+	base := []string{"--file=movie.srt", "--end=5m", "--endpoint=http://localhost"}
+
+	t.Run("defaults without profile", func(t *testing.T) {
+		config, err := cmd.ParseArgs(base)
+		require.NoError(t, err)
+		assert.Equal(t, cmd.DefaultRules(), config.Rules)
+	})
+
+	t.Run("flags override the profile", func(t *testing.T) {
+		config, err := cmd.ParseArgs(append(base, "--profile=broadcast", "--coverage=0.95", "--allowed-tags=i,B", "--line-limits=ja=14:2"))
+		require.NoError(t, err)
+		assert.Equal(t, "broadcast", config.Profile)
+		assert.Equal(t, 0.95, config.Rules.Coverage)
+		assert.Equal(t, 17.0, config.Rules.MaxCPS)
+		assert.Equal(t, []string{"i", "b"}, config.Rules.AllowedTags)
+		assert.Equal(t, models.LineLimits{MaxCharsPerLine: 14, MaxLines: 2}, config.Rules.LanguageLineLimits["ja"])
+	})
+
+	t.Run("invalid rules are rejected", func(t *testing.T) {
+		_, err := cmd.ParseArgs(append(base, "--min-duration=8s"))
+		assert.ErrorContains(t, err, "minimum cue duration must not exceed maximum")
+	})
+}
@@ -0,0 +1,529 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/cmd"
+	"github.com/theCompanyDream/srt-test/internal/models"
+)
+
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "caption-validator")
+	if err != nil {
+		panic(err)
+	}
+	binary = filepath.Join(dir, "caption-validator")
+	build := exec.Command("go", "build", "-o", binary, "../..")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+const validSRT = `1
+00:00:00,000 --> 00:00:04,000
+Hello and welcome
+
+2
+00:00:04,000 --> 00:00:08,000
+to the show
+`
+
+func writeCaptions(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func languageServer(t *testing.T, lang string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.LangResponse{Lang: lang})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// runValidator runs the binary and returns its exit code, stdout and stderr
+func runValidator(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	command := exec.Command(binary, args...)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := command.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), stdout.String(), stderr.String()
+	}
+	require.NoError(t, err)
+	return 0, stdout.String(), stderr.String()
+}
+
+func TestExitCodes(t *testing.T) {
+	srt := writeCaptions(t, "ok.srt", validSRT)
+	english := languageServer(t, "en-US")
+
+	t.Run("pass", func(t *testing.T) {
+		code, stdout, _ := runValidator(t, "--file", srt, "--end=8s", "--endpoint", english)
+		assert.Equal(t, cmd.ExitPass, code)
+		assert.Empty(t, stdout)
+	})
+
+	t.Run("validation failed", func(t *testing.T) {
+		code, stdout, stderr := runValidator(t, "--file", srt, "--end=1m", "--endpoint", english)
+		assert.Equal(t, cmd.ExitValidationFailed, code)
+		assert.Contains(t, stdout, "insufficient_coverage")
+		assert.Contains(t, stderr, "Validation failed")
+	})
+
+	t.Run("warnings below fail-on pass", func(t *testing.T) {
+		code, stdout, _ := runValidator(t, "--file", srt, "--end=8s", "--endpoint", english, "--max-duration=3s")
+		assert.Equal(t, cmd.ExitPass, code)
+		assert.Contains(t, stdout, "cue_too_long")
+
+		code, _, _ = runValidator(t, "--file", srt, "--end=8s", "--endpoint", english, "--max-duration=3s", "--fail-on=warning")
+		assert.Equal(t, cmd.ExitValidationFailed, code)
+	})
+
+	t.Run("wrong language", func(t *testing.T) {
+		code, stdout, _ := runValidator(t, "--file", srt, "--end=8s", "--endpoint", languageServer(t, "fr-FR"))
+		assert.Equal(t, cmd.ExitValidationFailed, code)
+		assert.Contains(t, stdout, "invalid_language")
+	})
+
+	t.Run("usage error", func(t *testing.T) {
+		code, _, stderr := runValidator(t, "--file", srt)
+		assert.Equal(t, cmd.ExitUsage, code)
+		assert.Contains(t, stderr, "end time is required")
+	})
+
+	t.Run("unsupported file type", func(t *testing.T) {
+		txt := writeCaptions(t, "notes.txt", "hello")
+		code, _, stderr := runValidator(t, "--file", txt, "--end=8s", "--endpoint", english)
+		assert.Equal(t, cmd.ExitInputUnreadable, code)
+		assert.Contains(t, stderr, "unsupported caption file type")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		code, _, stderr := runValidator(t, "--file", filepath.Join(t.TempDir(), "missing.srt"), "--end=8s", "--endpoint", english)
+		assert.Equal(t, cmd.ExitInputUnreadable, code)
+		assert.Contains(t, stderr, "failed to read caption file")
+	})
+
+	t.Run("detector unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		code, stdout, stderr := runValidator(t, "--file", srt, "--end=8s", "--endpoint", server.URL)
+		assert.Equal(t, cmd.ExitDetectorUnavailable, code)
+		assert.Contains(t, stdout, "language_detection_failed")
+		assert.Contains(t, stderr, "language detection endpoint unavailable")
+	})
+
+	t.Run("output failed", func(t *testing.T) {
+		html := filepath.Join(t.TempDir(), "missing", "report.html")
+		code, _, stderr := runValidator(t, "--file", srt, "--end=8s", "--endpoint", english, "--html", html)
+		assert.Equal(t, cmd.ExitOutputFailed, code)
+		assert.Contains(t, stderr, "Error writing HTML report")
+	})
+}
+
+func TestJSONReport(t *testing.T) {
+	srt := writeCaptions(t, "ok.srt", validSRT)
+	code, stdout, _ := runValidator(t, "--file", srt, "--end=16s", "--endpoint", languageServer(t, "en-US"), "--format=json")
+	assert.Equal(t, cmd.ExitValidationFailed, code)
+
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, srt, doc.File)
+	assert.Equal(t, "srt", doc.Format)
+	assert.Equal(t, models.VerdictFail, doc.Verdict)
+	assert.Equal(t, 2, doc.Metrics.CueCount)
+	assert.InDelta(t, 0.5, doc.Metrics.Coverage, 0.0001)
+	assert.Equal(t, "en-US", doc.Metrics.DetectedLanguage)
+}
+
+func TestBatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "season1"), 0o755))
+	for _, name := range []string{"pilot.srt", "season1/e1.srt", "season1/e2.srt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(validSRT), 0o644))
+	}
+	endpoint := languageServer(t, "en-US")
+
+	code, stdout, stderr := runValidator(t, "--file", filepath.Join(dir, "*.srt"), "--file", filepath.Join(dir, "season1"),
+		"--end=8s", "--endpoint", endpoint, "--format=json", "--jobs=2")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+
+	var doc models.BatchReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	require.Len(t, doc.Files, 3)
+	assert.Equal(t, filepath.Join(dir, "pilot.srt"), doc.Files[0].File)
+	assert.Equal(t, models.BatchTotals{Files: 3, Passed: 3}, doc.Totals)
+	assert.Equal(t, models.VerdictPass, doc.Verdict)
+
+	code, _, stderr = runValidator(t, "--file", dir, "--end=16s", "--endpoint", endpoint)
+	assert.Equal(t, cmd.ExitValidationFailed, code)
+	assert.Contains(t, stderr, "3 of 3 files failed validation")
+
+	code, _, stderr = runValidator(t, "--file", filepath.Join(dir, "*.vtt"), "--end=8s", "--endpoint", endpoint)
+	assert.Equal(t, cmd.ExitInputUnreadable, code)
+	assert.Contains(t, stderr, "no files match")
+}
+
+func TestRemoteAndStdinInput(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+
+	t.Run("stdin", func(t *testing.T) {
+		command := exec.Command(binary, "--file=-", "--input-format=srt", "--end=8s", "--endpoint", endpoint, "--format=json")
+		command.Stdin = strings.NewReader(validSRT)
+		output, err := command.Output()
+		require.NoError(t, err)
+
+		var doc models.Report
+		require.NoError(t, json.Unmarshal(output, &doc))
+		assert.Equal(t, "-", doc.File)
+		assert.Equal(t, 2, doc.Metrics.CueCount)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(validSRT))
+		}))
+		defer server.Close()
+
+		code, stdout, stderr := runValidator(t, "--file", server.URL+"/episode.srt?X-Amz-Signature=secret", "--end=8s", "--endpoint", endpoint, "--format=json")
+		assert.Equal(t, cmd.ExitPass, code, stderr)
+		assert.NotContains(t, stdout, "secret")
+
+		code, _, stderr = runValidator(t, "--file", server.URL+"/episode.srt", "--end=8s", "--endpoint", endpoint, "--max-input-size=16")
+		assert.Equal(t, cmd.ExitInputUnreadable, code)
+		assert.Contains(t, stderr, "exceeds the 16 byte input limit")
+	})
+}
+
+func TestFormatDetection(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	vtt := "WEBVTT\n\n00:00:00.000 --> 00:00:04.000\nHello and welcome\n\n00:00:04.000 --> 00:00:08.000\nto the show\n"
+	path := writeCaptions(t, "episode.srt", vtt)
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "vtt", doc.Format)
+	require.Len(t, doc.Findings, 1)
+	assert.Equal(t, models.CodeFormatMismatch, doc.Findings[0].Code)
+	assert.Equal(t, "File extension .srt does not match its WebVTT content", doc.Findings[0].Description)
+
+	code, _, _ = runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--fail-on=warning")
+	assert.Equal(t, cmd.ExitValidationFailed, code)
+}
+
+func TestEncodingFinding(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	latin1 := strings.Replace(validSRT, "Hello and welcome", "Caf\xe9 and welcome", 1)
+	path := writeCaptions(t, "legacy.srt", latin1)
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "ISO-8859-1", doc.Encoding)
+	require.Len(t, doc.Findings, 1)
+	assert.Equal(t, models.CodeEncoding, doc.Findings[0].Code)
+	assert.Equal(t, "Captions are encoded as ISO-8859-1, not UTF-8", doc.Findings[0].Description)
+}
+
+func TestMalformedCueFinding(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	malformed := strings.Replace(validSRT, "00:00:04,000 --> 00:00:08,000", "00:00:04.000 --> 00:00:08.000", 1)
+	path := writeCaptions(t, "malformed.srt", malformed)
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, 2, doc.Metrics.CueCount)
+
+	var codes []string
+	for _, finding := range doc.Findings {
+		codes = append(codes, finding.Code)
+	}
+	// The cue without timing keeps the timing of the one before, which it
+	// then overlaps
+	assert.Equal(t, []string{models.CodeMalformedCue, models.CodeMalformedCue, models.CodeCueOverlap}, codes)
+	assert.Equal(t, `Line 6: timing line "00:00:04.000 --> 00:00:08.000" is not recognised and is read as text`, doc.Findings[0].Description)
+	require.NotNil(t, doc.Findings[0].Location)
+	assert.Equal(t, 2, doc.Findings[0].Location.Cue)
+	assert.Equal(t, 6, doc.Findings[0].Location.Line)
+	assert.Equal(t, "Line 6: cue has no timing line", doc.Findings[1].Description)
+}
+
+func TestTTMLInput(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	ttml := `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="en"><body><div>
+<p begin="00:00:00.000" end="00:00:04.000">Hello and welcome</p>
+<p begin="4s" dur="4s">to the show</p>
+</div></body></tt>`
+	path := writeCaptions(t, "episode.dfxp", ttml)
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "ttml", doc.Format)
+	assert.Equal(t, 2, doc.Metrics.CueCount)
+	assert.Equal(t, 1.0, doc.Metrics.Coverage)
+	assert.Empty(t, doc.Findings)
+
+	// The language xml:lang declares is expected instead of --lang
+	french := strings.Replace(strings.Replace(ttml, `xml:lang="en"`, `xml:lang="fr"`, 1), "to the show", "à l'émission", 1)
+	path = writeCaptions(t, "episode-fr.dfxp", french)
+	code, stdout, stderr = runValidator(t, "--file", path, "--end=8s", "--endpoint", languageServer(t, "fr-FR"), "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	doc = models.Report{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "fr", doc.Config.Language)
+	assert.Nil(t, doc.Track)
+}
+
+func TestSAMITracks(t *testing.T) {
+	// The detector answers French for French text and English otherwise
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lang := "en-US"
+		if strings.Contains(string(body), "Bonjour") {
+			lang = "fr-FR"
+		}
+		json.NewEncoder(w).Encode(models.LangResponse{Lang: lang})
+	}))
+	defer endpoint.Close()
+	sami := `<SAMI><HEAD><STYLE TYPE="text/css"><!--
+.ENCC { Name: English; lang: en-US; }
+.FRCC { Name: French; lang: fr-FR; }
+--></STYLE></HEAD><BODY>
+<SYNC Start=0><P Class=ENCC>Hello and welcome<P Class=FRCC>Bonjour et bienvenue
+<SYNC Start=4000><P Class=ENCC>to the show<P Class=FRCC>dans l'émission
+<SYNC Start=8000><P Class=ENCC>&nbsp;<P Class=FRCC>&nbsp;
+</BODY></SAMI>`
+	path := writeCaptions(t, "show.smi", sami)
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint.URL, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.BatchReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	require.Len(t, doc.Files, 2)
+	assert.Equal(t, &models.Track{ID: "ENCC", Name: "English", Language: "en-US"}, doc.Files[0].Track)
+	assert.Equal(t, &models.Track{ID: "FRCC", Name: "French", Language: "fr-FR"}, doc.Files[1].Track)
+	assert.Equal(t, "fr-FR", doc.Files[1].Config.Language)
+	assert.Equal(t, "fr-FR", doc.Files[1].Metrics.DetectedLanguage)
+	assert.Equal(t, models.BatchTotals{Files: 2, Passed: 2}, doc.Totals)
+
+	code, _, stderr = runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint.URL, "--html", filepath.Join(t.TempDir(), "report.html"))
+	assert.Equal(t, cmd.ExitUsage, code)
+	assert.Contains(t, stderr, "--html requires a single caption track")
+}
+
+func TestSTLInput(t *testing.T) {
+	// A French EBU STL file with two subtitles covering 8 seconds
+	gsi := bytes.Repeat([]byte{' '}, 1024)
+	copy(gsi, "850STL25.01 000F")
+	var data bytes.Buffer
+	data.Write(gsi)
+	for i, text := range []string{"Bonjour et bienvenue", "dans l'\xC2emission"} {
+		tti := bytes.Repeat([]byte{0x8F}, 128)
+		copy(tti, []byte{0, byte(i + 1), 0, 0xFF, 0, 0, 0, byte(i * 4), 0, 0, 0, byte(i*4 + 4), 0, 20, 2, 0})
+		copy(tti[16:], text)
+		data.Write(tti)
+	}
+	path := writeCaptions(t, "programme.stl", data.String())
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", languageServer(t, "fr-FR"), "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "stl", doc.Format)
+	assert.Equal(t, "ISO 6937", doc.Encoding)
+	assert.Equal(t, "fr", doc.Config.Language)
+	assert.Equal(t, 2, doc.Metrics.CueCount)
+	assert.Equal(t, 1.0, doc.Metrics.Coverage)
+	assert.Empty(t, doc.Findings)
+
+	// The header, not --lang, sets the expected language
+	code, stdout, _ = runValidator(t, "--file", path, "--end=8s", "--endpoint", languageServer(t, "en-US"), "--lang=en-US")
+	assert.Equal(t, cmd.ExitValidationFailed, code)
+	assert.Contains(t, stdout, "Caption language is en-US, expected fr")
+}
+
+func TestMicroDVDInput(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	path := writeCaptions(t, "film.sub", "{0}{100}Hello and welcome\n{100}{200}to the show\n")
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--fps=25", "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "microdvd", doc.Format)
+	assert.Equal(t, "25", doc.Config.FrameRate)
+	assert.Equal(t, 1.0, doc.Metrics.Coverage)
+	assert.Empty(t, doc.Findings)
+
+	code, _, stderr = runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--fps=fast")
+	assert.Equal(t, cmd.ExitUsage, code)
+	assert.Contains(t, stderr, `invalid frame rate "fast"`)
+}
+
+func TestHLSPlaylist(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	dir := t.TempDir()
+	files := map[string]string{
+		"subs.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4.0,\nseg0.vtt\n#EXTINF:4.0,\nseg1.vtt\n#EXT-X-ENDLIST\n",
+		"seg0.vtt":  "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000\n\n00:00:00.000 --> 00:00:03.000\nHello and welcome\n\n00:00:03.000 --> 00:00:05.000\nto the show\n",
+		// The cue spanning the boundary is repeated in the second segment
+		"seg1.vtt": "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:1260000,LOCAL:00:00:04.000\n\n00:00:03.000 --> 00:00:05.000\nto the show\n\n00:00:05.000 --> 00:00:08.000\nand goodbye\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	code, stdout, stderr := runValidator(t, "--file", filepath.Join(dir, "subs.m3u8"), "--end=8s", "--endpoint", endpoint, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "hls", doc.Format)
+	assert.Equal(t, 3, doc.Metrics.CueCount)
+	assert.Equal(t, 1.0, doc.Metrics.Coverage)
+	require.Len(t, doc.Findings, 1)
+	assert.Equal(t, models.CodeDuplicateCue, doc.Findings[0].Code)
+	assert.Equal(t, models.SeverityInfo, doc.Findings[0].Severity)
+	assert.Contains(t, doc.Findings[0].Description, "seg1.vtt")
+}
+
+func TestASRInput(t *testing.T) {
+	endpoint := languageServer(t, "en-US")
+	path := writeCaptions(t, "interview.json", `{"text": "Hello and welcome to the show.", "language": "en", "segments": [
+  {"id": 0, "start": 0.0, "end": 4.0, "text": " Hello and welcome"},
+  {"id": 1, "start": 4.0, "end": 8.0, "text": " to the show."}]}`)
+
+	code, stdout, stderr := runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "asr", doc.Format)
+	assert.Equal(t, 1.0, doc.Metrics.Coverage)
+	assert.Empty(t, doc.Findings)
+
+	code, _, stderr = runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--asr-mapping=path=results.items")
+	assert.Equal(t, cmd.ExitInputUnreadable, code)
+	assert.Contains(t, stderr, "--asr-mapping")
+
+	code, _, stderr = runValidator(t, "--file", path, "--end=8s", "--endpoint", endpoint, "--asr-mapping=srt")
+	assert.Equal(t, cmd.ExitUsage, code)
+	assert.Contains(t, stderr, "invalid ASR mapping")
+}
+
+// matroskaElement encodes a Matroska element with its children
+func matroskaElement(id []byte, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+	size := []byte{0x01, 0, 0, 0, byte(len(body) >> 24), byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	return bytes.Join([][]byte{id, size, body}, nil)
+}
+
+func TestMatroskaTracks(t *testing.T) {
+	// The detector answers French for French text and English otherwise
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lang := "en-US"
+		if strings.Contains(string(body), "Bonjour") {
+			lang = "fr-FR"
+		}
+		json.NewEncoder(w).Encode(models.LangResponse{Lang: lang})
+	}))
+	defer endpoint.Close()
+
+	uint8Element := func(id []byte, value byte) []byte { return matroskaElement(id, []byte{value}) }
+	duration := make([]byte, 8)
+	for i, bits := 0, math.Float64bits(8000); i < 8; i++ {
+		duration[i] = byte(bits >> uint(56-8*i))
+	}
+	track := func(number byte, language string) []byte {
+		return matroskaElement([]byte{0xAE}, uint8Element([]byte{0xD7}, number), uint8Element([]byte{0x83}, 0x11),
+			matroskaElement([]byte{0x86}, []byte("S_TEXT/UTF8")), matroskaElement([]byte{0x22, 0xB5, 0x9C}, []byte(language)))
+	}
+	block := func(number byte, at int16, text string) []byte {
+		return matroskaElement([]byte{0xA0},
+			matroskaElement([]byte{0xA1}, []byte{0x80 | number, byte(at >> 8), byte(at), 0}, []byte(text)),
+			matroskaElement([]byte{0x9B}, []byte{0x0F, 0xA0}))
+	}
+	mkv := bytes.Join([][]byte{
+		matroskaElement([]byte{0x1A, 0x45, 0xDF, 0xA3}, matroskaElement([]byte{0x42, 0x82}, []byte("webm"))),
+		matroskaElement([]byte{0x18, 0x53, 0x80, 0x67},
+			matroskaElement([]byte{0x15, 0x49, 0xA9, 0x66}, matroskaElement([]byte{0x44, 0x89}, duration)),
+			matroskaElement([]byte{0x16, 0x54, 0xAE, 0x6B}, track(1, "eng"), track(2, "fre")),
+			matroskaElement([]byte{0x1F, 0x43, 0xB6, 0x75}, uint8Element([]byte{0xE7}, 0),
+				block(1, 0, "Hello and welcome"), block(2, 0, "Bonjour et bienvenue"),
+				block(1, 4000, "to the show"), block(2, 4000, "dans l'émission")),
+		),
+	}, nil)
+	path := filepath.Join(t.TempDir(), "show.webm")
+	require.NoError(t, os.WriteFile(path, mkv, 0o644))
+
+	// The declared duration is the end time
+	code, stdout, stderr := runValidator(t, "--file", path, "--endpoint", endpoint.URL, "--format=json")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var batch models.BatchReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &batch))
+	require.Len(t, batch.Files, 2)
+	assert.Equal(t, &models.Track{ID: "1", Language: "en"}, batch.Files[0].Track)
+	assert.Equal(t, &models.Track{ID: "2", Language: "fr"}, batch.Files[1].Track)
+	assert.Equal(t, 1.0, batch.Files[1].Metrics.Coverage)
+	assert.Equal(t, models.BatchTotals{Files: 2, Passed: 2}, batch.Totals)
+
+	code, stdout, stderr = runValidator(t, "--file", path, "--endpoint", endpoint.URL, "--format=json", "--track=fr")
+	assert.Equal(t, cmd.ExitPass, code, stderr)
+	var doc models.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "mkv", doc.Format)
+	assert.Equal(t, &models.Track{ID: "2", Language: "fr"}, doc.Track)
+	assert.Equal(t, 2, doc.Metrics.CueCount)
+
+	// --html is refused before any track is validated
+	code, stdout, stderr = runValidator(t, "--file", path, "--endpoint", endpoint.URL, "--html", filepath.Join(t.TempDir(), "report.html"))
+	assert.Equal(t, cmd.ExitUsage, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "--html requires a single caption track; choose one with --track")
+
+	// A track choice that matches no track is a usage error
+	for _, choice := range []string{"de", "9"} {
+		code, stdout, stderr = runValidator(t, "--file", path, "--endpoint", endpoint.URL, "--track="+choice)
+		assert.Equal(t, cmd.ExitUsage, code, choice)
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, `Error: no caption track "`+choice+`"; the tracks are 1 (en), 2 (fr)`)
+	}
+}
@@ -0,0 +1,125 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func TestASRReader_Whisper(t *testing.T) {
+	input := `{"text": " Hello there. How are you?", "language": "en",
+  "segments": [
+    {"id": 0, "start": 0.0, "end": 2.5, "text": " Hello there.", "tokens": [1, 2]},
+    {"id": 1, "start": 2.5, "end": 4.24, "text": " How are you?"},
+    {"id": 2, "start": 5.0, "end": 6.0, "text": " "}
+  ]}`
+	cues := readCues(t, parse.NewASRReader(strings.NewReader(input), nil))
+	require.Len(t, cues, 2)
+	assert.Equal(t, time.Duration(0), cues[0].StartTime)
+	assert.Equal(t, 2500*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, "Hello there.", cues[0].Text)
+	assert.Equal(t, 4240*time.Millisecond, cues[1].EndTime)
+	assert.Equal(t, []string{"How are you?"}, cues[1].Lines)
+}
+
+func TestASRReader_Transcribe(t *testing.T) {
+	word := func(start, end, text string) string {
+		return `{"start_time": "` + start + `", "end_time": "` + end + `", "alternatives": [{"confidence": "0.99", "content": "` + text + `"}], "type": "pronunciation"}`
+	}
+	punctuation := func(text string) string {
+		return `{"alternatives": [{"confidence": "0.0", "content": "` + text + `"}], "type": "punctuation"}`
+	}
+	items := []string{
+		word("0.04", "0.4", "Hello"), punctuation(","), word("0.4", "0.9", "world"), punctuation("."),
+		word("1.0", "1.3", "Next"), word("1.3", "1.8", "sentence"),
+		// A long pause starts a new cue
+		word("4.0", "4.5", "Later"),
+	}
+	input := `{"jobName": "job", "results": {"transcripts": [{"transcript": "Hello, world."}], "items": [` + strings.Join(items, ",") + `]}, "status": "COMPLETED"}`
+
+	cues := readCues(t, parse.NewASRReader(strings.NewReader(input), nil))
+	require.Len(t, cues, 3)
+	assert.Equal(t, "Hello, world.", cues[0].Text)
+	assert.Equal(t, 40*time.Millisecond, cues[0].StartTime)
+	assert.Equal(t, 900*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, "Next sentence", cues[1].Text)
+	assert.Equal(t, "Later", cues[2].Text)
+	assert.Equal(t, 4*time.Second, cues[2].StartTime)
+}
+
+func TestASRReader_WordsWrapIntoLines(t *testing.T) {
+	mapping, err := parse.ParseASRMapping("path=words,start=t.0,end=t.1,text=w,units=ms,words=true")
+	require.NoError(t, err)
+
+	var words []string
+	for i := 0; i < 20; i++ {
+		words = append(words, fmt.Sprintf(`{"t": [%d, %d], "w": "caption"}`, i*250, i*250+200))
+	}
+	input := `{"words": [` + strings.Join(words, ",") + `]}`
+	cues := readCues(t, parse.NewASRReader(strings.NewReader(input), &mapping))
+
+	// Cues are cut before two lines of 42 characters overflow
+	require.Len(t, cues, 2)
+	assert.Equal(t, 2450*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, []string{
+		"caption caption caption caption caption",
+		"caption caption caption caption caption",
+	}, cues[0].Lines)
+	assert.Equal(t, 2500*time.Millisecond, cues[1].StartTime)
+}
+
+func TestASRReader_WordsCutAtSixSeconds(t *testing.T) {
+	var words []string
+	for i := 0; i < 12; i++ {
+		words = append(words, fmt.Sprintf(`{"start": %.1f, "end": %.1f, "text": "go"}`, float64(i)*0.6, float64(i)*0.6+0.4))
+	}
+	mapping := parse.ASRGeneric
+	mapping.Words = true
+	cues := readCues(t, parse.NewASRReader(strings.NewReader("["+strings.Join(words, ",")+"]"), &mapping))
+	require.Len(t, cues, 2)
+	assert.Equal(t, 5800*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, 6*time.Second, cues[1].StartTime)
+}
+
+func TestASRReader_GenericArray(t *testing.T) {
+	input := `[{"start": 1, "end": 2, "text": "One"}, {"end": 5, "text": "No start"}, {"start": "2.5", "end": "3s", "text": "Two"}]`
+	cues := readCues(t, parse.NewASRReader(strings.NewReader(input), nil))
+	require.Len(t, cues, 2)
+	assert.Equal(t, time.Second, cues[0].StartTime)
+	assert.Equal(t, 2500*time.Millisecond, cues[1].StartTime)
+	assert.Equal(t, 3*time.Second, cues[1].EndTime)
+
+	doc, err := parse.ParseDocument("-", parse.Options{Stdin: strings.NewReader(input)})
+	require.NoError(t, err)
+	assert.Equal(t, parse.FormatASR, doc.Format)
+	assert.Equal(t, []parse.Diagnostic{{Message: "item 2 has no timing; it is ignored"}}, doc.Diagnostics)
+}
+
+func TestASRReader_NoTimedArray(t *testing.T) {
+	_, err := parse.ParseCaptions(strings.NewReader(`{"segments": {"start": 1}}`), parse.FormatASR, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--asr-mapping")
+
+	_, err = parse.ParseCaptions(strings.NewReader(`{"segments": [{"start": 1,`), parse.FormatASR, 0)
+	assert.EqualError(t, err, "invalid ASR transcript: unexpected end of JSON")
+}
+
+func TestParseASRMapping(t *testing.T) {
+	mapping, err := parse.ParseASRMapping("Whisper")
+	require.NoError(t, err)
+	assert.Equal(t, parse.ASRWhisper, mapping)
+
+	mapping, err = parse.ParseASRMapping("path=data.cues, text=caption, units=ms")
+	require.NoError(t, err)
+	assert.Equal(t, parse.ASRMapping{Path: "data.cues", Start: "start", End: "end", Text: "caption", Unit: time.Millisecond}, mapping)
+
+	for _, value := range []string{"srt", "units=min", "words=maybe", "colour=red", "text="} {
+		_, err := parse.ParseASRMapping(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,83 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func TestParseASS(t *testing.T) {
+	input := `[Script Info]
+Title: Episode 1
+ScriptType: v4.00+
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize
+Style: Default,Arial,20
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Comment: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,Timing note
+Dialogue: 0,0:00:01.50,0:00:03.00,Default,Narrator,0,0,0,,{\an8}Hello,\Nworld
+Dialogue: 0,0:00:04.00,0:00:06.25,Signs,,0,0,0,,{\p1}m 0 0 l 100 0 100 100{\p0}Sign\htext
+Dialogue: 0,1:02:03.4,1:02:05.00,Default,,0,0,0,,{\i1}Italic{\i0} and {\b1}bold{\b0}
+`
+	captions, err := parse.ParseASS(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 3)
+
+	assert.Equal(t, 1500*time.Millisecond, captions[0].StartTime)
+	assert.Equal(t, 3*time.Second, captions[0].EndTime)
+	assert.Equal(t, []string{"Hello,", "world"}, captions[0].Lines)
+	assert.Equal(t, "Hello, world", captions[0].Text)
+	assert.Equal(t, map[string]string{"style": "Default", "actor": "Narrator"}, captions[0].Metadata)
+	assert.Equal(t, 12, captions[0].Line)
+
+	assert.Equal(t, "Sign text", captions[1].Text)
+	assert.Equal(t, map[string]string{"style": "Signs"}, captions[1].Metadata)
+	assert.Equal(t, 6250*time.Millisecond, captions[1].EndTime)
+
+	assert.Equal(t, time.Hour+2*time.Minute+3400*time.Millisecond, captions[2].StartTime)
+	assert.Equal(t, "Italic and bold", captions[2].Text)
+}
+
+func TestParseASS_SSAFormat(t *testing.T) {
+	input := `[Script Info]
+ScriptType: v4.00
+
+[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,0:00:01.00,0:00:02.00,*Default,Bob,0000,0000,0000,,Hi there
+`
+	captions, err := parse.ParseASS(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, "Hi there", captions[0].Text)
+	assert.Equal(t, "Bob", captions[0].Metadata["actor"])
+}
+
+func TestASSReader_Diagnostics(t *testing.T) {
+	input := `[Events]
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,First
+Dialogue: 0,soon,0:00:04.00,Default,,0,0,0,,Bad
+Dialogue: 0,0:00:05.00
+Dialogue: 0,0:00:06.00,0:00:07.00,Default,,0,0,0,,Last
+`
+	cues := readCues(t, parse.NewASSReader(strings.NewReader(input), 0))
+	require.Len(t, cues, 2)
+	assert.Equal(t, []parse.Diagnostic{{Line: 2, Message: "dialogue comes before the [Events] Format line; the ASS field order is assumed"}}, cues[0].Diagnostics)
+	assert.Equal(t, []parse.Diagnostic{
+		{Line: 3, Message: `start time "soon" is not recognised; the dialogue is ignored`},
+		{Line: 4, Message: "dialogue has 2 fields, expected 10, and is ignored"},
+	}, cues[1].Diagnostics)
+}
+
+func TestASSText(t *testing.T) {
+	assert.Equal(t, []string{"Top", "Bottom"}, parse.ASSText(`{\pos(10,20)\c&H00FFFF&}Top\nBottom`))
+	assert.Empty(t, parse.ASSText(`{\p1}m 0 0 l 10 10`))
+	assert.Empty(t, parse.ASSText(`{\an8}`))
+}
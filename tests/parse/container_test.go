@@ -0,0 +1,313 @@
+package parse
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// ebml encodes a Matroska element with its children
+func ebml(id uint32, children ...[]byte) []byte {
+	return ebmlSized(id, 0, children...)
+}
+
+// ebmlSized encodes a Matroska element, with an unknown size when unknown
+// is not 0
+func ebmlSized(id uint32, unknown int, children ...[]byte) []byte {
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> uint(shift)); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	body := bytes.Join(children, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+	if unknown != 0 {
+		size = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	}
+	return append(append(out, size...), body...)
+}
+
+func ebmlUint(id uint32, value uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, value)
+	return ebml(id, b)
+}
+
+func ebmlFloat(id uint32, value float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(value))
+	return ebml(id, b)
+}
+
+func ebmlText(id uint32, value string) []byte {
+	return ebml(id, []byte(value))
+}
+
+// mkvBlock encodes the body of a block of a track at a time relative to
+// its cluster
+func mkvBlock(id uint32, track byte, relative int16, payload []byte) []byte {
+	return ebml(id, []byte{0x80 | track, byte(uint16(relative) >> 8), byte(relative), 0}, payload)
+}
+
+func mkvFile(t *testing.T, compressed bool) []byte {
+	t.Helper()
+	french := func(text string) []byte {
+		if !compressed {
+			return []byte(text)
+		}
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte(text))
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	var encodings []byte
+	if compressed {
+		encodings = ebml(0x6D80, ebml(0x6240, ebml(0x5034, ebmlUint(0x4254, 0))))
+	}
+
+	return bytes.Join([][]byte{
+		ebml(0x1A45DFA3, ebmlText(0x4282, "matroska")),
+		ebmlSized(0x18538067, -1,
+			ebml(0x1549A966, ebmlUint(0x2AD7B1, 1000000), ebmlFloat(0x4489, 8000)),
+			ebml(0x1654AE6B,
+				ebml(0xAE, ebmlUint(0xD7, 1), ebmlUint(0x83, 1), ebmlText(0x86, "V_MPEG4/ISO/AVC")),
+				ebml(0xAE, ebmlUint(0xD7, 2), ebmlUint(0x83, 0x11), ebmlText(0x86, "S_TEXT/UTF8"), ebmlText(0x22B59C, "fre"), ebmlText(0x536E, "Français"), encodings),
+				ebml(0xAE, ebmlUint(0xD7, 3), ebmlUint(0x83, 0x11), ebmlText(0x86, "S_TEXT/ASS"), ebmlText(0x22B59C, "eng"), ebmlText(0x22B59D, "en-GB")),
+				ebml(0xAE, ebmlUint(0xD7, 4), ebmlUint(0x83, 0x11), ebmlText(0x86, "S_HDMV/PGS")),
+			),
+			ebml(0x1F43B675,
+				ebmlUint(0xE7, 0),
+				mkvBlock(0xA3, 1, 0, bytes.Repeat([]byte{0xAA}, 5000)),
+				ebml(0xA0, mkvBlock(0xA1, 2, 1000, french("Bonjour\r\nle monde")), ebmlUint(0x9B, 1500)),
+				ebml(0xA0, mkvBlock(0xA1, 3, 2000, []byte(`0,0,Default,Bob,0,0,0,,{\i1}Hello{\i0}\NWorld`)), ebmlUint(0x9B, 1000)),
+				mkvBlock(0xA3, 4, 2500, []byte{0x16, 0x00}),
+			),
+			ebmlSized(0x1F43B675, -1,
+				ebmlUint(0xE7, 5000),
+				mkvBlock(0xA3, 2, 0, french("Sans durée")),
+			),
+		),
+	}, nil)
+}
+
+func TestMatroskaReader(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		r, err := parse.NewMatroskaReader(bytes.NewReader(mkvFile(t, compressed)))
+		require.NoError(t, err)
+		assert.Equal(t, 8*time.Second, r.Duration())
+		assert.Equal(t, []models.Track{
+			{ID: "2", Name: "Français", Language: "fr"},
+			{ID: "3", Language: "en-GB"},
+		}, r.Tracks())
+
+		cues := readCues(t, r)
+		require.Len(t, cues, 3)
+		assert.Equal(t, "2", cues[0].Track)
+		assert.Equal(t, "fr", cues[0].Language)
+		assert.Equal(t, []string{"Bonjour", "le monde"}, cues[0].Lines)
+		assert.Equal(t, time.Second, cues[0].StartTime)
+		assert.Equal(t, 2500*time.Millisecond, cues[0].EndTime)
+		assert.Equal(t, []parse.Diagnostic{{Message: "subtitle track 4 (S_HDMV/PGS) cannot be read and is not validated"}}, cues[0].Diagnostics)
+
+		assert.Equal(t, "3", cues[1].Track)
+		assert.Equal(t, []string{"Hello", "World"}, cues[1].Lines)
+		assert.Equal(t, "Hello World", cues[1].Text)
+		assert.Equal(t, map[string]string{"style": "Default", "actor": "Bob"}, cues[1].Metadata)
+
+		assert.Equal(t, 5*time.Second, cues[2].StartTime)
+		assert.Equal(t, cues[2].StartTime, cues[2].EndTime)
+		assert.Equal(t, []parse.Diagnostic{{Message: "subtitle track 2: block at 5s has no duration"}}, cues[2].Diagnostics)
+	}
+}
+
+func TestMatroskaReader_Invalid(t *testing.T) {
+	_, err := parse.NewMatroskaReader(bytes.NewReader([]byte("not matroska")))
+	assert.EqualError(t, err, "invalid Matroska: no EBML header")
+
+	_, err = parse.NewMatroskaReader(bytes.NewReader(ebml(0x1A45DFA3, ebmlText(0x4282, "other"))))
+	assert.EqualError(t, err, `invalid Matroska: document type "other"`)
+
+	file := mkvFile(t, false)
+	r, err := parse.NewMatroskaReader(bytes.NewReader(file[:len(file)-4]))
+	require.NoError(t, err)
+	for err == nil {
+		_, err = r.Next()
+	}
+	assert.EqualError(t, err, "invalid Matroska: file is truncated")
+}
+
+// box encodes an MP4 box
+func box(typ string, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out, uint32(8+len(body)))
+	copy(out[4:], typ)
+	return append(out, body...)
+}
+
+// u32s encodes big-endian 32-bit numbers
+func u32s(values ...uint32) []byte {
+	out := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(out[4*i:], v)
+	}
+	return out
+}
+
+// mp4Track encodes a track whose samples are listed by the sample table
+// in stbl
+func mp4Track(id uint32, handler, language, entry string, stbl ...[]byte) []byte {
+	packed := uint16(language[0]-0x60)<<10 | uint16(language[1]-0x60)<<5 | uint16(language[2]-0x60)
+	return box("trak",
+		box("tkhd", u32s(0, 0, 0, id, 0)),
+		box("mdia",
+			box("mdhd", u32s(0, 0, 0, 1000, 0), []byte{byte(packed >> 8), byte(packed), 0, 0}),
+			box("hdlr", u32s(0, 0), []byte(handler), make([]byte, 12), []byte("SubtitleHandler\x00")),
+			box("minf", box("stbl", append([][]byte{box("stsd", u32s(0, 1), box(entry))}, stbl...)...)),
+		),
+	)
+}
+
+func tx3gSample(text string) []byte {
+	return append([]byte{byte(len(text) >> 8), byte(len(text))}, text...)
+}
+
+func TestMP4Reader(t *testing.T) {
+	samples := [][]byte{
+		tx3gSample("Hello\nthere"),
+		tx3gSample(""),
+		box("vttc", box("payl", []byte("Hola"))),
+	}
+	build := func(offset uint32) []byte {
+		ftyp := box("ftyp", []byte("isom"), u32s(0x200), []byte("isomiso2mp41"))
+		moov := box("moov",
+			box("mvhd", u32s(0, 0, 0, 1000, 9000), make([]byte, 80)),
+			mp4Track(1, "vide", "und", "avc1"),
+			mp4Track(2, "sbtl", "eng", "tx3g",
+				box("stts", u32s(0, 2, 1, 1000, 1, 2000)),
+				box("stsz", u32s(0, 0, 2, uint32(len(samples[0])), uint32(len(samples[1])))),
+				box("stsc", u32s(0, 1, 1, 2, 1)),
+				box("stco", u32s(0, 1, offset)),
+			),
+			mp4Track(3, "subt", "spa", "wvtt",
+				box("stts", u32s(0, 1, 1, 1500)),
+				box("stsz", u32s(0, 0, 1, uint32(len(samples[2])))),
+				box("stsc", u32s(0, 1, 1, 1, 1)),
+				box("stco", u32s(0, 1, offset+uint32(len(samples[0])+len(samples[1])))),
+			),
+			mp4Track(4, "text", "eng", "text"),
+		)
+		return bytes.Join([][]byte{ftyp, moov, box("mdat", bytes.Join(samples, nil))}, nil)
+	}
+	file := build(0)
+	file = build(uint32(len(file) - len(bytes.Join(samples, nil))))
+
+	r, err := parse.NewMP4Reader(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	assert.Equal(t, 9*time.Second, r.Duration())
+	assert.Equal(t, []models.Track{
+		{ID: "2", Name: "SubtitleHandler", Language: "en"},
+		{ID: "3", Name: "SubtitleHandler", Language: "es"},
+	}, r.Tracks())
+
+	cues := readCues(t, r)
+	require.Len(t, cues, 2)
+	assert.Equal(t, []string{"Hello", "there"}, cues[0].Lines)
+	assert.Equal(t, time.Duration(0), cues[0].StartTime)
+	assert.Equal(t, time.Second, cues[0].EndTime)
+	assert.Equal(t, "2", cues[0].Track)
+	assert.Equal(t, []parse.Diagnostic{{Message: "subtitle track 4 (text) cannot be read and is not validated"}}, cues[0].Diagnostics)
+	assert.Equal(t, "Hola", cues[1].Text)
+	assert.Equal(t, "3", cues[1].Track)
+	assert.Equal(t, 1500*time.Millisecond, cues[1].EndTime)
+}
+
+func TestMP4Reader_Fragmented(t *testing.T) {
+	sample := box("vttc", box("payl", []byte("Fragment one")))
+	moov := box("moov",
+		box("mvhd", u32s(0, 0, 0, 1000, 0), make([]byte, 80)),
+		mp4Track(1, "subt", "eng", "wvtt"),
+		box("mvex", box("trex", u32s(0, 1, 1, 0, 0, 0))),
+	)
+	traf := func(dataOffset uint32) []byte {
+		return box("traf",
+			box("tfhd", u32s(0x020000, 1)),
+			box("tfdt", u32s(0x01000000, 0, 4000)),
+			box("trun", u32s(0x000301, 2, dataOffset, 2000, uint32(len(sample)), 1000, 8)),
+		)
+	}
+	moof := box("moof", box("mfhd", u32s(0, 1)), traf(0))
+	moof = box("moof", box("mfhd", u32s(0, 1)), traf(uint32(len(moof)+8)))
+	file := bytes.Join([][]byte{box("ftyp", []byte("iso6")), moov, moof, box("mdat", sample, box("vtte"))}, nil)
+
+	path := filepath.Join(t.TempDir(), "subs.mp4")
+	require.NoError(t, os.WriteFile(path, file, 0o644))
+	doc, err := parse.ParseDocument(path, parse.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, parse.FormatMP4, doc.Format)
+	require.Len(t, doc.Captions, 1)
+	assert.Equal(t, "Fragment one", doc.Captions[0].Text)
+	assert.Equal(t, 4*time.Second, doc.Captions[0].StartTime)
+	assert.Equal(t, 6*time.Second, doc.Captions[0].EndTime)
+}
+
+func TestMP4Reader_SampleCounts(t *testing.T) {
+	moov := func(stbl ...[]byte) []byte {
+		return box("moov",
+			box("mvhd", u32s(0, 0, 0, 1000, 0), make([]byte, 80)),
+			mp4Track(1, "sbtl", "eng", "tx3g", stbl...),
+			box("mvex", box("trex", u32s(0, 1, 1, 1000, 2, 0))),
+		)
+	}
+	read := func(parts ...[]byte) error {
+		file := bytes.Join(parts, nil)
+		_, err := parse.NewMP4Reader(bytes.NewReader(file), int64(len(file)))
+		return err
+	}
+
+	// Counts are checked before the samples they declare are allocated
+	fragment := box("moof", box("traf", box("tfhd", u32s(0, 1)), box("trun", u32s(0, 0x0FFFFFFF))))
+	assert.EqualError(t, read(moov(), fragment), "invalid MP4: subtitle track 1 has more than 1048576 samples")
+	fragment = box("moof", box("traf", box("tfhd", u32s(0, 1)), box("trun", u32s(0, 1000))))
+	assert.EqualError(t, read(moov(), fragment), "invalid MP4: 1000 samples of subtitle track 1 do not fit in the file")
+	fragment = box("moof", box("traf", box("tfhd", u32s(0, 1)), box("trun", u32s(0x000200, 3, 2))))
+	assert.EqualError(t, read(moov(), fragment), "invalid MP4: track run of 3 samples of subtitle track 1 is truncated")
+
+	table := moov(box("stts", u32s(0, 1, 0x0FFFFFFF, 1)), box("stsz", u32s(0, 2, 0x0FFFFFFF)))
+	assert.EqualError(t, read(table), "invalid MP4: subtitle track 1 has more than 1048576 samples")
+	table = moov(box("stts", u32s(0, 1, 1000, 1)), box("stsz", u32s(0, 2, 1000)))
+	assert.EqualError(t, read(table), "invalid MP4: 1000 samples of subtitle track 1 do not fit in the file")
+}
+
+func TestStream_Track(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "film.mkv")
+	require.NoError(t, os.WriteFile(path, mkvFile(t, false), 0o644))
+
+	doc, err := parse.ParseDocument(path, parse.Options{Track: "en-gb"})
+	require.NoError(t, err)
+	require.Len(t, doc.Captions, 1)
+	assert.Equal(t, "3", doc.Captions[0].Track)
+	assert.Equal(t, 8*time.Second, doc.Duration)
+
+	_, err = parse.ParseDocument(path, parse.Options{Track: "de"})
+	assert.EqualError(t, err, `no caption track "de"; the tracks are 2 (fr), 3 (en-GB)`)
+
+	// An input with a single track is read whatever track is chosen
+	captions, err := parse.ParseCaptions(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n")), parse.FormatSRT, 0)
+	require.NoError(t, err)
+	assert.Len(t, captions, 1)
+}
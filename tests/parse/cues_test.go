@@ -0,0 +1,143 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func readCues(t *testing.T, r parse.CueReader) []parse.Cue {
+	t.Helper()
+	var cues []parse.Cue
+	require.NoError(t, parse.ForEachCue(r, func(cue parse.Cue) error {
+		cues = append(cues, cue)
+		return nil
+	}))
+	return cues
+}
+
+func TestNewCueReader(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nWorld\n"
+	r, err := parse.NewCueReader(strings.NewReader(srt), parse.FormatSRT, 0)
+	require.NoError(t, err)
+	cues := readCues(t, r)
+	require.Len(t, cues, 2)
+	assert.Equal(t, "Hello", cues[0].Text)
+	assert.Equal(t, 3*time.Second, cues[1].StartTime)
+	assert.Empty(t, cues[0].Diagnostics)
+	assert.Empty(t, cues[1].Diagnostics)
+
+	_, err = parse.NewCueReader(strings.NewReader(srt), "txt", 0)
+	assert.EqualError(t, err, "unsupported caption format: txt")
+}
+
+func TestCueReader_Diagnostics(t *testing.T) {
+	t.Run("SRT", func(t *testing.T) {
+		srt := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+			"two\n00:00:03,000 --> 00:00:04,000\nWorld\n\n" +
+			"3\n00:00:05.000 --> 00:00:06.000\nDotted\n\n" +
+			"4\nOrphan text\n"
+		cues := readCues(t, parse.NewSRTReader(strings.NewReader(srt), 0))
+		require.Len(t, cues, 4)
+		assert.Empty(t, cues[0].Diagnostics)
+		assert.Equal(t, []parse.Diagnostic{{Line: 5, Message: `expected a sequence number, found "two"`}}, cues[1].Diagnostics)
+		assert.Equal(t, []parse.Diagnostic{
+			{Line: 10, Message: `timing line "00:00:05.000 --> 00:00:06.000" is not recognised and is read as text`},
+			{Line: 10, Message: "cue has no timing line"},
+		}, cues[2].Diagnostics)
+		assert.Equal(t, []parse.Diagnostic{{Line: 14, Message: "cue has no timing line"}}, cues[3].Diagnostics)
+	})
+
+	t.Run("WebVTT", func(t *testing.T) {
+		vtt := "WEBVTT\n\nNOTE a comment\n\n00:00:01.000 --> 00:00:02.000\nHello\n\nNo timing here\n"
+		cues := readCues(t, parse.NewWebVTTReader(strings.NewReader(vtt), 0))
+		require.Len(t, cues, 2)
+		assert.Empty(t, cues[0].Diagnostics)
+		assert.Equal(t, []parse.Diagnostic{{Line: 8, Message: "cue has no timing line"}}, cues[1].Diagnostics)
+	})
+}
+
+func TestForEachCue_StopsOnError(t *testing.T) {
+	r := parse.NewSRTReader(newSRTStream(time.Minute), 0)
+	stop := errors.New("stop")
+	seen := 0
+	err := parse.ForEachCue(r, func(parse.Cue) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 3, seen)
+}
+
+func TestOpenStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captions.txt")
+	require.NoError(t, os.WriteFile(path, []byte("WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n"), 0o644))
+
+	stream, err := parse.OpenStream(path, parse.Options{})
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, parse.FormatWebVTT, stream.Format)
+	assert.Equal(t, parse.FormatWebVTT, stream.Detected)
+	assert.Equal(t, "txt", stream.Extension)
+
+	cue, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", cue.Text)
+	_, err = stream.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, parse.EncodingUTF8, stream.Encoding)
+}
+
+// BenchmarkCueReader_MultiHour streams generated SRT files of increasing
+// length cue by cue. As with BenchmarkLineReader_MultiHour, the
+// live-heap-KB metric stays flat as the input grows.
+func BenchmarkCueReader_MultiHour(b *testing.B) {
+	for _, hours := range []int{1, 4, 12} {
+		b.Run(fmt.Sprintf("%dh", hours), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			var stats runtime.MemStats
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				base := stats.HeapAlloc
+
+				r := parse.NewSRTReader(newSRTStream(time.Duration(hours)*time.Hour), 0)
+				n := 0
+				err := parse.ForEachCue(r, func(parse.Cue) error {
+					if n++; n%2000 == 0 {
+						runtime.GC()
+						runtime.ReadMemStats(&stats)
+						if stats.HeapAlloc > base && stats.HeapAlloc-base > peak {
+							peak = stats.HeapAlloc - base
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(peak)/1024, "live-heap-KB")
+		})
+	}
+}
+
+func TestTextPreview(t *testing.T) {
+	assert.Equal(t, "Hello there", parse.TextPreview("  Hello\n\tthere "))
+	long := strings.Repeat("あ", 50)
+	assert.Equal(t, strings.Repeat("あ", 39)+"…", parse.TextPreview(long))
+}
@@ -0,0 +1,99 @@
+package parse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+const encodedSRT = "1\r\n00:00:01,000 --> 00:00:02,000\r\nCafé “Zoë” 😀\r\n"
+
+func utf16Bytes(s string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	var buf bytes.Buffer
+	for _, u := range units {
+		if bigEndian {
+			buf.Write([]byte{byte(u >> 8), byte(u)})
+		} else {
+			buf.Write([]byte{byte(u), byte(u >> 8)})
+		}
+	}
+	return buf.Bytes()
+}
+
+func parseBytes(t *testing.T, data []byte) *parse.Document {
+	t.Helper()
+	doc, err := parse.ParseDocument("-", parse.Options{Stdin: bytes.NewReader(data)})
+	require.NoError(t, err)
+	return doc
+}
+
+func TestParseDocument_Encodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		text     string
+	}{
+		{"UTF-8", []byte(encodedSRT), parse.EncodingUTF8, "Café “Zoë” 😀"},
+		{"UTF-8 with BOM", append([]byte("\xef\xbb\xbf"), encodedSRT...), parse.EncodingUTF8, "Café “Zoë” 😀"},
+		{"UTF-16LE with BOM", utf16Bytes(encodedSRT, false, true), parse.EncodingUTF16LE, "Café “Zoë” 😀"},
+		{"UTF-16BE with BOM", utf16Bytes(encodedSRT, true, true), parse.EncodingUTF16BE, "Café “Zoë” 😀"},
+		{"UTF-16LE without BOM", utf16Bytes(encodedSRT, false, false), parse.EncodingUTF16LE, "Café “Zoë” 😀"},
+		{"UTF-16BE without BOM", utf16Bytes(encodedSRT, true, false), parse.EncodingUTF16BE, "Café “Zoë” 😀"},
+		{
+			"windows-1252",
+			[]byte("1\r\n00:00:01,000 --> 00:00:02,000\r\nCaf\xe9 \x93Zo\xeb\x94 \x80\r\n"),
+			parse.EncodingWindows1252,
+			"Café “Zoë” €",
+		},
+		{
+			"ISO-8859-1",
+			[]byte("1\n00:00:01,000 --> 00:00:02,000\n\xc7a va tr\xe8s bien\n"),
+			parse.EncodingLatin1,
+			"Ça va très bien",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseBytes(t, tt.data)
+			assert.Equal(t, parse.FormatSRT, doc.Format)
+			assert.Equal(t, tt.encoding, doc.Encoding)
+			require.Len(t, doc.Captions, 1)
+			assert.Equal(t, tt.text, doc.Captions[0].Text)
+		})
+	}
+}
+
+func TestParseDocument_EncodingAcrossReads(t *testing.T) {
+	// Multi-byte characters and surrogate pairs straddle the decoder's
+	// internal read boundaries somewhere in a large input
+	var b strings.Builder
+	for i := 1; i <= 3000; i++ {
+		b.WriteString("1\n00:00:01,000 --> 00:00:02,000\nÉté 😀 à Zürich\n\n")
+	}
+	content := b.String()
+
+	doc := parseBytes(t, []byte(content))
+	assert.Equal(t, parse.EncodingUTF8, doc.Encoding)
+	require.Len(t, doc.Captions, 3000)
+	for _, caption := range doc.Captions {
+		require.Equal(t, "Été 😀 à Zürich", caption.Text)
+	}
+
+	doc = parseBytes(t, utf16Bytes(content, false, true))
+	assert.Equal(t, parse.EncodingUTF16LE, doc.Encoding)
+	require.Len(t, doc.Captions, 3000)
+	for _, caption := range doc.Captions {
+		require.Equal(t, "Été 😀 à Zürich", caption.Text)
+	}
+}
@@ -0,0 +1,101 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// writeHLS writes a playlist and its segments to a new directory and
+// returns the path of the playlist
+func writeHLS(t *testing.T, playlist string, segments map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range segments {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	path := filepath.Join(dir, "subs.m3u8")
+	require.NoError(t, os.WriteFile(path, []byte(playlist), 0o644))
+	return path
+}
+
+func TestHLSReader(t *testing.T) {
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		"#EXTINF:6.000,\nvtt/seg1.vtt\n#EXTINF:6.000,\nvtt/seg2.vtt\n" +
+		"#EXT-X-DISCONTINUITY\n#EXTINF:6.000,\nvtt/seg3.vtt\n#EXT-X-ENDLIST\n"
+	path := writeHLS(t, playlist, map[string]string{
+		// The MPEG-2 timestamp rolls over between the first two segments
+		"vtt/seg1.vtt": "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:8589844592,LOCAL:00:00:00.000\n\n" +
+			"00:00:01.000 --> 00:00:02.000\nOne\n\n00:00:05.000 --> 00:00:07.000\nAcross\n",
+		"vtt/seg2.vtt": "WEBVTT\nX-TIMESTAMP-MAP=LOCAL:00:00:06.000,MPEGTS:450000\n\n" +
+			"00:00:05.000 --> 00:00:07.000\nAcross\n\n00:00:08.000 --> 00:00:09.000\nTwo\n",
+		"vtt/seg3.vtt": "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000\n\n" +
+			"00:00:01.000 --> 00:00:02.000\nThree\n",
+	})
+
+	doc, err := parse.ParseDocument(path, parse.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, parse.FormatHLS, doc.Format)
+	require.Len(t, doc.Captions, 4)
+	for i, want := range []struct {
+		text       string
+		start, end time.Duration
+	}{
+		{"One", 1 * time.Second, 2 * time.Second},
+		{"Across", 5 * time.Second, 7 * time.Second},
+		{"Two", 8 * time.Second, 9 * time.Second},
+		{"Three", 13 * time.Second, 14 * time.Second},
+	} {
+		assert.Equal(t, want.text, doc.Captions[i].Text)
+		assert.Equal(t, want.start, doc.Captions[i].StartTime, want.text)
+		assert.Equal(t, want.end, doc.Captions[i].EndTime, want.text)
+	}
+	assert.Equal(t, []parse.Diagnostic{{
+		Code:    models.CodeDuplicateCue,
+		Message: "cue is repeated in segment vtt/seg2.vtt across the segment boundary and is counted once",
+	}}, doc.Diagnostics)
+}
+
+func TestHLSReader_SplitCue(t *testing.T) {
+	// A cue clipped at the boundary and continued in the next segment is
+	// read as one
+	playlist := "#EXTM3U\n#EXTINF:4,\na.vtt\n#EXTINF:4,\nb.vtt\n"
+	path := writeHLS(t, playlist, map[string]string{
+		"a.vtt": "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000\n\n00:00:03.000 --> 00:00:04.000\nHello\n",
+		"b.vtt": "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:1260000,LOCAL:00:00:00.000\n\n00:00:00.000 --> 00:00:01.500\nHello\n",
+	})
+	captions, err := parse.ParseInput(path, parse.Options{})
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, 3*time.Second, captions[0].StartTime)
+	assert.Equal(t, 5500*time.Millisecond, captions[0].EndTime)
+}
+
+func TestHLSReader_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		expected string
+	}{
+		{"master playlist", "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8\n", "HLS master playlists are not read"},
+		{"missing segment", "#EXTM3U\n#EXTINF:6,\nmissing.vtt\n", "HLS segment missing.vtt:"},
+		{"no header", "#EXTINF:6,\nseg.vtt\n", "does not start with #EXTM3U"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeHLS(t, tt.playlist, nil)
+			_, err := parse.ParseInput(path, parse.Options{Format: parse.FormatHLS})
+			require.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), tt.expected), err.Error())
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func readLines(t *testing.T, r *parse.LineReader) []string {
+	t.Helper()
+	var lines []string
+	for r.Scan() {
+		lines = append(lines, r.Text())
+	}
+	return lines
+}
+
+func TestLineReader(t *testing.T) {
+	r := parse.NewLineReader(strings.NewReader("one\r\ntwo\n\nthree"), 0)
+	assert.Equal(t, []string{"one", "two", "", "three"}, readLines(t, r))
+	assert.Equal(t, 4, r.Line())
+	assert.NoError(t, r.Err())
+
+	r = parse.NewLineReader(strings.NewReader(""), 0)
+	assert.Empty(t, readLines(t, r))
+	assert.NoError(t, r.Err())
+}
+
+func TestLineReader_LongLines(t *testing.T) {
+	long := strings.Repeat("a", 300<<10)
+
+	t.Run("lines beyond the bufio.Scanner limit are read", func(t *testing.T) {
+		r := parse.NewLineReader(strings.NewReader("short\n"+long+"\nafter\n"), 0)
+		lines := readLines(t, r)
+		require.NoError(t, r.Err())
+		require.Len(t, lines, 3)
+		assert.Equal(t, long, lines[1])
+		assert.Equal(t, "after", lines[2])
+	})
+
+	t.Run("a line over the maximum is an error", func(t *testing.T) {
+		r := parse.NewLineReader(strings.NewReader("short\n"+long+"\nafter\n"), 100<<10)
+		assert.Equal(t, []string{"short"}, readLines(t, r))
+
+		var lineErr *parse.LineTooLongError
+		require.True(t, errors.As(r.Err(), &lineErr))
+		assert.Equal(t, 2, lineErr.Line)
+		assert.EqualError(t, r.Err(), "line 2 is longer than the maximum line size of 102400 bytes")
+	})
+
+	t.Run("the maximum excludes the line ending", func(t *testing.T) {
+		r := parse.NewLineReader(strings.NewReader("12345\r\n123456\n"), 5)
+		assert.Equal(t, []string{"12345"}, readLines(t, r))
+		assert.ErrorContains(t, r.Err(), "line 2 is longer than the maximum line size of 5 bytes")
+	})
+}
+
+func TestParseWebVTT_LongNote(t *testing.T) {
+	image := strings.Repeat("QUJD", 256<<10)
+	input := "WEBVTT\nKind: captions\n\nNOTE inline thumbnail\ndata:image/png;base64," + image + "\n\n" +
+		"STYLE\n::cue { color: yellow }\n\n" +
+		"00:00:01.000 --> 00:00:02.000\nHello\n"
+
+	captions, err := parse.ParseWebVTT(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, "Hello", captions[0].Text)
+	assert.Equal(t, time.Second, captions[0].StartTime)
+
+	_, err = parse.ParseInput("-", parse.Options{Stdin: strings.NewReader(input), MaxLineSize: 64 << 10})
+	assert.ErrorContains(t, err, "line 5 is longer than the maximum line size of 65536 bytes")
+}
+
+func TestParseWebVTT_CueIdentifier(t *testing.T) {
+	input := "WEBVTT\n\nintro\n00:00:01.000 --> 00:00:03.000\nHello there\nGeneral Kenobi\n\n" +
+		"2\n00:00:04.000 --> 00:00:05.000 align:start\nBye\n"
+
+	captions, err := parse.ParseWebVTT(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 2)
+	assert.Equal(t, []string{"Hello there", "General Kenobi"}, captions[0].Lines)
+	assert.Equal(t, "Hello there General Kenobi", captions[0].Text)
+	assert.Equal(t, []string{"Bye"}, captions[1].Lines)
+	assert.Equal(t, 4*time.Second, captions[1].StartTime)
+}
+
+// srtStream generates an SRT file of the given length on the fly, with a
+// two-line cue every three seconds, so that benchmarks do not hold the
+// whole input in memory
+type srtStream struct {
+	cues, next int
+	pending    []byte
+}
+
+func newSRTStream(length time.Duration) *srtStream {
+	return &srtStream{cues: int(length / (3 * time.Second))}
+}
+
+func (s *srtStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.next >= s.cues {
+			return 0, io.EOF
+		}
+		start := time.Duration(s.next) * 3 * time.Second
+		s.next++
+		s.pending = []byte(fmt.Sprintf("%d\n%s --> %s\nThis is caption number %d\nwith a second line of text\n\n",
+			s.next, srtTimestamp(start), srtTimestamp(start+2500*time.Millisecond), s.next))
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func srtTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// BenchmarkLineReader_MultiHour reads generated SRT files of increasing
+// length. The live-heap-KB metric, sampled after a collection every few
+// thousand lines, stays flat as the input grows because only the current
+// line is kept.
+func BenchmarkLineReader_MultiHour(b *testing.B) {
+	for _, hours := range []int{1, 4, 12} {
+		b.Run(fmt.Sprintf("%dh", hours), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			var stats runtime.MemStats
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				base := stats.HeapAlloc
+
+				r := parse.NewLineReader(newSRTStream(time.Duration(hours)*time.Hour), 0)
+				for n := 0; r.Scan(); n++ {
+					if n%5000 == 0 {
+						runtime.GC()
+						runtime.ReadMemStats(&stats)
+						if stats.HeapAlloc > base && stats.HeapAlloc-base > peak {
+							peak = stats.HeapAlloc - base
+						}
+					}
+				}
+				if r.Err() != nil {
+					b.Fatal(r.Err())
+				}
+			}
+			b.ReportMetric(float64(peak)/1024, "live-heap-KB")
+		})
+	}
+}
+
+func BenchmarkParseWebVTT_LongLine(b *testing.B) {
+	input := "WEBVTT\n\nNOTE\n" + strings.Repeat("QUJD", 1<<20) + "\n\n00:00:01.000 --> 00:00:02.000\nHello\n"
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parse.ParseWebVTT(strings.NewReader(input)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
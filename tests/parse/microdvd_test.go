@@ -0,0 +1,84 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  parse.FrameRate
+	}{
+		{"25", parse.FrameRate25},
+		{"23.976", parse.FrameRate23976},
+		{"23.98", parse.FrameRate23976},
+		{"29.97", parse.FrameRate2997},
+		{"59.94", parse.FrameRate{Num: 60000, Den: 1001}},
+		{"24000/1001", parse.FrameRate23976},
+		{"12.5", parse.FrameRate{Num: 25, Den: 2}},
+	}
+	for _, tt := range tests {
+		rate, err := parse.ParseFrameRate(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, rate, tt.input)
+	}
+
+	for _, input := range []string{"", "fast", "0", "-25", "25/0"} {
+		_, err := parse.ParseFrameRate(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseMicroDVD(t *testing.T) {
+	input := "{1}{1}23.976\n{24}{72}{y:i}Hello|/world\n\n{100}{}No end\nnot a cue\n{240000}{240048}Two hours in\n"
+	r := parse.NewMicroDVDReader(strings.NewReader(input), 0, parse.FrameRate{})
+	cues := readCues(t, r)
+	require.Len(t, cues, 3)
+	assert.Equal(t, parse.FrameRate23976, r.FrameRate())
+
+	assert.Equal(t, 1001*time.Millisecond, cues[0].StartTime)
+	assert.Equal(t, 3003*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, []string{"Hello", "world"}, cues[0].Lines)
+	assert.Equal(t, 2, cues[0].Line)
+
+	assert.Equal(t, cues[1].StartTime, cues[1].EndTime)
+	assert.Equal(t, []parse.Diagnostic{{Line: 4, Message: "cue has no end frame"}}, cues[1].Diagnostics)
+
+	// Exact conversion keeps long films in sync: 23.976 as a decimal would
+	// put this cue 10ms late
+	assert.Equal(t, 10010*time.Second, cues[2].StartTime)
+	assert.Equal(t, []parse.Diagnostic{{Line: 5, Message: `line "not a cue" is not a MicroDVD cue and is ignored`}}, cues[2].Diagnostics)
+}
+
+func TestMicroDVDReader_FrameRate(t *testing.T) {
+	t.Run("given rate wins over the header", func(t *testing.T) {
+		r := parse.NewMicroDVDReader(strings.NewReader("{1}{1}23.976\n{25}{50}Hello\n"), 0, parse.FrameRate25)
+		cues := readCues(t, r)
+		require.Len(t, cues, 1)
+		assert.Equal(t, time.Second, cues[0].StartTime)
+		assert.Equal(t, []parse.Diagnostic{{Line: 1, Message: "the file declares 24000/1001 frames per second; 25 is used instead"}}, cues[0].Diagnostics)
+	})
+
+	t.Run("no rate", func(t *testing.T) {
+		r := parse.NewMicroDVDReader(strings.NewReader("{24}{48}Hello\n"), 0, parse.FrameRate{})
+		cues := readCues(t, r)
+		require.Len(t, cues, 1)
+		assert.Equal(t, 1001*time.Millisecond, cues[0].StartTime)
+		assert.Equal(t, []parse.Diagnostic{{Line: 1, Message: "the file declares no frame rate; 24000/1001 (23.976) frames per second is assumed"}}, cues[0].Diagnostics)
+	})
+
+	t.Run("options reach the parser", func(t *testing.T) {
+		doc, err := parse.ParseDocument("-", parse.Options{Stdin: strings.NewReader("{30}{60}Hello\n"), FrameRate: parse.FrameRate30})
+		require.NoError(t, err)
+		assert.Equal(t, parse.FormatMicroDVD, doc.Format)
+		require.Len(t, doc.Captions, 1)
+		assert.Equal(t, time.Second, doc.Captions[0].StartTime)
+		assert.Empty(t, doc.Diagnostics)
+	})
+}
@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+const bilingualSAMI = `<SAMI>
+<HEAD>
+<TITLE>Show</TITLE>
+<STYLE TYPE="text/css">
+<!--
+P { font-family: Arial; }
+.ENCC { Name: English; lang: en-US; SAMIType: CC; }
+.FRCC { Name: French; lang: fr-FR; SAMIType: CC; }
+-->
+</STYLE>
+</HEAD>
+<BODY>
+<SYNC Start=1000><P Class=ENCC>Hello &amp; welcome<br>to the show
+<P class="frcc">Bonjour et bienvenue
+<SYNC Start=4000><P Class=ENCC>&nbsp;
+<SYNC Start=5000><P Class=ENCC><font color=yellow>Second</font> line
+<P Class=FRCC>&nbsp;
+<SYNC Start=8000><P Class=ENCC>&nbsp;
+</BODY>
+</SAMI>
+`
+
+func TestParseSAMI(t *testing.T) {
+	r := parse.NewSAMIReader(strings.NewReader(bilingualSAMI))
+	cues := readCues(t, r)
+	require.Len(t, cues, 3)
+
+	english := cues[0]
+	assert.Equal(t, "ENCC", english.Track)
+	assert.Equal(t, "en-US", english.Language)
+	assert.Equal(t, time.Second, english.StartTime)
+	assert.Equal(t, 4*time.Second, english.EndTime)
+	assert.Equal(t, []string{"Hello & welcome", "to the show"}, english.Lines)
+	assert.Equal(t, 13, english.Line)
+
+	// The French caption stays up until its own class is cleared
+	french := cues[1]
+	assert.Equal(t, "FRCC", french.Track)
+	assert.Equal(t, "fr-FR", french.Language)
+	assert.Equal(t, time.Second, french.StartTime)
+	assert.Equal(t, 5*time.Second, french.EndTime)
+	assert.Equal(t, "Bonjour et bienvenue", french.Text)
+
+	assert.Equal(t, "Second line", cues[2].Text)
+	assert.Equal(t, 8*time.Second, cues[2].EndTime)
+
+	assert.Equal(t, []models.Track{
+		{ID: "ENCC", Name: "English", Language: "en-US"},
+		{ID: "FRCC", Name: "French", Language: "fr-FR"},
+	}, r.Tracks())
+}
+
+func TestSAMIReader_LooseHTML(t *testing.T) {
+	// No STYLE block, unclosed tags, stray comments and a file that ends
+	// with a caption on screen
+	input := "<sami><body>\n<!-- <SYNC Start=0><P Class=X>hidden -->\n<sync start='500'><p class=KRCC>One<P class=KRCC>\n<SYNC Start=oops><P class=KRCC>Ignored\n<SYNC Start=2500><p class=KRCC>Two"
+	r := parse.NewSAMIReader(strings.NewReader(input))
+	cues := readCues(t, r)
+	require.Len(t, cues, 2)
+
+	assert.Equal(t, "One", cues[0].Text)
+	assert.Equal(t, 500*time.Millisecond, cues[0].StartTime)
+	assert.Equal(t, 2500*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, []parse.Diagnostic{{Line: 4, Message: `SYNC start "oops" is not a number of milliseconds; its captions are ignored`}}, cues[0].Diagnostics)
+
+	assert.Equal(t, "Two", cues[1].Text)
+	assert.Equal(t, "KRCC", cues[1].Track)
+	assert.Equal(t, cues[1].StartTime, cues[1].EndTime)
+	assert.Equal(t, []parse.Diagnostic{{Line: 5, Message: "caption is still displayed at the end of the file"}}, cues[1].Diagnostics)
+	assert.Equal(t, []models.Track{{ID: "KRCC"}}, r.Tracks())
+}
+
+func TestParseDocument_SAMITracks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "show.smi")
+	require.NoError(t, os.WriteFile(path, []byte(bilingualSAMI), 0o644))
+
+	doc, err := parse.ParseDocument(path, parse.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, parse.FormatSAMI, doc.Format)
+	assert.False(t, doc.ExtensionMismatch())
+	assert.Len(t, doc.Captions, 3)
+	assert.Len(t, doc.Tracks, 2)
+}
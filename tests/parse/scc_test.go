@@ -0,0 +1,161 @@
+package parse
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// sccWord encodes a CEA-608 byte pair as an SCC hex word with odd parity
+func sccWord(b1, b2 byte) string {
+	parity := func(b byte) byte {
+		if bits.OnesCount8(b)%2 == 0 {
+			return b | 0x80
+		}
+		return b
+	}
+	return fmt.Sprintf("%02x%02x", parity(b1), parity(b2))
+}
+
+// sccControl encodes a control code sent twice, as SCC files do
+func sccControl(b1, b2 byte) string {
+	word := sccWord(b1, b2)
+	return word + " " + word
+}
+
+// sccText encodes text as byte pairs, padding the last pair with a null
+func sccText(text string) string {
+	var words []string
+	for i := 0; i < len(text); i += 2 {
+		b2 := byte(0)
+		if i+1 < len(text) {
+			b2 = text[i+1]
+		}
+		words = append(words, sccWord(text[i], b2))
+	}
+	return strings.Join(words, " ")
+}
+
+var (
+	sccRCL = sccControl(0x14, 0x20)
+	sccRU2 = sccControl(0x14, 0x25)
+	sccCR  = sccControl(0x14, 0x2d)
+	sccEDM = sccControl(0x14, 0x2c)
+	sccENM = sccControl(0x14, 0x2e)
+	sccEOC = sccControl(0x14, 0x2f)
+	sccRDC = sccControl(0x14, 0x29)
+	// sccRow15 and sccRow14 are preamble address codes for the bottom rows
+	sccRow15 = sccControl(0x14, 0x70)
+	sccRow14 = sccControl(0x14, 0x50)
+)
+
+func frames(n int64) time.Duration {
+	return parse.FrameRate2997.Duration(n)
+}
+
+func TestParseSCC_PopOn(t *testing.T) {
+	input := "Scenarist_SCC V1.0\n\n" +
+		"00:00:00:00\t" + strings.Join([]string{sccENM, sccRCL, sccRow14, sccText("Hello"), sccRow15, sccText("world"), sccEOC}, " ") + "\n\n" +
+		"00:00:02:00\t" + strings.Join([]string{sccRCL, sccRow15, sccText("CAFE"), sccControl(0x12, 0x21), sccEDM, sccEOC}, " ") + "\n\n" +
+		"00:00:04:00\t" + sccEDM + "\n"
+
+	captions, err := parse.ParseSCC(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 2)
+
+	// EOC is the 15th pair of the first line
+	assert.Equal(t, frames(14), captions[0].StartTime)
+	assert.Equal(t, []string{"Hello", "world"}, captions[0].Lines)
+	assert.Equal(t, "Hello world", captions[0].Text)
+	assert.Equal(t, 3, captions[0].Line)
+
+	// EDM clears the screen before EOC shows the next caption
+	assert.Equal(t, frames(60+8), captions[0].EndTime)
+	assert.Equal(t, "CAFÉ", captions[1].Text)
+	assert.Equal(t, frames(60+10), captions[1].StartTime)
+	assert.Equal(t, frames(120), captions[1].EndTime)
+}
+
+func TestParseSCC_RollUp(t *testing.T) {
+	input := "Scenarist_SCC V1.0\n\n" +
+		"00:00:01:00\t" + strings.Join([]string{sccRU2, sccCR, sccRow15, sccText("Line one")}, " ") + "\n\n" +
+		"00:00:03:00\t" + strings.Join([]string{sccRU2, sccCR, sccRow15, sccText("Line two")}, " ") + "\n\n" +
+		"00:00:05:00\t" + strings.Join([]string{sccRU2, sccCR, sccRow15, sccText("Line three")}, " ") + "\n\n" +
+		"00:00:07:00\t" + sccEDM + "\n"
+
+	captions, err := parse.ParseSCC(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 3)
+
+	assert.Equal(t, []string{"Line one"}, captions[0].Lines)
+	assert.Equal(t, frames(30), captions[0].StartTime)
+	assert.Equal(t, frames(90+2), captions[0].EndTime)
+	assert.Equal(t, []string{"Line one", "Line two"}, captions[1].Lines)
+	// Two rows are kept, so the first line rolls off
+	assert.Equal(t, []string{"Line two", "Line three"}, captions[2].Lines)
+	assert.Equal(t, frames(210), captions[2].EndTime)
+}
+
+func TestParseSCC_PaintOn(t *testing.T) {
+	input := "Scenarist_SCC V1.0\n\n" +
+		"00:00:00:00\t" + strings.Join([]string{sccRDC, sccRow15, sccText("Paint")}, " ") + "\n" +
+		"00:00:01:00\t" + sccText(" on") + "\n" +
+		"00:00:02:00\t" + sccEDM + "\n"
+
+	captions, err := parse.ParseSCC(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 2)
+	assert.Equal(t, "Paint", captions[0].Text)
+	assert.Equal(t, frames(4), captions[0].StartTime)
+	assert.Equal(t, frames(30), captions[0].EndTime)
+	assert.Equal(t, "Paint on", captions[1].Text)
+	assert.Equal(t, frames(60), captions[1].EndTime)
+}
+
+func TestParseSCC_Timecodes(t *testing.T) {
+	input := "Scenarist_SCC V1.0\n\n" +
+		"00:10:00;00\t" + strings.Join([]string{sccRCL, sccRow15, sccText("Drop frame"), sccEOC}, " ") + "\n\n" +
+		"00:10:05;00\t" + sccEDM + "\n"
+
+	captions, err := parse.ParseSCC(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	// Ten minutes of drop-frame timecode is 17982 frames, which keeps it
+	// within a millisecond of clock time
+	assert.InDelta(t, float64(10*time.Minute), float64(frames(17982)), float64(time.Millisecond))
+	assert.Equal(t, frames(17982+2+2+5), captions[0].StartTime)
+	assert.Equal(t, frames(17982+150), captions[0].EndTime)
+}
+
+func TestSCCReader_Diagnostics(t *testing.T) {
+	input := "Scenarist_SCC V1.0\n\n" +
+		"not a caption line\n" +
+		"00:00:00:00\t" + strings.Join([]string{sccRCL, sccRow15, sccText("Hi"), "zzzz", sccEOC}, " ") + "\n"
+
+	cues := readCues(t, parse.NewSCCReader(strings.NewReader(input), 0))
+	require.Len(t, cues, 1)
+	assert.Equal(t, "Hi", cues[0].Text)
+	assert.Equal(t, []parse.Diagnostic{
+		{Line: 3, Message: `line "not a caption line" is not a timecode followed by byte pairs and is ignored`},
+		{Line: 4, Message: `"zzzz" is not a hex byte pair and is ignored`},
+		{Line: 4, Message: "caption is still displayed at the end of the file"},
+	}, cues[0].Diagnostics)
+	assert.Equal(t, frames(8), cues[0].EndTime)
+}
+
+func TestParseSCC_OnlyChannelOne(t *testing.T) {
+	input := "Scenarist_SCC V1.0\n\n" +
+		"00:00:00:00\t" + strings.Join([]string{sccRCL, sccRow15, sccText("One"), sccControl(0x1c, 0x20), sccText("Two"), sccControl(0x1c, 0x2f), sccEOC}, " ") + "\n\n" +
+		"00:00:02:00\t" + sccEDM + "\n"
+
+	captions, err := parse.ParseSCC(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, "One", captions[0].Text)
+}
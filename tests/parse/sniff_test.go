@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"WebVTT", "WEBVTT\n\n00:00.000 --> 00:01.000\nHi\n", parse.FormatWebVTT},
+		{"WebVTT with BOM and title", "\xef\xbb\xbfWEBVTT - Episode 1\r\n", parse.FormatWebVTT},
+		{"WEBVTT prefix of another word", "WEBVTTX\n", ""},
+		{"SRT", "1\n00:00:01,000 --> 00:00:02,000\nHi\n", parse.FormatSRT},
+		{"SRT with CRLF and leading blank lines", "\r\n\r\n1\r\n00:00:01,000 --> 00:00:02,000\r\nHi\r\n", parse.FormatSRT},
+		{"TTML", `<?xml version="1.0" encoding="UTF-8"?>` + "\n<!-- exported -->\n" + `<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="en">`, parse.FormatTTML},
+		{"prefixed TTML", `<tt:tt xmlns:tt="http://www.w3.org/ns/ttml">`, parse.FormatTTML},
+		{"SAMI", "<SAMI>\n<HEAD>", parse.FormatSAMI},
+		{"MicroDVD", "{1}{1}23.976\n{24}{48}Hello", parse.FormatMicroDVD},
+		{"EBU STL", "850STL25.01\x31" + "00" + "09", parse.FormatSTL},
+		{"Whisper JSON", "{\n  \"text\": \" Hello\",\n  \"segments\": [", parse.FormatASR},
+		{"HLS playlist", "#EXTM3U\n#EXT-X-TARGETDURATION:6\n", parse.FormatHLS},
+		{"JSON array", "[{\"start\": 0.5", parse.FormatASR},
+		{"Matroska", "\x1a\x45\xdf\xa3\x01\x00\x00\x00", parse.FormatMatroska},
+		{"MP4", "\x00\x00\x00\x18ftypisom", parse.FormatMP4},
+		{"SCC", "Scenarist_SCC V1.0\n\n00:00:00:00\t9420", parse.FormatSCC},
+		{"ASS", "[Script Info]\nScriptType: v4.00+\n", parse.FormatASS},
+		{"SBV", "0:00:01.000,0:00:03.000\nHi\n", parse.FormatSBV},
+		{"transcript", "[00:01] Hello\n[00:04] World\n", parse.FormatTranscript},
+		{"transcript with hours", "[1:02:03.5] Hello\n", parse.FormatTranscript},
+		{"other XML", `<?xml version="1.0"?><html>`, ""},
+		{"plain text", "hello world", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parse.Sniff([]byte(tt.content)))
+		})
+	}
+}
+
+func TestParseDocument_Detection(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	vtt := "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n"
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+
+	t.Run("WebVTT named .srt", func(t *testing.T) {
+		doc, err := parse.ParseDocument(write("named.srt", vtt), parse.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, parse.FormatWebVTT, doc.Format)
+		assert.Len(t, doc.Captions, 1)
+		assert.True(t, doc.ExtensionMismatch())
+	})
+
+	t.Run("SRT named .txt", func(t *testing.T) {
+		doc, err := parse.ParseDocument(write("upload.txt", srt), parse.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, parse.FormatSRT, doc.Format)
+		assert.Equal(t, "txt", doc.Extension)
+		assert.True(t, doc.ExtensionMismatch())
+	})
+
+	t.Run("matching extension", func(t *testing.T) {
+		doc, err := parse.ParseDocument(write("ok.srt", srt), parse.Options{})
+		require.NoError(t, err)
+		assert.False(t, doc.ExtensionMismatch())
+	})
+
+	t.Run("unrecognised content falls back to the extension", func(t *testing.T) {
+		doc, err := parse.ParseDocument(write("empty.srt", "\n"), parse.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, parse.FormatSRT, doc.Format)
+		assert.Empty(t, doc.Detected)
+	})
+
+	t.Run("forced format wins over content", func(t *testing.T) {
+		doc, err := parse.ParseDocument(write("forced.vtt", vtt), parse.Options{Format: parse.FormatSRT})
+		require.NoError(t, err)
+		assert.Equal(t, parse.FormatSRT, doc.Format)
+		assert.Equal(t, parse.FormatWebVTT, doc.Detected)
+	})
+
+	t.Run("forced unsupported format", func(t *testing.T) {
+		_, err := parse.ParseDocument(write("show.txt", srt), parse.Options{Format: "docx"})
+		assert.ErrorContains(t, err, "unsupported caption format: docx")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := parse.ParseDocument(write("notes.txt", "hello"), parse.Options{})
+		assert.ErrorIs(t, err, parse.ErrUnknownFormat)
+	})
+}
@@ -0,0 +1,141 @@
+package parse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+const sourceSRT = `1
+00:00:01,000 --> 00:00:03,000
+Hello world
+`
+
+func TestFormatFromPath(t *testing.T) {
+	assert.Equal(t, "srt", parse.FormatFromPath("captions/episode.SRT"))
+	assert.Equal(t, "vtt", parse.FormatFromPath("https://bucket.example.com/a/b.vtt?X-Amz-Signature=abc.srt"))
+	assert.Equal(t, "ass", parse.FormatFromPath("show.ssa"))
+	assert.Equal(t, "", parse.FormatFromPath("notes.txt"))
+	assert.Equal(t, "", parse.FormatFromPath("-"))
+}
+
+func TestIsCaptionFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		format string
+		found  bool
+	}{
+		{"vtt file", "captions.vtt", "vtt", true},
+		{"srt file", "captions.srt", "srt", true},
+		{"vtt uppercase", "captions.VTT", "vtt", true},
+		{"srt uppercase", "captions.SRT", "srt", true},
+		{"with path", "/path/to/captions.vtt", "vtt", true},
+		{"ttml file", "captions.ttml", "ttml", true},
+		{"dfxp uppercase", "captions.DFXP", "ttml", true},
+		{"scc file", "captions.scc", "scc", true},
+		{"ass file", "captions.ass", "ass", true},
+		{"ssa file", "captions.ssa", "ass", true},
+		{"sbv file", "captions.sbv", "sbv", true},
+		{"smi file", "captions.smi", "sami", true},
+		{"sami file", "captions.sami", "sami", true},
+		{"stl file", "captions.stl", "stl", true},
+		{"sub file", "captions.sub", "microdvd", true},
+		{"hls playlist", "subs.m3u8", "hls", true},
+		{"matroska file", "film.mkv", "mkv", true},
+		{"webm file", "clip.webm", "mkv", true},
+		{"mp4 uppercase", "film.MP4", "mp4", true},
+		// Generic extensions are only read when named
+		{"xml file", "captions.xml", "ttml", false},
+		{"json file", "transcript.json", "asr", false},
+		{"txt file", "captions.txt", "", false},
+		{"no extension", "captions", "", false},
+		{"double extension", "captions.vtt.backup", "", false},
+		{"empty path", "", "", false},
+		{"directory", "captions.vtt/", "", false},
+		{"directory with path", "/path/to/subs.srt/", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.format, parse.FormatFromPath(tt.path))
+			assert.Equal(t, tt.found, parse.IsCaptionFile(tt.path))
+		})
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	assert.Equal(t, "https://bucket.example.com/a.vtt", parse.DisplayName("https://bucket.example.com/a.vtt?X-Amz-Signature=secret"))
+	assert.Equal(t, "dir/a?.srt", parse.DisplayName("dir/a?.srt"))
+}
+
+func TestParseInput_Stdin(t *testing.T) {
+	captions, err := parse.ParseInput("-", parse.Options{Format: parse.FormatSRT, Stdin: strings.NewReader(sourceSRT)})
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, "Hello world", captions[0].Text)
+
+	// Without a format, stdin is recognised by its content
+	captions, err = parse.ParseInput("-", parse.Options{Stdin: strings.NewReader(sourceSRT)})
+	require.NoError(t, err)
+	assert.Len(t, captions, 1)
+
+	_, err = parse.ParseInput("-", parse.Options{Stdin: strings.NewReader("hello")})
+	assert.ErrorIs(t, err, parse.ErrUnknownFormat)
+
+	_, err = parse.ParseInput("-", parse.Options{Format: parse.FormatSRT, MaxInputSize: 10, Stdin: strings.NewReader(sourceSRT)})
+	assert.ErrorContains(t, err, "input exceeds the 10 byte limit")
+}
+
+func TestParseInput_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/captions.srt":
+			w.Write([]byte(sourceSRT))
+		case "/chunked.srt":
+			// Flushing forces a chunked response without Content-Length
+			w.Write([]byte(sourceSRT[:10]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(sourceSRT[10:]))
+		case "/slow.srt":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(sourceSRT))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("presigned URL uses the path extension", func(t *testing.T) {
+		captions, err := parse.ParseInput(server.URL+"/captions.srt?X-Amz-Signature=abc", parse.Options{})
+		require.NoError(t, err)
+		require.Len(t, captions, 1)
+		assert.Equal(t, time.Second, captions[0].StartTime)
+	})
+
+	t.Run("non-200 responses are errors", func(t *testing.T) {
+		_, err := parse.ParseInput(server.URL+"/missing.srt?token=secret", parse.Options{})
+		assert.ErrorContains(t, err, "404 Not Found")
+		assert.NotContains(t, err.Error(), "secret")
+	})
+
+	t.Run("Content-Length above the limit is rejected", func(t *testing.T) {
+		_, err := parse.ParseInput(server.URL+"/captions.srt", parse.Options{MaxInputSize: 16})
+		assert.ErrorContains(t, err, "exceeds the 16 byte input limit")
+	})
+
+	t.Run("bodies without Content-Length are limited while reading", func(t *testing.T) {
+		_, err := parse.ParseInput(server.URL+"/chunked.srt", parse.Options{MaxInputSize: 16})
+		assert.ErrorContains(t, err, "input exceeds the 16 byte limit")
+	})
+
+	t.Run("downloads time out", func(t *testing.T) {
+		_, err := parse.ParseInput(server.URL+"/slow.srt", parse.Options{FetchTimeout: 50 * time.Millisecond})
+		assert.Error(t, err)
+	})
+}
@@ -138,6 +138,28 @@ Line 2`
 		assert.Equal(t, "Hello @world! #test <b>HTML</b> \"quotes\" Line 2", captions[0].Text)
 	})
 
+	t.Run("SRT keeps the line structure of each cue", func(t *testing.T) {
+		input := `1
+00:00:01,000 --> 00:00:04,000
+First line
+Second line
+
+2
+00:00:05,000 --> 00:00:06,000
+Only line`
+
+		reader := bufio.NewReader(strings.NewReader(input))
+		captions, err := parse.ParseSRT(reader)
+
+		assert.NoError(t, err)
+		assert.Len(t, captions, 2)
+		assert.Equal(t, "First line Second line", captions[0].Text)
+		assert.Equal(t, []string{"First line", "Second line"}, captions[0].Lines)
+		assert.Equal(t, []string{"Only line"}, captions[1].Lines)
+		assert.Equal(t, 2, captions[0].Line)
+		assert.Equal(t, 7, captions[1].Line)
+	})
+
 	t.Run("SRT with Windows line endings", func(t *testing.T) {
 		input := "1\r\n00:00:01,000 --> 00:00:04,000\r\nHello world\r\n\r\n2\r\n00:00:05,000 --> 00:00:08,000\r\nAnother caption\r\n"
 
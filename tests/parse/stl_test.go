@@ -0,0 +1,117 @@
+package parse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+// stlHeader builds a GSI block with the given disk format code, character
+// code table, language code and start-of-programme timecode
+func stlHeader(dfc, cct, lc, tcp string) []byte {
+	gsi := bytes.Repeat([]byte{' '}, 1024)
+	copy(gsi, "850"+dfc)
+	copy(gsi[12:], cct+lc)
+	copy(gsi[224:], tcp)
+	return gsi
+}
+
+// stlBlock builds a TTI block. in and out are HH, MM, SS and FF.
+func stlBlock(number int, ebn byte, in, out [4]byte, comment bool, text ...byte) []byte {
+	tti := make([]byte, 128)
+	tti[1], tti[2], tti[3] = byte(number), byte(number>>8), ebn
+	copy(tti[5:9], in[:])
+	copy(tti[9:13], out[:])
+	if comment {
+		tti[15] = 1
+	}
+	field := bytes.Repeat([]byte{0x8F}, 112)
+	copy(field, text)
+	copy(tti[16:], field)
+	return tti
+}
+
+func stlFile(header []byte, blocks ...[]byte) []byte {
+	return append(header, bytes.Join(blocks, nil)...)
+}
+
+func TestParseSTL(t *testing.T) {
+	data := stlFile(stlHeader("STL25.01", "00", "0F", "10000000"),
+		// Double height with a blank row between the lines, and ISO 6937
+		// accents placed before their letter
+		stlBlock(1, 0xFF, [4]byte{10, 0, 1, 0}, [4]byte{10, 0, 3, 12}, false,
+			append(append([]byte{0x0D, 'C', 'a', 'f', 0xC2, 'e', 0x8A, 0x8A, 0x0D}, "Gar"...), 0xCB, 'c', 'o', 'n')...),
+		stlBlock(2, 0xFF, [4]byte{10, 0, 4, 0}, [4]byte{10, 0, 5, 0}, true, []byte("A comment")...),
+		// A subtitle continued in an extension block, in italics
+		stlBlock(3, 0x00, [4]byte{10, 0, 6, 0}, [4]byte{10, 0, 8, 0}, false, append([]byte{0x80}, "Long subtitle "...)...),
+		stlBlock(3, 0xFF, [4]byte{10, 0, 6, 0}, [4]byte{10, 0, 8, 0}, false, append([]byte("continued"), 0x81, 0xA9, 'q', 0xBA)...),
+		stlBlock(4, 0x00, [4]byte{10, 0, 9, 0}, [4]byte{10, 0, 10, 0}, false, []byte("Cut short")...),
+		stlBlock(5, 0xFF, [4]byte{10, 0, 11, 0}, [4]byte{10, 0, 70, 0}, false, []byte("Bad time")...),
+	)
+	cues := readCues(t, parse.NewSTLReader(bytes.NewReader(data)))
+	require.Len(t, cues, 4)
+
+	// Times count from the start-of-programme timecode
+	assert.Equal(t, time.Second, cues[0].StartTime)
+	assert.Equal(t, 3480*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, []string{"Café", "Garçon"}, cues[0].Lines)
+	assert.Equal(t, "fr", cues[0].Language)
+
+	assert.Equal(t, "Long subtitle continued‘q”", cues[1].Text)
+	assert.Equal(t, 6*time.Second, cues[1].StartTime)
+
+	assert.Equal(t, "Cut short", cues[2].Text)
+	assert.Equal(t, []parse.Diagnostic{{Message: "subtitle 4 has no last extension block"}}, cues[2].Diagnostics)
+	assert.Equal(t, []parse.Diagnostic{{Message: "subtitle 5: time code out 10:00:70:00 is not valid"}}, cues[3].Diagnostics)
+}
+
+func TestSTLReader_Header(t *testing.T) {
+	t.Run("timecodes written from zero", func(t *testing.T) {
+		data := stlFile(stlHeader("STL30.01", "00", "09", "10000000"),
+			stlBlock(1, 0xFF, [4]byte{0, 0, 1, 15}, [4]byte{0, 0, 2, 0}, false, []byte("Hello")...))
+		cues := readCues(t, parse.NewSTLReader(bytes.NewReader(data)))
+		require.Len(t, cues, 1)
+		assert.Equal(t, 1500*time.Millisecond, cues[0].StartTime)
+		assert.Equal(t, "en", cues[0].Language)
+	})
+
+	t.Run("Cyrillic code table", func(t *testing.T) {
+		data := stlFile(stlHeader("STL25.01", "01", "56", ""),
+			stlBlock(1, 0xFF, [4]byte{0, 0, 1, 0}, [4]byte{0, 0, 2, 0}, false, 0xBF, 0xE0, 0xD8, 0xD2, 0xD5, 0xE2))
+		cues := readCues(t, parse.NewSTLReader(bytes.NewReader(data)))
+		require.Len(t, cues, 1)
+		assert.Equal(t, "Привет", cues[0].Text)
+		assert.Equal(t, "ru", cues[0].Language)
+	})
+
+	t.Run("not an EBU STL file", func(t *testing.T) {
+		_, err := parse.ParseSTL(strings.NewReader("00:00:01:00 , 00:00:02:00 , Spruce subtitle\n"))
+		assert.ErrorContains(t, err, "invalid EBU STL")
+
+		_, err = parse.ParseSTL(bytes.NewReader(stlHeader("STL99.02", "00", "09", "")))
+		assert.ErrorContains(t, err, `disk format code "STL99.02" is not recognised`)
+	})
+}
+
+func TestParseDocument_STL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "programme.stl")
+	data := stlFile(stlHeader("STL25.01", "00", "08", "00000000"),
+		stlBlock(1, 0xFF, [4]byte{0, 0, 1, 0}, [4]byte{0, 0, 2, 0}, false, append([]byte("Gr"), 0xC8, 'u', 0xFB, 'e')...))
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	// The binary content reaches the parser as it is, not decoded as text
+	doc, err := parse.ParseDocument(path, parse.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, parse.FormatSTL, doc.Format)
+	assert.Equal(t, "ISO 6937", doc.Encoding)
+	assert.Equal(t, "de", doc.Language)
+	require.Len(t, doc.Captions, 1)
+	assert.Equal(t, "Grüße", doc.Captions[0].Text)
+}
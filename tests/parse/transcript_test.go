@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func TestParseSBV(t *testing.T) {
+	input := "0:00:01.000,0:00:03.500\nHello\nworld\n\n0:00:04.000,0:00:06.000\nSecond cue\n\nnot a timing\nDropped\n\n1:00:00.250,1:00:02.000\nLast"
+	cues := readCues(t, parse.NewSBVReader(strings.NewReader(input), 0))
+	require.Len(t, cues, 3)
+
+	assert.Equal(t, time.Second, cues[0].StartTime)
+	assert.Equal(t, 3500*time.Millisecond, cues[0].EndTime)
+	assert.Equal(t, []string{"Hello", "world"}, cues[0].Lines)
+	assert.Equal(t, 1, cues[0].Line)
+	assert.Equal(t, "Second cue", cues[1].Text)
+
+	assert.Equal(t, time.Hour+250*time.Millisecond, cues[2].StartTime)
+	assert.Equal(t, "Last", cues[2].Text)
+	assert.Equal(t, []parse.Diagnostic{{Line: 8, Message: `expected a timing line, found "not a timing"; the block is ignored`}}, cues[2].Diagnostics)
+}
+
+func TestParseTranscript(t *testing.T) {
+	input := "[00:01] Hello and welcome\n[00:04] to the show,\nwhich continues here\n\n[01:00:10.5] An hour in\n"
+	captions, err := parse.ParseTranscript(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 3)
+
+	assert.Equal(t, time.Second, captions[0].StartTime)
+	assert.Equal(t, 4*time.Second, captions[0].EndTime)
+	assert.Equal(t, "Hello and welcome", captions[0].Text)
+
+	assert.Equal(t, []string{"to the show,", "which continues here"}, captions[1].Lines)
+	assert.Equal(t, time.Hour+10500*time.Millisecond, captions[1].EndTime)
+	assert.Equal(t, 2, captions[1].Line)
+
+	// The last entry lasts as long as the others on average
+	average := (3*time.Second + captions[1].EndTime - captions[1].StartTime) / 2
+	assert.Equal(t, captions[2].StartTime+average, captions[2].EndTime)
+}
+
+func TestTranscriptReader_Diagnostics(t *testing.T) {
+	input := "Preamble without a timestamp\n[00:05] First\n[00:03] Earlier\n"
+	cues := readCues(t, parse.NewTranscriptReader(strings.NewReader(input), 0))
+	require.Len(t, cues, 2)
+	assert.Equal(t, []parse.Diagnostic{{Line: 1, Message: `text "Preamble without a timestamp" has no timestamp before it and is ignored`}}, cues[0].Diagnostics)
+	// With no entry of positive duration, the last one cannot be given an
+	// average
+	assert.Equal(t, []parse.Diagnostic{
+		{Line: 3, Message: `timestamp "[00:03]" is earlier than the one before it`},
+		{Line: 3, Message: "entry has no end time"},
+	}, cues[1].Diagnostics)
+
+	cues = readCues(t, parse.NewTranscriptReader(strings.NewReader("[00:05] Only\n"), 0))
+	require.Len(t, cues, 1)
+	assert.Equal(t, []parse.Diagnostic{{Line: 1, Message: "entry has no end time"}}, cues[0].Diagnostics)
+}
+
+func TestParseDocument_Transcript(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("[00:01] Hello\n[00:03] World\n"), 0o644))
+		return path
+	}
+
+	// Transcripts have no extension of their own
+	doc, err := parse.ParseDocument(write("talk.txt"), parse.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, parse.FormatTranscript, doc.Format)
+	assert.False(t, doc.ExtensionMismatch())
+	assert.Len(t, doc.Captions, 2)
+
+	doc, err = parse.ParseDocument(write("talk.srt"), parse.Options{})
+	require.NoError(t, err)
+	assert.True(t, doc.ExtensionMismatch())
+}
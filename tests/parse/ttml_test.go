@@ -0,0 +1,139 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/parse"
+)
+
+func TestParseTTML(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter"
+    ttp:frameRate="30" ttp:frameRateMultiplier="1000 1001" ttp:tickRate="10000000" xml:lang="en">
+  <head>
+    <metadata><ttm:title xmlns:ttm="http://www.w3.org/ns/ttml#metadata">Episode 1</ttm:title></metadata>
+  </head>
+  <body>
+    <div begin="10s">
+      <p begin="00:00:01.500" end="00:00:03.000">Hello<br/>
+        <span tts:fontStyle="italic" xmlns:tts="http://www.w3.org/ns/ttml#styling">world</span></p>
+      <p begin="00:00:04:15" dur="2s" xml:lang="fr">Bonjour   tout
+        le monde</p>
+    </div>
+    <div xml:lang="de">
+      <p begin="30000000t" end="4s">Hallo</p>
+      <p>
+        <span begin="5s" end="6s">Rolling</span>
+        <span begin="6s" end="7.25s">caption</span>
+      </p>
+      <p begin="100ms" end="8s"></p>
+    </div>
+  </body>
+</tt>`
+
+	captions, err := parse.ParseTTML(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 4)
+
+	assert.Equal(t, 11500*time.Millisecond, captions[0].StartTime)
+	assert.Equal(t, 13*time.Second, captions[0].EndTime)
+	assert.Equal(t, []string{"Hello", "world"}, captions[0].Lines)
+	assert.Equal(t, "Hello world", captions[0].Text)
+	assert.Equal(t, "en", captions[0].Language)
+	assert.Equal(t, 9, captions[0].Line)
+
+	// 15 frames at 29.97 fps
+	assert.Equal(t, 14*time.Second+500500*time.Microsecond, captions[1].StartTime)
+	assert.Equal(t, captions[1].StartTime+2*time.Second, captions[1].EndTime)
+	assert.Equal(t, "Bonjour tout le monde", captions[1].Text)
+	assert.Equal(t, "fr", captions[1].Language)
+
+	assert.Equal(t, 3*time.Second, captions[2].StartTime)
+	assert.Equal(t, 4*time.Second, captions[2].EndTime)
+	assert.Equal(t, "de", captions[2].Language)
+
+	// An untimed paragraph spans its timed spans
+	assert.Equal(t, 5*time.Second, captions[3].StartTime)
+	assert.Equal(t, 7250*time.Millisecond, captions[3].EndTime)
+	assert.Equal(t, "Rolling caption", captions[3].Text)
+}
+
+func TestParseTTML_TimeExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   string
+		begin    string
+		expected time.Duration
+	}{
+		{"clock time", ``, "01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"clock time with fraction", ``, "00:00:01.25", 1250 * time.Millisecond},
+		{"frames at the default 30 fps", ``, "00:00:01:15", 1500 * time.Millisecond},
+		{"frames and sub-frames", `ttp:frameRate="25" ttp:subFrameRate="2"`, "00:00:00:10.1", 420 * time.Millisecond},
+		{"hours", ``, "1.5h", 90 * time.Minute},
+		{"minutes", ``, "2m", 2 * time.Minute},
+		{"milliseconds", ``, "250ms", 250 * time.Millisecond},
+		{"frames", `ttp:frameRate="24" ttp:frameRateMultiplier="1000 1001"`, "24f", 1001 * time.Millisecond},
+		{"ticks", `ttp:tickRate="90000"`, "180000t", 2 * time.Second},
+		{"ticks default to the frame rate", `ttp:frameRate="25"`, "50t", 2 * time.Second},
+		{"ticks default to seconds", ``, "3t", 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" ` + tt.params +
+				`><body><div><p begin="` + tt.begin + `" dur="1s">Text</p></div></body></tt>`
+			captions, err := parse.ParseTTML(strings.NewReader(input))
+			require.NoError(t, err)
+			require.Len(t, captions, 1)
+			assert.Equal(t, tt.expected, captions[0].StartTime)
+		})
+	}
+}
+
+func TestTTMLReader_Diagnostics(t *testing.T) {
+	input := `<tt xmlns="http://www.w3.org/ns/ttml"><body><div>
+<p begin="soon" end="2s">Bad begin</p>
+<p begin="3s">No end</p>
+</div></body></tt>`
+
+	cues := readCues(t, parse.NewTTMLReader(strings.NewReader(input)))
+	require.Len(t, cues, 2)
+	assert.Equal(t, []parse.Diagnostic{{Line: 2, Message: `begin time "soon" is not recognised and is ignored`}}, cues[0].Diagnostics)
+	assert.Equal(t, 2*time.Second, cues[0].EndTime)
+	assert.Equal(t, []parse.Diagnostic{{Line: 3, Message: "cue has no end time"}}, cues[1].Diagnostics)
+	assert.Equal(t, 3*time.Second, cues[1].EndTime)
+
+	captions, err := parse.ParseTTML(strings.NewReader(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<tt><body><p begin="1s" end="2s">Caf&eacute;&nbsp;au lait</p></body></tt>`))
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, "Café au lait", captions[0].Text)
+
+	_, err = parse.ParseTTML(strings.NewReader(`<tt><body><p begin="1s" end="2s">Unclosed</body></tt>`))
+	assert.ErrorContains(t, err, "invalid TTML")
+}
+
+func TestParseTTML_ClippedToParent(t *testing.T) {
+	input := `<tt xmlns="http://www.w3.org/ns/ttml"><body><div begin="1s" end="5s">
+<p begin="3s" end="10s">Clipped</p>
+</div></body></tt>`
+
+	captions, err := parse.ParseTTML(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, captions, 1)
+	assert.Equal(t, 4*time.Second, captions[0].StartTime)
+	assert.Equal(t, 5*time.Second, captions[0].EndTime)
+}
+
+func TestFrameRate(t *testing.T) {
+	assert.Equal(t, 1001*time.Millisecond, parse.FrameRate2997.Duration(30))
+	assert.Equal(t, time.Hour+3600*time.Millisecond, parse.FrameRate2997.Duration(108000))
+	assert.Equal(t, 40*time.Millisecond, parse.FrameRate25.Duration(1))
+	assert.Equal(t, "30000/1001", parse.FrameRate2997.String())
+	assert.Equal(t, "25", parse.FrameRate25.String())
+	assert.False(t, parse.FrameRate{}.Valid())
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/report"
+)
+
+func TestWriteHTML(t *testing.T) {
+	r := sampleReport()
+	r.Start = 0
+	r.End = 20 * time.Second
+	r.Cues = []models.CaptionEntry{
+		{StartTime: 0, EndTime: 5 * time.Second, Text: "<b>Hello</b>"},
+		{StartTime: 10 * time.Second, EndTime: 10400 * time.Millisecond, Text: "Hi"},
+	}
+	r.LanguageWindows = []models.LanguageWindow{
+		{Start: 0, End: 10 * time.Second, Language: "en-US", Matches: true},
+		{Start: 10 * time.Second, End: 20 * time.Second, Language: "fr-FR"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteHTML(&buf, r))
+	output := buf.String()
+
+	assert.Contains(t, output, "<!DOCTYPE html>")
+	assert.Contains(t, output, "<title>Caption validation: captions/episode.srt</title>")
+	// Self-contained: no external scripts or stylesheets
+	assert.NotContains(t, output, "src=")
+	assert.NotContains(t, output, "<link")
+
+	// Cue text is escaped
+	assert.Contains(t, output, "&lt;b&gt;Hello&lt;/b&gt;")
+	assert.NotContains(t, output, "<b>Hello</b>")
+
+	// Cue bars and gaps are positioned as percentages of the range
+	assert.Contains(t, output, `id="cue-1" style="left: 0.0000%; width: 25.0000%"`)
+	assert.Contains(t, output, `class="bar gap" style="left: 25.0000%; width: 25.0000%"`)
+	assert.Contains(t, output, `class="bar gap" style="left: 52.0000%; width: 48.0000%"`)
+
+	// Language windows
+	assert.Contains(t, output, `class="bar window match"`)
+	assert.Contains(t, output, `class="bar window mismatch"`)
+
+	// Findings with a location get a marker linked to their row
+	assert.Contains(t, output, `class="bar marker warning" data-finding="finding-2" style="left: 50.0000%; width: 2.0000%"`)
+	assert.Contains(t, output, `<tr class="finding" id="finding-2" data-cue="cue-3">`)
+	assert.Contains(t, output, `<tr class="finding" id="finding-1" data-cue="">`)
+	assert.NotContains(t, output, `data-finding="finding-1"`)
+}
+
+func TestWriteHTMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, report.WriteHTMLFile(path, sampleReport()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Findings (2)")
+}
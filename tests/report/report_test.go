@@ -0,0 +1,258 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/report"
+)
+
+func sampleReport() *models.Report {
+	return &models.Report{
+		File:   "captions/episode.srt",
+		Format: "srt",
+		Config: models.ReportConfig{
+			Start:    "0s",
+			End:      "5m0s",
+			Endpoint: "http://localhost/detect",
+			Language: "en-US",
+			FailOn:   models.SeverityError,
+			Rules:    models.Rules{Coverage: 0.8, MinDuration: 833 * time.Millisecond, MaxDuration: 7 * time.Second},
+		},
+		Metrics: models.Metrics{CueCount: 12, Coverage: 0.5, DetectedLanguage: "en-US"},
+		Checks:  []string{models.CheckParse, models.CheckCoverage, models.CheckCueDuration, models.CheckLanguage},
+		Findings: []models.ValidationError{
+			{
+				Type:        "insufficient_coverage",
+				Description: "Captions do not cover required 80.0% of time range 0s to 5m0s",
+				Code:        models.CodeCoverage,
+				Severity:    models.SeverityError,
+			},
+			{
+				Type:        "cue_too_short",
+				Description: "Cue 3 (00:00:10.000 --> 00:00:10.400) is displayed for 400ms, min 833ms: \"Hi\"",
+				Code:        models.CodeCueTooShort,
+				Severity:    models.SeverityWarning,
+				Location:    &models.Location{Cue: 3, Start: 10 * time.Second, End: 10400 * time.Millisecond, Line: 10},
+			},
+		},
+		Verdict: models.VerdictFail,
+		Reason:  "findings at or above error severity",
+	}
+}
+
+func TestWrite_JSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, report.FormatJSONLines, sampleReport()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], `{"type":"insufficient_coverage"`))
+	assert.True(t, strings.HasSuffix(lines[1], `"code":"CV501","severity":"warning","location":{"cue":3,"start_ms":10000,"end_ms":10400,"line":10}}`), lines[1])
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, report.FormatJSON, sampleReport()))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "captions/episode.srt", doc["file"])
+	assert.Equal(t, "fail", doc["verdict"])
+	assert.Len(t, doc["findings"], 2)
+
+	config := doc["config"].(map[string]interface{})
+	rules := config["rules"].(map[string]interface{})
+	assert.Equal(t, "833ms", rules["min_duration"])
+	assert.Equal(t, "7s", rules["max_duration"])
+
+	metrics := doc["metrics"].(map[string]interface{})
+	assert.Equal(t, 12.0, metrics["cue_count"])
+	assert.Equal(t, "en-US", metrics["detected_language"])
+}
+
+func TestWrite_JSONWithoutFindings(t *testing.T) {
+	r := sampleReport()
+	r.Findings = nil
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, report.FormatJSON, r))
+	assert.Contains(t, buf.String(), `"findings": []`)
+}
+
+func TestWrite_JUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, report.FormatJUnit, sampleReport()))
+	assert.True(t, strings.HasPrefix(buf.String(), "<?xml"))
+
+	var doc struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Type string `xml:"type,attr"`
+					Body string `xml:",chardata"`
+				} `xml:"failure"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, 4, doc.Tests)
+	assert.Equal(t, 1, doc.Failures)
+	require.Len(t, doc.Suites, 1)
+	assert.Equal(t, "captions/episode.srt", doc.Suites[0].Name)
+
+	cases := doc.Suites[0].Cases
+	require.Len(t, cases, 4)
+	assert.Equal(t, "coverage", cases[1].Name)
+	require.NotNil(t, cases[1].Failure)
+	assert.Equal(t, models.CodeCoverage, cases[1].Failure.Type)
+
+	// Warnings below --fail-on do not fail the test case
+	assert.Equal(t, "cue_duration", cases[2].Name)
+	assert.Nil(t, cases[2].Failure)
+	assert.Contains(t, cases[2].SystemOut, "CV501")
+}
+
+func TestWrite_SARIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, report.FormatSARIF, sampleReport()))
+
+	var doc struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, "2.1.0", doc.Version)
+	require.Len(t, doc.Runs, 1)
+	run := doc.Runs[0]
+	assert.Equal(t, "caption-validator", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, models.CodeCoverage, run.Tool.Driver.Rules[0].ID)
+
+	require.Len(t, run.Results, 2)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, 1, run.Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "warning", run.Results[1].Level)
+	assert.Equal(t, 10, run.Results[1].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "captions/episode.srt", run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	assert.ErrorContains(t, report.Write(&buf, "yaml", sampleReport()), "unsupported output format")
+	assert.False(t, report.IsValidFormat("yaml"))
+}
+
+func sampleBatch() *models.BatchReport {
+	passing := &models.Report{
+		File:    "captions/intro.srt",
+		Config:  models.ReportConfig{Language: "en-US", FailOn: models.SeverityError},
+		Checks:  []string{models.CheckParse, models.CheckCoverage},
+		Verdict: models.VerdictPass,
+	}
+	return &models.BatchReport{
+		Files:   []*models.Report{sampleReport(), passing},
+		Totals:  models.BatchTotals{Files: 2, Passed: 1, Failed: 1, Findings: 2},
+		Verdict: models.VerdictFail,
+		Reason:  "1 of 2 files failed validation",
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	t.Run("jsonl tags findings with their file", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteBatch(&buf, report.FormatJSONLines, sampleBatch()))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], `{"file":"captions/episode.srt","type":"insufficient_coverage"`))
+	})
+
+	t.Run("json has files and totals", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteBatch(&buf, report.FormatJSON, sampleBatch()))
+		var doc struct {
+			Files []struct {
+				File     string            `json:"file"`
+				Findings []json.RawMessage `json:"findings"`
+			} `json:"files"`
+			Totals  models.BatchTotals `json:"totals"`
+			Verdict string             `json:"verdict"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+		require.Len(t, doc.Files, 2)
+		assert.Len(t, doc.Files[0].Findings, 2)
+		assert.NotNil(t, doc.Files[1].Findings)
+		assert.Equal(t, 2, doc.Totals.Files)
+		assert.Equal(t, "fail", doc.Verdict)
+	})
+
+	t.Run("junit has one suite per file", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteBatch(&buf, report.FormatJUnit, sampleBatch()))
+		output := buf.String()
+		assert.Contains(t, output, `<testsuites name="caption-validator" tests="6" failures="1" errors="0">`)
+		assert.Contains(t, output, `<testsuite name="captions/episode.srt"`)
+		assert.Contains(t, output, `<testsuite name="captions/intro.srt" tests="2" failures="0" errors="0">`)
+	})
+
+	t.Run("sarif has a single run", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteBatch(&buf, report.FormatSARIF, sampleBatch()))
+		var log struct {
+			Runs []struct {
+				Results []json.RawMessage `json:"results"`
+			} `json:"runs"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+		require.Len(t, log.Runs, 1)
+		assert.Len(t, log.Runs[0].Results, 2)
+	})
+
+	t.Run("text ends with totals", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteBatchText(&buf, sampleBatch(), false))
+		output := buf.String()
+		assert.Contains(t, output, "File:     captions/episode.srt\n")
+		assert.Contains(t, output, "File:     captions/intro.srt\n")
+		assert.Contains(t, output, "Files:    2 (1 passed, 1 failed, 0 errors)\n")
+		assert.Contains(t, output, "Findings: 2\n")
+		assert.True(t, strings.HasSuffix(output, "Verdict:  FAIL - 1 of 2 files failed validation\n"))
+	})
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/report"
+)
+
+func TestWriteText(t *testing.T) {
+	r := sampleReport()
+	r.Findings[1].Location.Excerpt = "Hi"
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteText(&buf, r, false))
+	output := buf.String()
+
+	assert.Contains(t, output, "File:     captions/episode.srt\n")
+	assert.Contains(t, output, "Format:   srt\n")
+	assert.Contains(t, output, "Cues:     12\n")
+	assert.Contains(t, output, "Coverage: 50.0% (required 80.0%)\n")
+	assert.Contains(t, output, "Language: en-US (expected en-US)\n")
+	assert.Contains(t, output, "Verdict:  FAIL - findings at or above error severity\n")
+	assert.Contains(t, output, "insufficient_coverage (CV200) 1 finding\n")
+	assert.Contains(t, output, `  warning  00:00:10.000 --> 00:00:10.400  cue 3  "Hi"`)
+	assert.NotContains(t, output, "\033[")
+
+	// Groups are ordered by code
+	assert.Less(t, strings.Index(output, "CV200"), strings.Index(output, "CV501"))
+}
+
+func TestWriteText_GroupsByRule(t *testing.T) {
+	r := sampleReport()
+	extra := r.Findings[1]
+	extra.Location = &models.Location{Cue: 5}
+	r.Findings = append(r.Findings, extra)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteText(&buf, r, false))
+	assert.Contains(t, buf.String(), "cue_too_short (CV501) 2 findings\n")
+	assert.Equal(t, 1, strings.Count(buf.String(), "cue_too_short (CV501)"))
+}
+
+func TestWriteText_Color(t *testing.T) {
+	r := sampleReport()
+	r.Findings = nil
+	r.Verdict = models.VerdictPass
+	r.Reason = ""
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteText(&buf, r, true))
+	assert.Contains(t, buf.String(), "\033[32m\033[1mPASS\033[0m")
+	assert.Contains(t, buf.String(), "No findings")
+}
+
+func TestUseColor(t *testing.T) {
+	assert.False(t, report.UseColor(&bytes.Buffer{}))
+
+	file, err := os.CreateTemp(t.TempDir(), "report")
+	require.NoError(t, err)
+	defer file.Close()
+	assert.False(t, report.UseColor(file), "regular files are not terminals")
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+func TestCountChars(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected int
+	}{
+		{"ascii", "Hello world", 11},
+		{"accented latin", "Café à la crème", 15},
+		{"combining marks are not counted", "Cafe\u0301", 4},
+		{"japanese full-width", "こんにちは、世界", 8},
+		{"full-width latin", "ＡＢＣ", 3},
+		{"chinese", "你好世界", 4},
+		{"formatting tags are ignored", "<i>Hello</i> <b>world</b>", 11},
+		{"ass override blocks are ignored", `{\an8}Hello`, 5},
+		{"empty line", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.CountChars(tt.line))
+		})
+	}
+}
+
+func TestLineLimitsFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		lang      string
+		defaults  models.LineLimits
+		overrides map[string]models.LineLimits
+		expected  models.LineLimits
+	}{
+		{"default for english", "en-US", models.LineLimits{}, nil, models.LineLimits{MaxCharsPerLine: 42, MaxLines: 2}},
+		{"primary subtag lookup", "ja-JP", models.LineLimits{}, nil, models.LineLimits{MaxCharsPerLine: 13, MaxLines: 2}},
+		{"case and separator insensitive", "ZH_Hant", models.LineLimits{}, nil, models.LineLimits{MaxCharsPerLine: 16, MaxLines: 2}},
+		{
+			"override replaces built-in",
+			"ja",
+			models.LineLimits{},
+			map[string]models.LineLimits{"ja": {MaxCharsPerLine: 14}},
+			models.LineLimits{MaxCharsPerLine: 14, MaxLines: 2},
+		},
+		{
+			"full tag override wins over primary subtag",
+			"de-CH",
+			models.LineLimits{},
+			map[string]models.LineLimits{"de": {MaxCharsPerLine: 40}, "de-ch": {MaxCharsPerLine: 38, MaxLines: 3}},
+			models.LineLimits{MaxCharsPerLine: 38, MaxLines: 3},
+		},
+		{"profile limits replace the default", "en", models.LineLimits{MaxCharsPerLine: 37}, nil, models.LineLimits{MaxCharsPerLine: 37, MaxLines: 2}},
+		{"built-in language limits win over the profile", "ja", models.LineLimits{MaxCharsPerLine: 37, MaxLines: 3}, nil, models.LineLimits{MaxCharsPerLine: 13, MaxLines: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.LineLimitsFor(tt.lang, tt.defaults, tt.overrides))
+		})
+	}
+}
+
+func TestValidateLineLimits(t *testing.T) {
+	limits := models.LineLimits{MaxCharsPerLine: 42, MaxLines: 2}
+
+	t.Run("cues within limits pass", func(t *testing.T) {
+		captions := []models.CaptionEntry{
+			{StartTime: 0, EndTime: time.Second, Text: "Hello there", Lines: []string{"Hello", "there"}},
+		}
+		assert.Empty(t, utils.ValidateLineLimits(captions, limits))
+	})
+
+	t.Run("long line is reported with cue details", func(t *testing.T) {
+		line := "This line is far too long to fit on a television screen"
+		captions := []models.CaptionEntry{
+			{StartTime: 0, EndTime: time.Second, Text: "Short", Lines: []string{"Short"}},
+			{StartTime: 2 * time.Second, EndTime: 4 * time.Second, Text: line, Lines: []string{line}},
+		}
+
+		captions[1].Line = 6
+
+		errs := utils.ValidateLineLimits(captions, limits)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "line_too_long", errs[0].Type)
+		assert.Equal(t, models.CodeLineTooLong, errs[0].Code)
+		assert.Equal(t, models.SeverityWarning, errs[0].Severity)
+		assert.Equal(t, &models.Location{
+			Cue:     2,
+			Start:   2 * time.Second,
+			End:     4 * time.Second,
+			Line:    7,
+			Excerpt: "This line is far too long to fit on a t…",
+		}, errs[0].Location)
+		assert.Contains(t, errs[0].Description, "Cue 2 (00:00:02.000 --> 00:00:04.000) line 1 has 55 characters, max 42")
+	})
+
+	t.Run("too many lines", func(t *testing.T) {
+		captions := []models.CaptionEntry{
+			{StartTime: 0, EndTime: time.Second, Text: "one two three", Lines: []string{"one", "two", "three"}},
+		}
+
+		errs := utils.ValidateLineLimits(captions, limits)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "too_many_lines", errs[0].Type)
+	})
+
+	t.Run("cjk limits count characters not bytes", func(t *testing.T) {
+		jaLimits := utils.LineLimitsFor("ja", models.LineLimits{}, nil)
+		captions := []models.CaptionEntry{
+			{StartTime: 0, EndTime: time.Second, Text: "今日はいい天気ですね", Lines: []string{"今日はいい天気ですね"}},
+			{StartTime: time.Second, EndTime: 2 * time.Second, Text: "今日はとてもいい天気ですね、散歩", Lines: []string{"今日はとてもいい天気ですね、散歩"}},
+		}
+
+		errs := utils.ValidateLineLimits(captions, jaLimits)
+		assert.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Description, "Cue 2")
+		assert.Contains(t, errs[0].Description, "16 characters, max 13")
+	})
+
+	t.Run("cues without lines fall back to text", func(t *testing.T) {
+		captions := []models.CaptionEntry{
+			{StartTime: 0, EndTime: time.Second, Text: "This caption was built without line information at all"},
+		}
+		assert.Len(t, utils.ValidateLineLimits(captions, limits), 1)
+	})
+
+	t.Run("zero limits disable the checks", func(t *testing.T) {
+		captions := []models.CaptionEntry{
+			{StartTime: 0, EndTime: time.Second, Text: "a b c", Lines: []string{"a", "b", "c"}},
+		}
+		assert.Empty(t, utils.ValidateLineLimits(captions, models.LineLimits{}))
+	})
+}
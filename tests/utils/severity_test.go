@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+func TestApplySeverities(t *testing.T) {
+	findings := []models.ValidationError{
+		{Type: "line_too_long", Code: models.CodeLineTooLong, Severity: models.SeverityWarning},
+		{Type: "cue_overlap", Code: models.CodeCueOverlap, Severity: models.SeverityWarning},
+		{Type: "cue_too_short", Code: models.CodeCueTooShort, Severity: models.SeverityWarning},
+	}
+
+	result := utils.ApplySeverities(findings, map[string]models.Severity{
+		models.CodeLineTooLong: models.SeverityError,
+		"cue_too_short":        models.SeverityInfo,
+		models.CodeCueOverlap:  models.SeverityNone,
+	})
+
+	require.Len(t, result, 2)
+	assert.Equal(t, models.SeverityError, result[0].Severity)
+	assert.Equal(t, models.SeverityInfo, result[1].Severity)
+	assert.Equal(t, models.SeverityWarning, findings[0].Severity, "input is not modified")
+}
+
+func TestFailsThreshold(t *testing.T) {
+	warnings := []models.ValidationError{
+		{Severity: models.SeverityInfo},
+		{Severity: models.SeverityWarning},
+	}
+
+	tests := []struct {
+		name     string
+		findings []models.ValidationError
+		failOn   models.Severity
+		expected bool
+	}{
+		{"no findings", nil, models.SeverityInfo, false},
+		{"warnings below error threshold", warnings, models.SeverityError, false},
+		{"warnings at warning threshold", warnings, models.SeverityWarning, true},
+		{"info threshold", warnings, models.SeverityInfo, true},
+		{"none never fails", []models.ValidationError{{Severity: models.SeverityError}}, models.SeverityNone, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.FailsThreshold(tt.findings, tt.failOn))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+func TestCueChecks_MatchBatchValidation(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: 0, EndTime: 500 * time.Millisecond, Text: "Hi"},
+		{StartTime: 400 * time.Millisecond, EndTime: 2 * time.Second, Text: "<b>Overlapping</b> and a fairly long line of caption text here"},
+		{StartTime: 2100 * time.Millisecond, EndTime: 12 * time.Second, Text: "Too long", Lines: []string{"one", "two", "three"}},
+		{StartTime: 13 * time.Second, EndTime: 14 * time.Second, Text: "Fine"},
+	}
+
+	tests := []struct {
+		name  string
+		check utils.CueCheck
+		want  []models.ValidationError
+	}{
+		{"line limits", utils.NewLineLimitsCheck(utils.DefaultLineLimits), utils.ValidateLineLimits(captions, utils.DefaultLineLimits)},
+		{"durations", utils.NewDurationCheck(time.Second, 7*time.Second), utils.ValidateCueDurations(captions, time.Second, 7*time.Second)},
+		{"gaps", utils.NewGapCheck(200 * time.Millisecond), utils.ValidateGaps(captions, 200*time.Millisecond)},
+		{"reading speed", utils.NewReadingSpeedCheck(20), utils.ValidateReadingSpeed(captions, 20)},
+		{"tags", utils.NewTagCheck([]string{"i"}), utils.ValidateTags(captions, []string{"i"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotEmpty(t, tt.want)
+			for i, caption := range captions {
+				tt.check.Add(i, caption)
+			}
+			assert.Equal(t, tt.want, tt.check.Findings())
+		})
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	coverage := utils.NewCoverage(0, 10*time.Second)
+	assert.Equal(t, 0.0, coverage.Ratio())
+	coverage.Add(models.CaptionEntry{StartTime: -time.Second, EndTime: 2 * time.Second})
+	coverage.Add(models.CaptionEntry{StartTime: 8 * time.Second, EndTime: 12 * time.Second})
+	assert.InDelta(t, 0.4, coverage.Ratio(), 1e-9)
+	assert.True(t, coverage.Meets(0.4))
+	assert.False(t, coverage.Meets(0.5))
+
+	assert.False(t, utils.NewCoverage(time.Second, time.Second).Meets(0))
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+func TestCaptionTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"no tags", "Hello world", nil},
+		{"simple tags", "<i>Hello</i> <b>world</b>", []string{"i", "b"}},
+		{"tags with attributes", `<font color="red">Alert</font>`, []string{"font"}},
+		{"webvtt spans", "<v Roger>Hi <c.yellow>there</c>", []string{"v", "c"}},
+		{"case insensitive", "<I>Loud</I>", []string{"i"}},
+		{"timestamps are not tags", "Karaoke <00:00:01.000>style", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.CaptionTags(tt.text))
+		})
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: 0, EndTime: time.Second, Text: "<i>Thinking</i>"},
+		{StartTime: time.Second, EndTime: 2 * time.Second, Text: `<font color="red">Danger</font>`},
+		{StartTime: 2 * time.Second, EndTime: 3 * time.Second, Text: "Plain"},
+	}
+
+	t.Run("nil list allows everything", func(t *testing.T) {
+		assert.Empty(t, utils.ValidateTags(captions, nil))
+	})
+
+	t.Run("disallowed tags are reported", func(t *testing.T) {
+		errs := utils.ValidateTags(captions, []string{"i"})
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "tag_not_allowed", errs[0].Type)
+		assert.Contains(t, errs[0].Description, "Cue 2")
+		assert.Contains(t, errs[0].Description, "<font>")
+	})
+
+	t.Run("empty list allows no tags", func(t *testing.T) {
+		assert.Len(t, utils.ValidateTags(captions, []string{}), 2)
+	})
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+func TestCoverageGaps(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: 5 * time.Second, EndTime: 8 * time.Second},
+		{StartTime: 1 * time.Second, EndTime: 3 * time.Second},
+		{StartTime: 2 * time.Second, EndTime: 4 * time.Second},
+		{StartTime: 8 * time.Second, EndTime: 12 * time.Second},
+	}
+
+	gaps := utils.CoverageGaps(captions, 0, 10*time.Second)
+	assert.Equal(t, []models.Interval{
+		{Start: 0, End: 1 * time.Second},
+		{Start: 4 * time.Second, End: 5 * time.Second},
+	}, gaps)
+
+	assert.Equal(t, []models.Interval{{Start: 0, End: 10 * time.Second}}, utils.CoverageGaps(nil, 0, 10*time.Second))
+}
+
+func TestOverlaps(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: 0, EndTime: 2 * time.Second},
+		{StartTime: 2 * time.Second, EndTime: 4 * time.Second},
+		{StartTime: 3 * time.Second, EndTime: 6 * time.Second},
+		{StartTime: 5 * time.Second, EndTime: 7 * time.Second},
+	}
+
+	assert.Equal(t, []models.Interval{
+		{Start: 3 * time.Second, End: 4 * time.Second},
+		{Start: 5 * time.Second, End: 6 * time.Second},
+	}, utils.Overlaps(captions))
+}
+
+func TestDetectLanguageWindows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lang := "en-US"
+		if strings.Contains(string(body), "Hola") {
+			lang = "es-ES"
+		}
+		json.NewEncoder(w).Encode(models.LangResponse{Lang: lang})
+	}))
+	defer server.Close()
+
+	captions := []models.CaptionEntry{
+		{StartTime: 1 * time.Second, EndTime: 2 * time.Second, Text: "Hello there"},
+		{StartTime: 11 * time.Second, EndTime: 12 * time.Second, Text: "Hola amigo"},
+	}
+
+	windows := utils.DetectLanguageWindows(captions, 0, 25*time.Second, 10*time.Second, server.URL, "en-US")
+	require.Len(t, windows, 3)
+
+	assert.Equal(t, models.LanguageWindow{Start: 0, End: 10 * time.Second, Language: "en-US", Matches: true}, windows[0])
+	assert.Equal(t, models.LanguageWindow{Start: 10 * time.Second, End: 20 * time.Second, Language: "es-ES"}, windows[1])
+	assert.Equal(t, models.LanguageWindow{Start: 20 * time.Second, End: 25 * time.Second}, windows[2])
+}
+
+func TestCheckLanguageWindow(t *testing.T) {
+	assert.NoError(t, utils.CheckLanguageWindow(0, 2*time.Hour, 0))
+	assert.NoError(t, utils.CheckLanguageWindow(0, 2*time.Hour, 5*time.Minute))
+	assert.EqualError(t, utils.CheckLanguageWindow(0, 2*time.Hour, time.Second),
+		"language window 1s is shorter than the minimum of 10s")
+	assert.EqualError(t, utils.CheckLanguageWindow(0, 10*time.Hour, 10*time.Second),
+		"language window 10s splits 10h0m0s into 3600 windows, more than the maximum of 1000")
+	// Without an end time only the length is checked
+	assert.NoError(t, utils.CheckLanguageWindow(time.Minute, 0, 10*time.Second))
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theCompanyDream/srt-test/internal/models"
+	"github.com/theCompanyDream/srt-test/internal/utils"
+)
+
+func TestValidateCueDurations(t *testing.T) {
+	minDuration := 833 * time.Millisecond
+	maxDuration := 7 * time.Second
+
+	tests := []struct {
+		name          string
+		captions      []models.CaptionEntry
+		expectedTypes []string
+	}{
+		{
+			name: "durations within bounds",
+			captions: []models.CaptionEntry{
+				{StartTime: 0, EndTime: time.Second, Text: "One"},
+				{StartTime: 2 * time.Second, EndTime: 9 * time.Second, Text: "Two"},
+			},
+		},
+		{
+			name: "exact bounds are allowed",
+			captions: []models.CaptionEntry{
+				{StartTime: 0, EndTime: minDuration, Text: "Short"},
+				{StartTime: time.Second, EndTime: time.Second + maxDuration, Text: "Long"},
+			},
+		},
+		{
+			name: "flash cue",
+			captions: []models.CaptionEntry{
+				{StartTime: time.Second, EndTime: time.Second + 500*time.Millisecond, Text: "Blink"},
+			},
+			expectedTypes: []string{"cue_too_short"},
+		},
+		{
+			name: "lingering cue",
+			captions: []models.CaptionEntry{
+				{StartTime: 0, EndTime: 10 * time.Second, Text: "Still here"},
+			},
+			expectedTypes: []string{"cue_too_long"},
+		},
+		{
+			name: "every offending cue is reported",
+			captions: []models.CaptionEntry{
+				{StartTime: 0, EndTime: 100 * time.Millisecond, Text: "A"},
+				{StartTime: time.Second, EndTime: 2 * time.Second, Text: "B"},
+				{StartTime: 3 * time.Second, EndTime: 20 * time.Second, Text: "C"},
+				{StartTime: 21 * time.Second, EndTime: 21 * time.Second, Text: "D"},
+			},
+			expectedTypes: []string{"cue_too_short", "cue_too_long", "cue_too_short"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := utils.ValidateCueDurations(tt.captions, minDuration, maxDuration)
+
+			var types []string
+			for _, err := range errs {
+				types = append(types, err.Type)
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+}
+
+func TestValidateCueDurations_Description(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: time.Second, EndTime: 8 * time.Second, Text: "Fine"},
+		{StartTime: 61 * time.Second, EndTime: 61*time.Second + 400*time.Millisecond, Text: "A very quick flash of text that nobody can read in time"},
+	}
+
+	errs := utils.ValidateCueDurations(captions, 833*time.Millisecond, 0)
+	assert.Len(t, errs, 1)
+	assert.Equal(t,
+		`Cue 2 (00:01:01.000 --> 00:01:01.400) is displayed for 400ms, min 833ms: "A very quick flash of text that nobody …"`,
+		errs[0].Description)
+}
+
+func TestValidateCueDurations_ZeroBoundsDisabled(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: 0, EndTime: time.Millisecond, Text: "Blink"},
+		{StartTime: 0, EndTime: time.Hour, Text: "Forever"},
+	}
+	assert.Empty(t, utils.ValidateCueDurations(captions, 0, 0))
+}
+
+func TestValidateGaps(t *testing.T) {
+	captions := []models.CaptionEntry{
+		{StartTime: 0, EndTime: time.Second, Text: "A"},
+		{StartTime: time.Second, EndTime: 2 * time.Second, Text: "Chained"},
+		{StartTime: 2*time.Second + 40*time.Millisecond, EndTime: 3 * time.Second, Text: "Tight"},
+		{StartTime: 2*time.Second + 500*time.Millisecond, EndTime: 4 * time.Second, Text: "Overlap"},
+		{StartTime: 5 * time.Second, EndTime: 6 * time.Second, Text: "Spaced"},
+	}
+
+	errs := utils.ValidateGaps(captions, 83*time.Millisecond)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "gap_too_short", errs[0].Type)
+	assert.Contains(t, errs[0].Description, "Cue 3")
+	assert.Equal(t, "cue_overlap", errs[1].Type)
+	assert.Contains(t, errs[1].Description, "overlaps the previous cue by 500ms")
+
+	// Without a minimum gap, overlaps are still reported
+	errs = utils.ValidateGaps(captions, 0)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "cue_overlap", errs[0].Type)
+	assert.Contains(t, errs[0].Description, "Cue 4")
+}
+
+func TestValidateReadingSpeed(t *testing.T) {
+	captions := []models.CaptionEntry{
+		// 20 characters over 2 seconds
+		{StartTime: 0, EndTime: 2 * time.Second, Text: "Slow and easy to see", Lines: []string{"Slow and easy", "to see"}},
+		// 36 characters over 1 second
+		{StartTime: 3 * time.Second, EndTime: 4 * time.Second, Text: "Far too many words for a single beat"},
+		{StartTime: 5 * time.Second, EndTime: 5 * time.Second, Text: "Zero length"},
+	}
+
+	assert.InDelta(t, 9.5, utils.ReadingSpeed(captions[0]), 0.001)
+
+	errs := utils.ValidateReadingSpeed(captions, 17)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "reading_speed_too_high", errs[0].Type)
+	assert.Contains(t, errs[0].Description, "Cue 2")
+	assert.Contains(t, errs[0].Description, "36.0 characters per second, max 17.0")
+
+	assert.Empty(t, utils.ValidateReadingSpeed(captions, 0))
+}
@@ -1,13 +1,10 @@
 package utils
 
 import (
-	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"strings"
 	"testing"
 	"time"
 
@@ -17,32 +14,7 @@ import (
 	"github.com/theCompanyDream/srt-test/internal/utils"
 )
 
-func TestIsValidFileType(t *testing.T) {
-	tests := []struct {
-		name     string
-		filePath string
-		expected bool
-	}{
-		{"valid vtt file", "captions.vtt", true},
-		{"valid srt file", "captions.srt", true},
-		{"valid vtt uppercase", "captions.VTT", true},
-		{"valid srt uppercase", "captions.SRT", true},
-		{"valid with path", "/path/to/captions.vtt", true},
-		{"invalid txt file", "captions.txt", false},
-		{"invalid no extension", "captions", false},
-		{"invalid with dot", "captions.vtt.backup", false},
-		{"invalid empty string", "", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := utils.IsValidFileType(tt.filePath)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestValidateCoverage(t *testing.T) {
+func TestCoverage_Meets(t *testing.T) {
 	baseTime := time.Duration(0)
 	oneSec := time.Second
 	twoSec := 2 * time.Second
@@ -150,15 +122,18 @@ func TestValidateCoverage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := utils.ValidateCoverage(tt.captions, tt.tStart, tt.tEnd, tt.requiredCoverage)
-			assert.Equal(t, tt.expected, result, tt.name)
+			coverage := utils.NewCoverage(tt.tStart, tt.tEnd)
+			for _, caption := range tt.captions {
+				coverage.Add(caption)
+			}
+			assert.Equal(t, tt.expected, coverage.Meets(tt.requiredCoverage), tt.name)
 		})
 	}
 }
 
-func TestValidateLanguage(t *testing.T) {
+func TestValidateLanguageFor(t *testing.T) {
 	t.Run("empty text returns false", func(t *testing.T) {
-		result := utils.ValidateLanguage("", "http://example.com")
+		result := utils.ValidateLanguageFor("", "http://example.com", "en-US")
 		assert.False(t, result)
 	})
 
@@ -180,7 +155,7 @@ func TestValidateLanguage(t *testing.T) {
 		}))
 		defer server.Close()
 
-		result := utils.ValidateLanguage("Hello world", server.URL)
+		result := utils.ValidateLanguageFor("Hello world", server.URL, "en-US")
 		assert.True(t, result)
 	})
 
@@ -192,14 +167,14 @@ func TestValidateLanguage(t *testing.T) {
 		}))
 		defer server.Close()
 
-		result := utils.ValidateLanguage("Hola mundo", server.URL)
+		result := utils.ValidateLanguageFor("Hola mundo", server.URL, "en-US")
 		assert.False(t, result)
 	})
 
 	// Test error cases without making real HTTP calls
 	t.Run("invalid endpoint returns false", func(t *testing.T) {
 		// This will fail to connect, testing the error path
-		result := utils.ValidateLanguage("test text", "http://invalid-endpoint-that-does-not-exist:9999")
+		result := utils.ValidateLanguageFor("test text", "http://invalid-endpoint-that-does-not-exist:9999", "en-US")
 		assert.False(t, result)
 	})
 
@@ -209,7 +184,7 @@ func TestValidateLanguage(t *testing.T) {
 		}))
 		defer server.Close()
 
-		result := utils.ValidateLanguage("test text", server.URL)
+		result := utils.ValidateLanguageFor("test text", server.URL, "en-US")
 		assert.False(t, result)
 	})
 
@@ -220,63 +195,67 @@ func TestValidateLanguage(t *testing.T) {
 		}))
 		defer server.Close()
 
-		result := utils.ValidateLanguage("test text", server.URL)
+		result := utils.ValidateLanguageFor("test text", server.URL, "en-US")
 		assert.False(t, result)
 	})
 }
 
-func TestPrintValidationError(t *testing.T) {
-	tests := []struct {
-		name         string
-		errorType    string
-		description  string
-		expectedJSON string
-	}{
-		{
-			name:         "format error",
-			errorType:    "FormatError",
-			description:  "Invalid file format",
-			expectedJSON: `{"type":"FormatError","description":"Invalid file format"}`,
-		},
-		{
-			name:         "coverage error",
-			errorType:    "CoverageError",
-			description:  "Only 45% coverage",
-			expectedJSON: `{"type":"CoverageError","description":"Only 45% coverage"}`,
-		},
-		{
-			name:         "language error",
-			errorType:    "LanguageError",
-			description:  "Non-English content detected",
-			expectedJSON: `{"type":"LanguageError","description":"Non-English content detected"}`,
-		},
-	}
+func TestDetectLanguage(t *testing.T) {
+	t.Run("returns the detected language", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(models.LangResponse{Lang: "de-DE"})
+		}))
+		defer server.Close()
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
+		lang, err := utils.DetectLanguage("Guten Tag", server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "de-DE", lang)
+	})
 
-			utils.PrintValidationError(tt.errorType, tt.description)
+	t.Run("server error is reported", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
 
-			w.Close()
-			os.Stdout = oldStdout
+		_, err := utils.DetectLanguage("text", server.URL)
+		assert.ErrorContains(t, err, "502")
+	})
 
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
-			output := strings.TrimSpace(buf.String())
+	t.Run("invalid response is reported", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("not json"))
+		}))
+		defer server.Close()
 
-			// Verify the output matches expected JSON
-			assert.Equal(t, tt.expectedJSON, output)
+		_, err := utils.DetectLanguage("text", server.URL)
+		assert.ErrorContains(t, err, "invalid language detection response")
+	})
+}
 
-			// Verify it's valid JSON that can be unmarshalled
-			var ve models.ValidationError
-			err := json.Unmarshal([]byte(output), &ve)
-			assert.NoError(t, err)
-			assert.Equal(t, tt.errorType, ve.Type)
-			assert.Equal(t, tt.description, ve.Description)
-		})
+func TestValidateLanguageFor_Expected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.LangResponse{Lang: "ja-JP"})
+	}))
+	defer server.Close()
+
+	assert.True(t, utils.ValidateLanguageFor("こんにちは", server.URL, "ja-JP"))
+	assert.False(t, utils.ValidateLanguageFor("こんにちは", server.URL, "en-US"))
+}
+
+func TestLanguageMatches(t *testing.T) {
+	tests := []struct {
+		detected, expected string
+		want               bool
+	}{
+		{"en-US", "en-US", true},
+		{"en_us", "en-US", true},
+		{"en-US", "en", true},
+		{"en", "en-US", false},
+		{"en-GB", "en-US", false},
+		{"fr-FR", "en", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, utils.LanguageMatches(tt.detected, tt.expected), "%s vs %s", tt.detected, tt.expected)
 	}
 }